@@ -0,0 +1,263 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+// AntiCaptchaConfig configures a solver speaking the anti-captcha.com
+// createTask/getTaskResult protocol, CapMonster and others implement it too.
+type AntiCaptchaConfig struct {
+	Endpoint        string
+	APIKey          string
+	PollingInterval time.Duration
+	Timeout         time.Duration
+}
+
+var errAntiCaptchaTimeout = errors.New("task is not ready")
+
+type antiCaptchaSolver struct {
+	client          *http.Client
+	endpoint        string
+	apiKey          string
+	pollingInterval time.Duration
+	timeout         time.Duration
+}
+
+func NewAntiCaptchaSolver(cfg AntiCaptchaConfig) captcha.Solver {
+	return newAntiCaptchaSolver(cfg)
+}
+
+func NewAntiCaptchaBalanceChecker(cfg AntiCaptchaConfig) captcha.BalanceChecker {
+	return newAntiCaptchaSolver(cfg)
+}
+
+func newAntiCaptchaSolver(cfg AntiCaptchaConfig) *antiCaptchaSolver {
+	const (
+		defaultEndpoint        = "https://api.anti-captcha.com"
+		defaultPollingInterval = 3 * time.Second
+		defaultTimeout         = 2 * time.Minute
+		requestTimeout         = 30 * time.Second
+	)
+
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = defaultEndpoint
+	}
+
+	if cfg.PollingInterval <= 0 {
+		cfg.PollingInterval = defaultPollingInterval
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &antiCaptchaSolver{
+		client:          &http.Client{Timeout: requestTimeout},
+		endpoint:        strings.TrimSuffix(cfg.Endpoint, "/"),
+		apiKey:          cfg.APIKey,
+		pollingInterval: cfg.PollingInterval,
+		timeout:         cfg.Timeout,
+	}
+}
+
+type antiCaptchaResponse struct {
+	ErrorID          int    `json:"errorId"`
+	ErrorCode        string `json:"errorCode"`
+	ErrorDescription string `json:"errorDescription"`
+}
+
+func (r antiCaptchaResponse) err() error {
+	if r.ErrorID == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s", r.ErrorCode, r.ErrorDescription)
+}
+
+type antiCaptchaTask struct {
+	Type      string `json:"type"`
+	Body      string `json:"body"`
+	Numeric   int    `json:"numeric"`
+	MinLength int    `json:"minLength"`
+	MaxLength int    `json:"maxLength"`
+}
+
+type antiCaptchaCreateTask struct {
+	ClientKey string          `json:"clientKey"`
+	Task      antiCaptchaTask `json:"task"`
+}
+
+type antiCaptchaCreateTaskResponse struct {
+	antiCaptchaResponse
+	TaskID int64 `json:"taskId"`
+}
+
+type antiCaptchaTaskRequest struct {
+	ClientKey string `json:"clientKey"`
+	TaskID    int64  `json:"taskId"`
+}
+
+type antiCaptchaTaskResultResponse struct {
+	antiCaptchaResponse
+	Status   string `json:"status"`
+	Solution struct {
+		Text string `json:"text"`
+	} `json:"solution"`
+	Cost string `json:"cost"`
+}
+
+type antiCaptchaBalanceRequest struct {
+	ClientKey string `json:"clientKey"`
+}
+
+type antiCaptchaBalanceResponse struct {
+	antiCaptchaResponse
+	Balance float64 `json:"balance"`
+}
+
+const (
+	antiCaptchaTaskImageToText = "ImageToTextTask"
+	antiCaptchaNumericOnly     = 1
+	antiCaptchaStatusReady     = "ready"
+)
+
+func (a *antiCaptchaSolver) Solve(ctx context.Context, img image.Image) (captcha.Solution, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	created := antiCaptchaCreateTaskResponse{}
+	if err := a.call(ctx, "createTask", antiCaptchaCreateTask{
+		ClientKey: a.apiKey,
+		Task: antiCaptchaTask{
+			Type:      antiCaptchaTaskImageToText,
+			Body:      base64.StdEncoding.EncodeToString(img.Bytes),
+			Numeric:   antiCaptchaNumericOnly,
+			MinLength: captcha.CodeLength,
+			MaxLength: captcha.CodeLength,
+		},
+	}, &created); err != nil {
+		return captcha.Solution{}, fmt.Errorf("create task: %w", err)
+	}
+
+	result, err := a.waitResult(ctx, created.TaskID)
+	if err != nil {
+		return captcha.Solution{}, fmt.Errorf("task %d: %w", created.TaskID, err)
+	}
+
+	cost, err := strconv.ParseFloat(result.Cost, 64)
+	if err != nil && result.Cost != "" {
+		return captcha.Solution{}, fmt.Errorf("parse cost `%s`: %w", result.Cost, err)
+	}
+
+	return captcha.Solution{
+		Code:       result.Solution.Text,
+		Confidence: 1,
+		Cost:       cost,
+		Reporter: func(correct bool) error {
+			if correct {
+				return nil
+			}
+
+			return a.reportIncorrect(created.TaskID)
+		},
+	}, nil
+}
+
+func (a *antiCaptchaSolver) waitResult(ctx context.Context, taskID int64) (antiCaptchaTaskResultResponse, error) {
+	ticker := time.NewTicker(a.pollingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return antiCaptchaTaskResultResponse{}, fmt.Errorf("%w: %w", errAntiCaptchaTimeout, ctx.Err())
+		case <-ticker.C:
+		}
+
+		result := antiCaptchaTaskResultResponse{}
+		if err := a.call(ctx, "getTaskResult", antiCaptchaTaskRequest{
+			ClientKey: a.apiKey,
+			TaskID:    taskID,
+		}, &result); err != nil {
+			if ctx.Err() != nil {
+				return antiCaptchaTaskResultResponse{}, fmt.Errorf("%w: %w", errAntiCaptchaTimeout, ctx.Err())
+			}
+
+			return antiCaptchaTaskResultResponse{}, fmt.Errorf("get task result: %w", err)
+		}
+
+		if result.Status == antiCaptchaStatusReady {
+			return result, nil
+		}
+	}
+}
+
+func (a *antiCaptchaSolver) reportIncorrect(taskID int64) error {
+	response := antiCaptchaResponse{}
+	if err := a.call(context.Background(), "reportIncorrectImageCaptcha", antiCaptchaTaskRequest{
+		ClientKey: a.apiKey,
+		TaskID:    taskID,
+	}, &response); err != nil {
+		return fmt.Errorf("could not report captcha %d: %w", taskID, err)
+	}
+
+	return nil
+}
+
+func (a *antiCaptchaSolver) Balance(ctx context.Context) (float64, error) {
+	response := antiCaptchaBalanceResponse{}
+	if err := a.call(ctx, "getBalance", antiCaptchaBalanceRequest{ClientKey: a.apiKey}, &response); err != nil {
+		return 0, fmt.Errorf("could not get balance: %w", err)
+	}
+
+	return response.Balance, nil
+}
+
+type antiCaptchaErrorer interface {
+	err() error
+}
+
+func (a *antiCaptchaSolver) call(ctx context.Context, method string, request any, response antiCaptchaErrorer) error {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, a.endpoint+"/"+method, bytes.NewReader(requestBytes),
+	)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := a.client.Do(httpRequest)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", httpResponse.StatusCode)
+	}
+
+	if err := json.NewDecoder(httpResponse.Body).Decode(response); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return response.err()
+}
@@ -0,0 +1,190 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type fakeAntiCaptcha struct {
+	server *httptest.Server
+	// readyAfter is how many getTaskResult calls answer processing, a negative
+	// value never gets the task ready.
+	readyAfter int
+
+	m       sync.Mutex
+	polls   int
+	tasks   []antiCaptchaTask
+	reports []int64
+}
+
+func newFakeAntiCaptcha(t *testing.T, readyAfter int) *fakeAntiCaptcha {
+	t.Helper()
+
+	fake := &fakeAntiCaptcha{readyAfter: readyAfter}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /createTask", func(w http.ResponseWriter, r *http.Request) {
+		request := antiCaptchaCreateTask{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if request.ClientKey != "key" {
+			_, _ = w.Write([]byte(`{"errorId":1,"errorCode":"ERROR_KEY_DOES_NOT_EXIST","errorDescription":"bad key"}`))
+
+			return
+		}
+
+		fake.m.Lock()
+		fake.tasks = append(fake.tasks, request.Task)
+		fake.m.Unlock()
+
+		_, _ = w.Write([]byte(`{"errorId":0,"taskId":7}`))
+	})
+	mux.HandleFunc("POST /getTaskResult", func(w http.ResponseWriter, _ *http.Request) {
+		fake.m.Lock()
+		fake.polls++
+		ready := fake.readyAfter >= 0 && fake.polls > fake.readyAfter
+		fake.m.Unlock()
+
+		if !ready {
+			_, _ = w.Write([]byte(`{"errorId":0,"status":"processing"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"errorId":0,"status":"ready","solution":{"text":"123456"},"cost":"0.000700"}`))
+	})
+	mux.HandleFunc("POST /reportIncorrectImageCaptcha", func(w http.ResponseWriter, r *http.Request) {
+		request := antiCaptchaTaskRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		fake.m.Lock()
+		fake.reports = append(fake.reports, request.TaskID)
+		fake.m.Unlock()
+
+		_, _ = w.Write([]byte(`{"errorId":0,"status":"success"}`))
+	})
+	mux.HandleFunc("POST /getBalance", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"errorId":0,"balance":1.25}`))
+	})
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeAntiCaptcha) solver(apiKey string, timeout time.Duration) *antiCaptchaSolver {
+	return newAntiCaptchaSolver(AntiCaptchaConfig{
+		Endpoint:        f.server.URL + "/",
+		APIKey:          apiKey,
+		PollingInterval: time.Millisecond,
+		Timeout:         timeout,
+	})
+}
+
+func TestAntiCaptchaSolver_Solve(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeAntiCaptcha(t, 2)
+	solver := fake.solver("key", time.Second)
+
+	solution, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG()))
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Code != "123456" || solution.Cost != 0.0007 {
+		t.Errorf("unexpected solution %+v", solution)
+	}
+
+	fake.m.Lock()
+	defer fake.m.Unlock()
+
+	if fake.polls != 3 {
+		t.Errorf("expected 3 polls, got %d", fake.polls)
+	}
+
+	if len(fake.tasks) != 1 || fake.tasks[0].Type != antiCaptchaTaskImageToText ||
+		fake.tasks[0].MinLength != 6 || fake.tasks[0].MaxLength != 6 || fake.tasks[0].Body == "" {
+		t.Errorf("unexpected tasks %+v", fake.tasks)
+	}
+}
+
+func TestAntiCaptchaSolver_ReportIncorrect(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeAntiCaptcha(t, 0)
+	solver := fake.solver("key", time.Second)
+
+	for _, correct := range []bool{true, false} {
+		solution, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG()))
+		if err != nil {
+			t.Fatalf("solve: %v", err)
+		}
+
+		if err := solution.Report(correct); err != nil {
+			t.Fatalf("report: %v", err)
+		}
+	}
+
+	fake.m.Lock()
+	defer fake.m.Unlock()
+
+	if len(fake.reports) != 1 || fake.reports[0] != 7 {
+		t.Errorf("expected a single incorrect report of task 7, got %v", fake.reports)
+	}
+}
+
+func TestAntiCaptchaSolver_APIError(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeAntiCaptcha(t, 0)
+	solver := fake.solver("wrong", time.Second)
+
+	if _, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG())); err == nil {
+		t.Fatal("expected error for a wrong key")
+	}
+}
+
+func TestAntiCaptchaSolver_Timeout(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeAntiCaptcha(t, -1)
+	solver := fake.solver("key", 50*time.Millisecond)
+
+	_, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG()))
+	if !errors.Is(err, errAntiCaptchaTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
+
+func TestAntiCaptchaSolver_Balance(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeAntiCaptcha(t, 0)
+
+	balance, err := fake.solver("key", time.Second).Balance(context.Background())
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+
+	if balance != 1.25 {
+		t.Errorf("expected balance 1.25, got %v", balance)
+	}
+}
@@ -1,60 +1,190 @@
 package adapter
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/playwright-community/playwright-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/truewebber/gopkg/log"
 
 	"github.com/truewebber/kdmid-queue-checker/domain/page"
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
 )
 
-type BrowserDispatcher interface {
-	io.Closer
-	page.Dispatcher
+type BrowserDispatcherConfig struct {
+	BaseURL             *url.URL
+	Timeout             time.Duration
+	Blocking            ResourceBlocking
+	Screenshots         ScreenshotPolicies
+	MaxContexts         int
+	HealthCheckInterval time.Duration
+	RelaunchBackoff     time.Duration
+	MaxRelaunchBackoff  time.Duration
 }
 
+type browserLauncher func() (*playwright.Playwright, playwright.Browser, error)
+
 type browserDispatcher struct {
-	browser    playwright.Browser
+	launch              browserLauncher
+	baseURL             *url.URL
+	timeout             float64
+	acquireTimeout      time.Duration
+	blocking            ResourceBlocking
+	screenshots         ScreenshotPolicies
+	contexts            chan struct{}
+	healthCheckInterval time.Duration
+	relaunchBackoff     time.Duration
+	maxRelaunchBackoff  time.Duration
+	logger              log.Logger
+
+	m          sync.RWMutex
 	playwright *playwright.Playwright
+	browser    playwright.Browser
+	health     page.Health
+	launched   bool
+
+	disconnected chan struct{}
+	done         chan struct{}
+	stopped      chan struct{}
+	closeOnce    sync.Once
+}
+
+const (
+	defaultMaxContexts         = 2
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultRelaunchBackoff     = time.Second
+	defaultMaxRelaunchBackoff  = time.Minute
+	// defaultAcquireTimeout bounds the wait for a free context when no
+	// navigation timeout is set.
+	defaultAcquireTimeout = 2 * time.Minute
+)
+
+var (
+	browserUpGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kdmid",
+		Name:      "browser_up",
+		Help:      "Whether the browser used by the dispatcher is connected.",
+	})
+	browserRelaunchesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "browser_relaunches_total",
+		Help:      "Attempts to relaunch the disconnected browser.",
+	}, []string{"result"})
+	browserOpenContextsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kdmid",
+		Name:      "browser_open_contexts",
+		Help:      "Browser contexts currently opened by navigators.",
+	})
+)
+
+func NewBrowserDispatcher(cfg BrowserDispatcherConfig, logger log.Logger) (page.Dispatcher, error) {
+	return newBrowserDispatcher(cfg, launchWebKit, logger)
 }
 
-func NewBrowserDispatcher(proxyURL *url.URL) (BrowserDispatcher, error) {
-	proxy, err := configurePlaywrightProxy(proxyURL)
+func MustNewBrowserDispatcher(cfg BrowserDispatcherConfig, logger log.Logger) page.Dispatcher {
+	dispatcher, err := NewBrowserDispatcher(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("configure playwright proxy: %w", err)
+		panic(err)
+	}
+
+	return dispatcher
+}
+
+func newBrowserDispatcher(
+	cfg BrowserDispatcherConfig, launch browserLauncher, logger log.Logger,
+) (*browserDispatcher, error) {
+	if cfg.BaseURL == nil {
+		return nil, fmt.Errorf("no base URL provided")
 	}
 
+	for _, policy := range []*ScreenshotPolicy{
+		&cfg.Screenshots.Authorize, &cfg.Screenshots.Submit, &cfg.Screenshots.Calendar,
+	} {
+		if policy.Scope == "" {
+			policy.Scope = ScreenshotScopeFullPage
+		}
+
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("validate screenshot policy: %w", err)
+		}
+	}
+
+	if cfg.MaxContexts <= 0 {
+		cfg.MaxContexts = defaultMaxContexts
+	}
+
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = defaultHealthCheckInterval
+	}
+
+	if cfg.RelaunchBackoff <= 0 {
+		cfg.RelaunchBackoff = defaultRelaunchBackoff
+	}
+
+	if cfg.MaxRelaunchBackoff < cfg.RelaunchBackoff {
+		cfg.MaxRelaunchBackoff = max(defaultMaxRelaunchBackoff, cfg.RelaunchBackoff)
+	}
+
+	acquireTimeout := cfg.Timeout
+	if acquireTimeout <= 0 {
+		acquireTimeout = defaultAcquireTimeout
+	}
+
+	dispatcher := &browserDispatcher{
+		launch:              launch,
+		baseURL:             cfg.BaseURL,
+		timeout:             float64(cfg.Timeout.Milliseconds()),
+		acquireTimeout:      acquireTimeout,
+		blocking:            cfg.Blocking,
+		screenshots:         cfg.Screenshots,
+		contexts:            make(chan struct{}, cfg.MaxContexts),
+		healthCheckInterval: cfg.HealthCheckInterval,
+		relaunchBackoff:     cfg.RelaunchBackoff,
+		maxRelaunchBackoff:  cfg.MaxRelaunchBackoff,
+		logger:              logger,
+		disconnected:        make(chan struct{}, 1),
+		done:                make(chan struct{}),
+		stopped:             make(chan struct{}),
+	}
+
+	if err := dispatcher.relaunch(); err != nil {
+		return nil, err
+	}
+
+	go dispatcher.monitor()
+
+	return dispatcher, nil
+}
+
+func launchWebKit() (*playwright.Playwright, playwright.Browser, error) {
 	pw, err := playwright.Run()
 	if err != nil {
-		return nil, fmt.Errorf("run playwright: %w", err)
+		return nil, nil, fmt.Errorf("run playwright: %w", err)
 	}
 
 	browser, err := pw.WebKit.Launch(playwright.BrowserTypeLaunchOptions{
 		Headless: playwright.Bool(true),
-		Proxy:    proxy,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("launch browser: %w", err)
+		_ = pw.Stop()
+
+		return nil, nil, fmt.Errorf("launch browser: %w", err)
 	}
 
-	return &browserDispatcher{
-		browser:    browser,
-		playwright: pw,
-	}, nil
+	return pw, browser, nil
 }
 
-func configurePlaywrightProxy(proxyURL *url.URL) (*playwright.Proxy, error) {
-	if proxyURL == nil {
-		return nil, fmt.Errorf("no proxy URL provided")
+func configurePlaywrightProxy(contextProxy *proxy.Proxy) *playwright.Proxy {
+	if contextProxy == nil {
+		return nil
 	}
 
-	const httpScheme = "http"
-
-	if proxyURL.Scheme != httpScheme {
-		return nil, fmt.Errorf("unsupported scheme `%s`", proxyURL.Scheme)
-	}
+	proxyURL := contextProxy.URL
 
 	var (
 		username *string
@@ -72,44 +202,291 @@ func configurePlaywrightProxy(proxyURL *url.URL) (*playwright.Proxy, error) {
 	}
 
 	return &playwright.Proxy{
-		Server:   proxyURL.Host,
+		Server:   proxyURL.Scheme + "://" + proxyURL.Host,
 		Bypass:   nil,
 		Username: username,
 		Password: password,
-	}, nil
+	}
 }
 
-func MustNewBrowserDispatcher(proxyURL *url.URL) BrowserDispatcher {
-	dispatcher, err := NewBrowserDispatcher(proxyURL)
+func (c *browserDispatcher) NewNavigator(id, cd string, options page.NavigatorOptions) (page.Navigator, error) {
+	release, err := c.acquireContext()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return dispatcher
-}
+	browser, err := c.connectedBrowser()
+	if err != nil {
+		release()
 
-const timeout = float64(120 * 1000)
+		return nil, err
+	}
 
-func (c *browserDispatcher) NewNavigator(id, cd string) (page.Navigator, error) {
-	ctx, err := c.browser.NewContext()
+	ctx, err := browser.NewContext(playwright.BrowserNewContextOptions{
+		Proxy: configurePlaywrightProxy(options.Proxy),
+	})
 	if err != nil {
+		release()
+
+		if !browser.IsConnected() {
+			c.notifyDisconnected()
+
+			return nil, fmt.Errorf("%w: %w", page.ErrUnavailable, err)
+		}
+
 		return nil, fmt.Errorf("could not create new browser context: %w", err)
 	}
 
-	ctx.SetDefaultNavigationTimeout(timeout)
-	ctx.SetDefaultTimeout(timeout)
+	ctx.SetDefaultNavigationTimeout(c.timeout)
+	ctx.SetDefaultTimeout(c.timeout)
+
+	traffic := newTrafficCounter(c.blocking)
+
+	ctx.OnRequestFinished(traffic.onRequestFinished)
+
+	if c.blocking.enabled() {
+		if err := ctx.Route("**/*", traffic.onRoute); err != nil {
+			_ = ctx.Close()
+
+			release()
+
+			return nil, fmt.Errorf("could not route browser context: %w", err)
+		}
+	}
+
+	return &browserNavigator{
+		ctx:         ctx,
+		baseURL:     c.baseURL,
+		id:          id,
+		cd:          cd,
+		traffic:     traffic,
+		screenshots: c.screenshots,
+		release:     release,
+	}, nil
+}
+
+func (c *browserDispatcher) acquireContext() (func(), error) {
+	timer := time.NewTimer(c.acquireTimeout)
+	defer timer.Stop()
 
-	return &browserNavigator{ctx: ctx, id: id, cd: cd}, nil
+	select {
+	case c.contexts <- struct{}{}:
+	case <-c.done:
+		return nil, fmt.Errorf("%w: dispatcher closed", page.ErrUnavailable)
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: all %d browser contexts are busy", page.ErrUnavailable, cap(c.contexts))
+	}
+
+	browserOpenContextsGauge.Inc()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			<-c.contexts
+
+			browserOpenContextsGauge.Dec()
+		})
+	}, nil
 }
 
-func (c *browserDispatcher) Close() error {
-	if err := c.browser.Close(); err != nil {
-		return fmt.Errorf("could not close browser: %w", err)
+func (c *browserDispatcher) connectedBrowser() (playwright.Browser, error) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if c.browser == nil || !c.health.Healthy {
+		return nil, fmt.Errorf("%w: browser is being relaunched", page.ErrUnavailable)
+	}
+
+	return c.browser, nil
+}
+
+func (c *browserDispatcher) Health() page.Health {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	health := c.health
+	health.OpenNavigators = len(c.contexts)
+
+	return health
+}
+
+func (c *browserDispatcher) notifyDisconnected() {
+	select {
+	case c.disconnected <- struct{}{}:
+	default:
+	}
+}
+
+func (c *browserDispatcher) monitor() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-c.done:
+			return
+		case <-c.disconnected:
+			err = fmt.Errorf("browser is disconnected")
+		case <-ticker.C:
+			if err = c.checkBrowser(); err == nil {
+				continue
+			}
+		}
+
+		c.logger.Error("browser is unhealthy", "error", err.Error())
+		c.relaunchWithBackoff(err)
 	}
+}
+
+func (c *browserDispatcher) checkBrowser() error {
+	c.m.RLock()
+	browser := c.browser
+	c.m.RUnlock()
 
-	if err := c.playwright.Stop(); err != nil {
-		return fmt.Errorf("could not stop playwright: %w", err)
+	if browser == nil || !browser.IsConnected() {
+		return fmt.Errorf("browser is disconnected")
+	}
+
+	ctx, err := browser.NewContext()
+	if err != nil {
+		return fmt.Errorf("probe browser context: %w", err)
+	}
+
+	if err := ctx.Close(); err != nil {
+		return fmt.Errorf("close probe browser context: %w", err)
 	}
 
 	return nil
 }
+
+func (c *browserDispatcher) relaunchWithBackoff(cause error) {
+	c.markUnhealthy(cause)
+
+	backoff := c.relaunchBackoff
+
+	for {
+		err := c.relaunch()
+		if err == nil {
+			browserRelaunchesCounter.WithLabelValues("success").Inc()
+			c.logger.Info("browser relaunched")
+
+			return
+		}
+
+		browserRelaunchesCounter.WithLabelValues("failure").Inc()
+		c.logger.Error("failed relaunch browser", "error", err.Error(), "backoff", backoff)
+		c.markUnhealthy(err)
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, c.maxRelaunchBackoff)
+	}
+}
+
+func (c *browserDispatcher) relaunch() error {
+	c.m.Lock()
+	pw, browser := c.playwright, c.browser
+	c.playwright, c.browser = nil, nil
+	c.m.Unlock()
+
+	if err := stopBrowser(pw, browser); err != nil {
+		c.logger.Error("failed stop disconnected browser", "error", err.Error())
+	}
+
+	pw, browser, err := c.launch()
+	if err != nil {
+		return err
+	}
+
+	browser.OnDisconnected(func(disconnected playwright.Browser) {
+		c.m.RLock()
+		current := c.browser == disconnected
+		c.m.RUnlock()
+
+		if current {
+			c.notifyDisconnected()
+		}
+	})
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.playwright, c.browser = pw, browser
+
+	if c.launched {
+		c.health.Relaunches++
+	}
+
+	c.launched = true
+
+	c.health.Healthy = true
+	c.health.Since = time.Now()
+	c.health.LastError = ""
+
+	browserUpGauge.Set(1)
+
+	return nil
+}
+
+func (c *browserDispatcher) markUnhealthy(err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.health.Healthy {
+		c.health.Since = time.Now()
+	}
+
+	c.health.Healthy = false
+	c.health.LastError = err.Error()
+
+	browserUpGauge.Set(0)
+}
+
+func stopBrowser(pw *playwright.Playwright, browser playwright.Browser) error {
+	var errs []error
+
+	if browser != nil {
+		if err := browser.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("could not close browser: %w", err))
+		}
+	}
+
+	if pw != nil {
+		if err := pw.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("could not stop playwright: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *browserDispatcher) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		close(c.done)
+		<-c.stopped
+
+		c.m.Lock()
+		pw, browser := c.playwright, c.browser
+		c.playwright, c.browser = nil, nil
+		c.health.Healthy = false
+		c.health.LastError = "dispatcher closed"
+		c.m.Unlock()
+
+		browserUpGauge.Set(0)
+
+		err = stopBrowser(pw, browser)
+	})
+
+	return err
+}
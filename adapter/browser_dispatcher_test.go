@@ -0,0 +1,272 @@
+package adapter
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type fakeBrowser struct {
+	playwright.Browser
+
+	m              sync.Mutex
+	connected      bool
+	onDisconnected func(playwright.Browser)
+}
+
+func (b *fakeBrowser) IsConnected() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.connected
+}
+
+func (b *fakeBrowser) OnDisconnected(fn func(playwright.Browser)) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.onDisconnected = fn
+}
+
+func (b *fakeBrowser) NewContext(...playwright.BrowserNewContextOptions) (playwright.BrowserContext, error) {
+	if !b.IsConnected() {
+		return nil, errors.New("target closed")
+	}
+
+	return &fakeBrowserContext{}, nil
+}
+
+func (b *fakeBrowser) Close(...playwright.BrowserCloseOptions) error {
+	b.disconnect()
+
+	return nil
+}
+
+func (b *fakeBrowser) disconnect() {
+	b.m.Lock()
+	b.connected = false
+	onDisconnected := b.onDisconnected
+	b.m.Unlock()
+
+	if onDisconnected != nil {
+		onDisconnected(b)
+	}
+}
+
+type fakeBrowserContext struct {
+	playwright.BrowserContext
+}
+
+func (c *fakeBrowserContext) SetDefaultNavigationTimeout(float64) {}
+
+func (c *fakeBrowserContext) SetDefaultTimeout(float64) {}
+
+func (c *fakeBrowserContext) OnRequestFinished(func(playwright.Request)) {}
+
+func (c *fakeBrowserContext) Close(...playwright.BrowserContextCloseOptions) error {
+	return nil
+}
+
+type fakeLauncher struct {
+	m        sync.Mutex
+	failures int
+	browsers []*fakeBrowser
+}
+
+func (l *fakeLauncher) launch() (*playwright.Playwright, playwright.Browser, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if len(l.browsers) != 0 && l.failures > 0 {
+		l.failures--
+
+		return nil, nil, errors.New("launch failed")
+	}
+
+	browser := &fakeBrowser{connected: true}
+	l.browsers = append(l.browsers, browser)
+
+	return nil, browser, nil
+}
+
+func (l *fakeLauncher) last() *fakeBrowser {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.browsers[len(l.browsers)-1]
+}
+
+func newTestBrowserDispatcher(t *testing.T, launcher *fakeLauncher, maxContexts int) *browserDispatcher {
+	t.Helper()
+
+	dispatcher, err := newBrowserDispatcher(BrowserDispatcherConfig{
+		BaseURL:             &url.URL{Scheme: "http", Host: "localhost"},
+		Timeout:             100 * time.Millisecond,
+		MaxContexts:         maxContexts,
+		HealthCheckInterval: time.Hour,
+		RelaunchBackoff:     time.Millisecond,
+		MaxRelaunchBackoff:  5 * time.Millisecond,
+	}, launcher.launch, log.NewLogger())
+	if err != nil {
+		t.Fatalf("new browser dispatcher: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := dispatcher.Close(); err != nil {
+			t.Errorf("close dispatcher: %v", err)
+		}
+	})
+
+	return dispatcher
+}
+
+func waitForHealth(t *testing.T, dispatcher *browserDispatcher, check func(page.Health) bool) page.Health {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		health := dispatcher.Health()
+		if check(health) {
+			return health
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("health did not reach expected state, got %+v", health)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBrowserDispatcher_RelaunchAfterDisconnect(t *testing.T) {
+	t.Parallel()
+
+	launcher := &fakeLauncher{failures: 3}
+	dispatcher := newTestBrowserDispatcher(t, launcher, 1)
+
+	if health := dispatcher.Health(); !health.Healthy || health.Relaunches != 0 {
+		t.Fatalf("expected healthy dispatcher without relaunches, got %+v", health)
+	}
+
+	launcher.last().disconnect()
+
+	health := waitForHealth(t, dispatcher, func(health page.Health) bool {
+		return health.Healthy && health.Relaunches == 1
+	})
+
+	if health.LastError != "" {
+		t.Errorf("expected last error to be reset, got %q", health.LastError)
+	}
+
+	navigator, err := dispatcher.NewNavigator("12345", "abcdef", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("new navigator after relaunch: %v", err)
+	}
+
+	if err := navigator.Close(); err != nil {
+		t.Fatalf("close navigator: %v", err)
+	}
+}
+
+func TestBrowserDispatcher_UnavailableWhileRelaunching(t *testing.T) {
+	t.Parallel()
+
+	launcher := &fakeLauncher{failures: 1_000_000}
+	dispatcher := newTestBrowserDispatcher(t, launcher, 1)
+
+	launcher.last().disconnect()
+
+	health := waitForHealth(t, dispatcher, func(health page.Health) bool {
+		return !health.Healthy && health.LastError == "launch failed"
+	})
+
+	if health.Relaunches != 0 {
+		t.Errorf("expected no successful relaunches, got %d", health.Relaunches)
+	}
+
+	if _, err := dispatcher.NewNavigator("12345", "abcdef", page.NavigatorOptions{}); !errors.Is(err, page.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+
+	if health := dispatcher.Health(); health.OpenNavigators != 0 {
+		t.Errorf("expected context slot to be released, got %d open", health.OpenNavigators)
+	}
+}
+
+func TestBrowserDispatcher_LimitsContexts(t *testing.T) {
+	t.Parallel()
+
+	dispatcher := newTestBrowserDispatcher(t, &fakeLauncher{}, 2)
+
+	first, err := dispatcher.NewNavigator("1", "a", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("first navigator: %v", err)
+	}
+
+	second, err := dispatcher.NewNavigator("2", "b", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("second navigator: %v", err)
+	}
+
+	if health := dispatcher.Health(); health.OpenNavigators != 2 {
+		t.Errorf("expected 2 open navigators, got %d", health.OpenNavigators)
+	}
+
+	if _, err := dispatcher.NewNavigator("3", "c", page.NavigatorOptions{}); !errors.Is(err, page.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable when all contexts are busy, got %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("close first navigator: %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("close first navigator twice: %v", err)
+	}
+
+	third, err := dispatcher.NewNavigator("3", "c", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("third navigator after release: %v", err)
+	}
+
+	for _, navigator := range []page.Navigator{second, third} {
+		if err := navigator.Close(); err != nil {
+			t.Fatalf("close navigator: %v", err)
+		}
+	}
+
+	if health := dispatcher.Health(); health.OpenNavigators != 0 {
+		t.Errorf("expected no open navigators, got %d", health.OpenNavigators)
+	}
+}
+
+func TestBrowserDispatcher_Close(t *testing.T) {
+	t.Parallel()
+
+	launcher := &fakeLauncher{}
+	dispatcher := newTestBrowserDispatcher(t, launcher, 1)
+
+	if err := dispatcher.Close(); err != nil {
+		t.Fatalf("close dispatcher: %v", err)
+	}
+
+	if launcher.last().IsConnected() {
+		t.Error("expected browser to be closed")
+	}
+
+	if health := dispatcher.Health(); health.Healthy {
+		t.Error("expected closed dispatcher to be unhealthy")
+	}
+
+	if _, err := dispatcher.NewNavigator("12345", "abcdef", page.NavigatorOptions{}); !errors.Is(err, page.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable after close, got %v", err)
+	}
+}
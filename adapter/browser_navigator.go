@@ -13,24 +13,58 @@ import (
 )
 
 type browserNavigator struct {
-	ctx    playwright.BrowserContext
-	id, cd string
+	ctx         playwright.BrowserContext
+	baseURL     *url.URL
+	id, cd      string
+	traffic     *trafficCounter
+	screenshots ScreenshotPolicies
+	release     func()
 }
 
+const (
+	orderInfoPath  = "/queue/OrderInfo.aspx"
+	spCalendarPath = "/queue/SPCalendar.aspx"
+)
+
 func (c *browserNavigator) buildURL() *url.URL {
 	query := url.Values{}
 	query.Set("id", c.id)
 	query.Set("cd", c.cd)
 
-	return &url.URL{
-		Scheme:   "https",
-		Host:     "barcelona.kdmid.ru",
-		Path:     "/queue/OrderInfo.aspx",
-		RawQuery: query.Encode(),
-	}
+	orderInfoURL := c.baseURL.JoinPath(orderInfoPath)
+	orderInfoURL.RawQuery = query.Encode()
+
+	return orderInfoURL
+}
+
+func (c *browserNavigator) urlPattern(path string) string {
+	return c.baseURL.JoinPath(path).String() + "*"
 }
 
 func (c *browserNavigator) OpenPageToAuthorize() (page.Stat, error) {
+	return c.countTraffic(c.openPageToAuthorize)
+}
+
+func (c *browserNavigator) SubmitAuthorization(code string) (page.Stat, error) {
+	return c.countTraffic(func() (page.Stat, error) {
+		return c.submitAuthorization(code)
+	})
+}
+
+func (c *browserNavigator) OpenSlotBookingPage() (page.Stat, error) {
+	return c.countTraffic(c.openSlotBookingPage)
+}
+
+func (c *browserNavigator) countTraffic(step func() (page.Stat, error)) (page.Stat, error) {
+	c.traffic.reset()
+
+	stat, err := step()
+	stat.Traffic = c.traffic.snapshot()
+
+	return stat, err
+}
+
+func (c *browserNavigator) openPageToAuthorize() (page.Stat, error) {
 	if len(c.ctx.Pages()) != 0 {
 		return page.Stat{}, fmt.Errorf("there're pages in context")
 	}
@@ -57,9 +91,7 @@ func (c *browserNavigator) OpenPageToAuthorize() (page.Stat, error) {
 		}, fmt.Errorf("page content: %w", err)
 	}
 
-	pageScreenshot, err := browserPage.Screenshot(playwright.PageScreenshotOptions{
-		FullPage: playwright.Bool(true),
-	})
+	pageScreenshot, err := takeScreenshot(browserPage, c.screenshots.Authorize)
 	if err != nil {
 		return page.Stat{
 			Network: networkBuffer.Bytes(),
@@ -72,7 +104,7 @@ func (c *browserNavigator) OpenPageToAuthorize() (page.Stat, error) {
 			Network:    networkBuffer.Bytes(),
 			HTML:       []byte(pageHtml),
 			Screenshot: pageScreenshot,
-		}, fmt.Errorf("could not goto: %w", openPageErr)
+		}, fmt.Errorf("%w: %w", page.ErrNavigationFailed, openPageErr)
 	}
 
 	captchaScreenshot, err := c.takeCaptchaScreenshot(browserPage)
@@ -97,40 +129,42 @@ func (c *browserNavigator) OpenPageToAuthorize() (page.Stat, error) {
 	return stat, nil
 }
 
-func (c *browserNavigator) takeCaptchaScreenshot(browserPage playwright.Page) (image.PNG, error) {
+func (c *browserNavigator) takeCaptchaScreenshot(browserPage playwright.Page) (image.Image, error) {
 	locator := browserPage.Locator("img")
 
 	n, err := locator.Count()
 
 	if err != nil {
-		return nil, fmt.Errorf("count selected images: %w", err)
+		return image.Image{}, fmt.Errorf("count selected images: %w", err)
 	}
 
 	if n != 1 {
-		return nil, fmt.Errorf("expected 1 image, got %d", n)
+		return image.Image{}, fmt.Errorf("expected 1 image, got %d", n)
 	}
 
 	elemScreenshotBytes, err := locator.Screenshot()
 	if err != nil {
-		return nil, fmt.Errorf("could not take element image: %w", err)
+		return image.Image{}, fmt.Errorf("could not take element image: %w", err)
 	}
 
-	croppedScreenshot, err := image.Crop(elemScreenshotBytes, func(height, width int) image.CroppingRect {
-		return image.CroppingRect{
-			X0: width / 3,
-			Y0: 0,
-			X1: width / 3 * 2,
-			Y1: height,
-		}
-	})
+	croppedScreenshot, err := image.CropCaptcha(image.NewPNG(elemScreenshotBytes), captchaCroppingRect)
 	if err != nil {
-		return nil, fmt.Errorf("crop image: %w", err)
+		return image.Image{}, fmt.Errorf("crop image: %w", err)
 	}
 
 	return croppedScreenshot, nil
 }
 
-func (c *browserNavigator) SubmitAuthorization(code string) (page.Stat, error) {
+func captchaCroppingRect(height, width int) image.CroppingRect {
+	return image.CroppingRect{
+		X0: width / 3,
+		Y0: 0,
+		X1: width / 3 * 2,
+		Y1: height,
+	}
+}
+
+func (c *browserNavigator) submitAuthorization(code string) (page.Stat, error) {
 	pagesCount := len(c.ctx.Pages())
 	if pagesCount != 1 {
 		return page.Stat{}, fmt.Errorf("expected 1 page, got %d", pagesCount)
@@ -166,13 +200,13 @@ func (c *browserNavigator) SubmitAuthorization(code string) (page.Stat, error) {
 		}, fmt.Errorf("could not click submit button: %w", err)
 	}
 
-	err = browserPage.WaitForURL("https://barcelona.kdmid.ru/queue/OrderInfo.aspx*", playwright.PageWaitForURLOptions{
+	err = browserPage.WaitForURL(c.urlPattern(orderInfoPath), playwright.PageWaitForURLOptions{
 		WaitUntil: playwright.WaitUntilStateLoad,
 	})
 	if err != nil {
 		return page.Stat{
 			Network: networkBuffer.Bytes(),
-		}, fmt.Errorf("navigation failed: %w", err)
+		}, fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
 	}
 
 	if err := c.checkCaptchaSolved(browserPage); err != nil {
@@ -188,9 +222,7 @@ func (c *browserNavigator) SubmitAuthorization(code string) (page.Stat, error) {
 		}, fmt.Errorf("page content: %w", err)
 	}
 
-	screenshot, err := browserPage.Screenshot(playwright.PageScreenshotOptions{
-		FullPage: playwright.Bool(true),
-	})
+	screenshot, err := takeScreenshot(browserPage, c.screenshots.Submit)
 	if err != nil {
 		return page.Stat{
 			Network: networkBuffer.Bytes(),
@@ -250,7 +282,7 @@ func (c *browserNavigator) getSubmitButton(page playwright.Page) (playwright.Loc
 	return inputLocator, nil
 }
 
-func (c *browserNavigator) OpenSlotBookingPage() (page.Stat, error) {
+func (c *browserNavigator) openSlotBookingPage() (page.Stat, error) {
 	pagesCount := len(c.ctx.Pages())
 	if pagesCount != 1 {
 		return page.Stat{}, fmt.Errorf("expected 1 page, got %d", pagesCount)
@@ -277,13 +309,13 @@ func (c *browserNavigator) OpenSlotBookingPage() (page.Stat, error) {
 		}, fmt.Errorf("could not click button: %w", err)
 	}
 
-	err = browserPage.WaitForURL("https://barcelona.kdmid.ru/queue/SPCalendar.aspx*", playwright.PageWaitForURLOptions{
+	err = browserPage.WaitForURL(c.urlPattern(spCalendarPath), playwright.PageWaitForURLOptions{
 		WaitUntil: playwright.WaitUntilStateLoad,
 	})
 	if err != nil {
 		return page.Stat{
 			Network: networkBuffer.Bytes(),
-		}, fmt.Errorf("navigation failed: %w", err)
+		}, fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
 	}
 
 	pageHtml, err := browserPage.Content()
@@ -293,9 +325,7 @@ func (c *browserNavigator) OpenSlotBookingPage() (page.Stat, error) {
 		}, fmt.Errorf("page content: %w", err)
 	}
 
-	screenshot, err := browserPage.Screenshot(playwright.PageScreenshotOptions{
-		FullPage: playwright.Bool(true),
-	})
+	screenshot, err := takeScreenshot(browserPage, c.screenshots.Calendar)
 	if err != nil {
 		return page.Stat{
 			Network: networkBuffer.Bytes(),
@@ -388,6 +418,8 @@ func (c *browserNavigator) openAuthorizationPage(page playwright.Page, urlToOpen
 }
 
 func (c *browserNavigator) Close() error {
+	defer c.release()
+
 	if err := c.ctx.Close(); err != nil {
 		return fmt.Errorf("close browser context: %w", err)
 	}
@@ -400,17 +432,14 @@ type bytesBuffer struct {
 }
 
 func (b *bytesBuffer) onRequest(request playwright.Request) {
-	logEntry := fmt.Sprintf("Request: %v, headers: %v\n", request.URL(), request.Headers())
-
-	_, err := b.WriteString(logEntry)
-	if err != nil {
-		log.Printf("could not write to log file: %v", err)
-	}
+	b.log(fmt.Sprintf("Request: %v, headers: %v\n", request.URL(), request.Headers()))
 }
 
 func (b *bytesBuffer) onResponse(response playwright.Response) {
-	logEntry := fmt.Sprintf("Response: %v, Status: %v, headers: %v\n", response.URL(), response.Status(), response.Headers())
+	b.log(fmt.Sprintf("Response: %v, Status: %v, headers: %v\n", response.URL(), response.Status(), response.Headers()))
+}
 
+func (b *bytesBuffer) log(logEntry string) {
 	if _, err := b.WriteString(logEntry); err != nil {
 		log.Printf("could not write to log file: %v", err)
 	}
@@ -0,0 +1,134 @@
+package adapter
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+func newTestBrowserNavigator(t *testing.T, baseURL *url.URL) page.Navigator {
+	t.Helper()
+
+	// The checker blocks these by default, the flow has to work with them.
+	dispatcher, err := NewBrowserDispatcher(BrowserDispatcherConfig{
+		BaseURL: baseURL,
+		Timeout: 5 * time.Second,
+		Blocking: ResourceBlocking{
+			ResourceTypes:    []string{"font", "media"},
+			BlockURLPatterns: []*regexp.Regexp{regexp.MustCompile(`google-analytics\.com`)},
+			AllowURLPatterns: []*regexp.Regexp{regexp.MustCompile(`CodeImage\.aspx`)},
+		},
+	}, log.NewLogger())
+	if err != nil {
+		t.Skipf("browser is not available: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := dispatcher.Close(); err != nil {
+			t.Errorf("close dispatcher: %v", err)
+		}
+	})
+
+	navigator, err := dispatcher.NewNavigator("12345", "abcdef", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("new navigator: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := navigator.Close(); err != nil {
+			t.Errorf("close navigator: %v", err)
+		}
+	})
+
+	return navigator
+}
+
+func TestBrowserNavigator_Flow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		calendar fakeCalendar
+		want     bool
+	}{
+		{name: "No Free Time", calendar: fakeCalendarNoFreeTime, want: false},
+		{name: "Slots Available", calendar: fakeCalendarSlotsAvailable, want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := newFakeKdmid(t, tt.calendar)
+			navigator := newTestBrowserNavigator(t, fake.baseURL(t))
+
+			one, err := navigator.OpenPageToAuthorize()
+			if err != nil {
+				t.Fatalf("open page to authorize: %v", err)
+			}
+
+			if !one.Captcha.Presented || one.Captcha.Image.Empty() {
+				t.Fatal("expected captcha to be presented")
+			}
+
+			if len(one.HTML) == 0 || one.Screenshot.Empty() || len(one.Network) == 0 {
+				t.Error("expected html, screenshot and network artifacts")
+			}
+
+			if _, err := navigator.SubmitAuthorization(fakeCaptchaCode); err != nil {
+				t.Fatalf("submit authorization: %v", err)
+			}
+
+			three, err := navigator.OpenSlotBookingPage()
+			if err != nil {
+				t.Fatalf("open slot booking page: %v", err)
+			}
+
+			if three.SomethingInteresting != tt.want {
+				t.Errorf("expected something interesting %v, got %v", tt.want, three.SomethingInteresting)
+			}
+		})
+	}
+}
+
+func TestBrowserNavigator_WrongCaptcha(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeKdmid(t, fakeCalendarNoFreeTime)
+	navigator := newTestBrowserNavigator(t, fake.baseURL(t))
+
+	if _, err := navigator.OpenPageToAuthorize(); err != nil {
+		t.Fatalf("open page to authorize: %v", err)
+	}
+
+	_, err := navigator.SubmitAuthorization("000000")
+	if !errors.Is(err, page.ErrCaptchaNotSolved) {
+		t.Fatalf("expected ErrCaptchaNotSolved, got %v", err)
+	}
+}
+
+func TestBrowserNavigator_ErrorPage(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeKdmid(t, fakeCalendarNoFreeTime)
+	fake.broken = true
+
+	navigator := newTestBrowserNavigator(t, fake.baseURL(t))
+
+	stat, err := navigator.OpenPageToAuthorize()
+	if !errors.Is(err, page.ErrNavigationFailed) {
+		t.Fatalf("expected ErrNavigationFailed, got %v", err)
+	}
+
+	if len(stat.HTML) == 0 {
+		t.Error("expected error page html to be captured")
+	}
+}
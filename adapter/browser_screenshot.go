@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type ScreenshotScope string
+
+const (
+	ScreenshotScopeFullPage ScreenshotScope = "full_page"
+	ScreenshotScopeViewport ScreenshotScope = "viewport"
+	ScreenshotScopeElement  ScreenshotScope = "element"
+	ScreenshotScopeNone     ScreenshotScope = "none"
+)
+
+type ScreenshotPolicy struct {
+	Scope    ScreenshotScope
+	Selector string
+	Encoding image.EncodeOptions
+}
+
+type ScreenshotPolicies struct {
+	Authorize, Submit, Calendar ScreenshotPolicy
+}
+
+func (p ScreenshotPolicy) validate() error {
+	if err := p.Encoding.Validate(); err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	switch p.Scope {
+	case ScreenshotScopeFullPage, ScreenshotScopeViewport, ScreenshotScopeNone:
+		return nil
+	case ScreenshotScopeElement:
+		if p.Selector == "" {
+			return fmt.Errorf("no selector for element screenshot")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported screenshot scope `%s`", p.Scope)
+	}
+}
+
+func takeScreenshot(browserPage playwright.Page, policy ScreenshotPolicy) (image.Image, error) {
+	var (
+		screenshot []byte
+		err        error
+	)
+
+	switch policy.Scope {
+	case ScreenshotScopeNone:
+		return image.Image{}, nil
+	case ScreenshotScopeElement:
+		screenshot, err = browserPage.Locator(policy.Selector).First().Screenshot()
+	default:
+		screenshot, err = browserPage.Screenshot(playwright.PageScreenshotOptions{
+			FullPage: playwright.Bool(policy.Scope == ScreenshotScopeFullPage),
+		})
+	}
+
+	if err != nil {
+		return image.Image{}, err
+	}
+
+	encoded, err := image.Encode(image.NewPNG(screenshot), policy.Encoding)
+	if err != nil {
+		return image.Image{}, fmt.Errorf("encode screenshot: %w", err)
+	}
+
+	return encoded, nil
+}
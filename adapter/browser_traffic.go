@@ -0,0 +1,150 @@
+package adapter
+
+import (
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type ResourceBlocking struct {
+	ResourceTypes    []string
+	BlockURLPatterns []*regexp.Regexp
+	AllowURLPatterns []*regexp.Regexp
+}
+
+func (b ResourceBlocking) enabled() bool {
+	return len(b.ResourceTypes) != 0 || len(b.BlockURLPatterns) != 0
+}
+
+func (b ResourceBlocking) blocks(resourceType, requestURL string) bool {
+	for _, pattern := range b.AllowURLPatterns {
+		if pattern.MatchString(requestURL) {
+			return false
+		}
+	}
+
+	if slices.Contains(b.ResourceTypes, resourceType) {
+		return true
+	}
+
+	for _, pattern := range b.BlockURLPatterns {
+		if pattern.MatchString(requestURL) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trafficSettleTimeout bounds how long a step waits for the sizes of its
+// requests, the ones still missing are left out.
+const trafficSettleTimeout = 5 * time.Second
+
+// trafficCounter counts the requests the browser completed and the bytes it
+// received for them, blocked and failed requests only count as blocked.
+type trafficCounter struct {
+	blocking ResourceBlocking
+	traffic  page.Traffic
+	// pending counts the finished requests of the step whose sizes are still
+	// asked for, generation tells the steps apart so late sizes are dropped.
+	pending       int
+	generation    int
+	settleTimeout time.Duration
+	m             sync.Mutex
+	settled       *sync.Cond
+}
+
+func newTrafficCounter(blocking ResourceBlocking) *trafficCounter {
+	t := &trafficCounter{blocking: blocking, settleTimeout: trafficSettleTimeout}
+	t.settled = sync.NewCond(&t.m)
+
+	return t
+}
+
+func (t *trafficCounter) reset() {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.traffic = page.Traffic{}
+	t.nextGeneration()
+}
+
+func (t *trafficCounter) snapshot() page.Traffic {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	expired := false
+	timer := time.AfterFunc(t.settleTimeout, func() {
+		t.m.Lock()
+		defer t.m.Unlock()
+
+		expired = true
+		t.settled.Broadcast()
+	})
+
+	defer timer.Stop()
+
+	for t.pending > 0 && !expired {
+		t.settled.Wait()
+	}
+
+	traffic := t.traffic
+	t.nextGeneration()
+
+	return traffic
+}
+
+func (t *trafficCounter) nextGeneration() {
+	t.generation++
+	t.pending = 0
+}
+
+func (t *trafficCounter) onRoute(route playwright.Route) {
+	request := route.Request()
+
+	if !t.blocking.blocks(request.ResourceType(), request.URL()) {
+		_ = route.Continue()
+
+		return
+	}
+
+	t.m.Lock()
+	t.traffic.BlockedRequests++
+	t.m.Unlock()
+
+	_ = route.Abort("blockedbyclient")
+}
+
+// onRequestFinished asks for the sizes in its own goroutine, events are
+// emitted from the loop that would deliver the answer.
+func (t *trafficCounter) onRequestFinished(request playwright.Request) {
+	t.m.Lock()
+	t.pending++
+	generation := t.generation
+	t.m.Unlock()
+
+	go func() {
+		sizes, err := request.Sizes()
+
+		t.m.Lock()
+		defer t.m.Unlock()
+
+		if generation != t.generation {
+			return
+		}
+
+		t.traffic.Requests++
+
+		if err == nil {
+			t.traffic.Bytes += int64(max(sizes.ResponseHeadersSize, 0) + max(sizes.ResponseBodySize, 0))
+		}
+
+		t.pending--
+		t.settled.Broadcast()
+	}()
+}
@@ -0,0 +1,83 @@
+package adapter
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestResourceBlocking_blocks(t *testing.T) {
+	t.Parallel()
+
+	blocking := ResourceBlocking{
+		ResourceTypes:    []string{"font", "image"},
+		BlockURLPatterns: []*regexp.Regexp{regexp.MustCompile(`google-analytics\.com`)},
+		AllowURLPatterns: []*regexp.Regexp{regexp.MustCompile(`CodeImage\.aspx`)},
+	}
+
+	tests := []struct {
+		name         string
+		resourceType string
+		url          string
+		want         bool
+	}{
+		{"Document", "document", "https://barcelona.kdmid.ru/queue/OrderInfo.aspx", false},
+		{"Font", "font", "https://barcelona.kdmid.ru/fonts/a.woff2", true},
+		{"Image", "image", "https://barcelona.kdmid.ru/images/logo.png", true},
+		{"Captcha Image", "image", "https://barcelona.kdmid.ru/queue/CodeImage.aspx?id=1", false},
+		{"Analytics Script", "script", "https://www.google-analytics.com/analytics.js", true},
+		{"Site Script", "script", "https://barcelona.kdmid.ru/queue/WebResource.axd", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := blocking.blocks(tt.resourceType, tt.url); got != tt.want {
+				t.Errorf("blocks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type sizedRequest struct {
+	playwright.Request
+	sizes chan *playwright.RequestSizesResult
+}
+
+func (r *sizedRequest) Sizes() (*playwright.RequestSizesResult, error) {
+	return <-r.sizes, nil
+}
+
+func TestTrafficCounter_SnapshotDropsLateSizes(t *testing.T) {
+	t.Parallel()
+
+	counter := newTrafficCounter(ResourceBlocking{})
+	counter.settleTimeout = 100 * time.Millisecond
+
+	counter.reset()
+
+	quick := &sizedRequest{sizes: make(chan *playwright.RequestSizesResult, 1)}
+	quick.sizes <- &playwright.RequestSizesResult{ResponseHeadersSize: 10, ResponseBodySize: 90}
+	counter.onRequestFinished(quick)
+
+	// The answer to the hung request never comes before the snapshot.
+	hung := &sizedRequest{sizes: make(chan *playwright.RequestSizesResult)}
+	counter.onRequestFinished(hung)
+
+	traffic := counter.snapshot()
+	if traffic.Requests != 1 || traffic.Bytes != 100 {
+		t.Errorf("expected only the quick request counted, got %+v", traffic)
+	}
+
+	counter.reset()
+	hung.sizes <- &playwright.RequestSizesResult{ResponseBodySize: 1000}
+
+	if traffic := counter.snapshot(); traffic.Requests != 0 || traffic.Bytes != 0 {
+		t.Errorf("expected the late sizes left out of the next step, got %+v", traffic)
+	}
+}
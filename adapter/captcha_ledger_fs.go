@@ -0,0 +1,215 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+)
+
+type captchaLedgerFs struct {
+	storageFile string
+	cache       ledger
+	m           sync.RWMutex
+	keepDays    int
+	logger      log.Logger
+}
+
+type ledger struct {
+	Balance *ledgerBalance                    `json:"balance,omitempty"`
+	Days    map[string]map[string]ledgerSpend `json:"days"`
+}
+
+type ledgerBalance struct {
+	Amount    float64   `json:"amount"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type ledgerSpend struct {
+	Solves int     `json:"solves"`
+	Cost   float64 `json:"cost"`
+}
+
+// NewCaptchaLedgerFs keeps the ledger in a single json file, spend older than
+// keepDays days is dropped on the next write.
+func NewCaptchaLedgerFs(dir string, keepDays int, logger log.Logger) (captcha.Ledger, error) {
+	const storageFileName = "captcha_ledger.json"
+
+	fs := &captchaLedgerFs{
+		storageFile: path.Join(dir, storageFileName),
+		cache:       ledger{Days: make(map[string]map[string]ledgerSpend)},
+		keepDays:    keepDays,
+		logger:      logger,
+	}
+
+	if err := fs.readAllToCache(); err != nil && !errors.Is(err, errNoFileExists) {
+		return nil, fmt.Errorf("failed to read captcha ledger from disk: %w", err)
+	}
+
+	return fs, nil
+}
+
+func MustNewCaptchaLedgerFs(dir string, keepDays int, logger log.Logger) captcha.Ledger {
+	storage, err := NewCaptchaLedgerFs(dir, keepDays, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return storage
+}
+
+func (l *captchaLedgerFs) Record(_ context.Context, recipientID int64, at time.Time, spend captcha.Spend) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	day := ledgerDay(at)
+	recipient := strconv.FormatInt(recipientID, decimal)
+
+	if l.cache.Days[day] == nil {
+		l.cache.Days[day] = make(map[string]ledgerSpend)
+	}
+
+	daySpend := l.cache.Days[day][recipient]
+	daySpend.Solves += spend.Solves
+	daySpend.Cost += spend.Cost
+	l.cache.Days[day][recipient] = daySpend
+
+	l.dropOldDays()
+
+	if err := l.writeCache(); err != nil {
+		return fmt.Errorf("failed to write captcha ledger to disk: %w", err)
+	}
+
+	return nil
+}
+
+func (l *captchaLedgerFs) Daily(_ context.Context, day time.Time) (map[int64]captcha.Spend, error) {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	daySpend := l.cache.Days[ledgerDay(day)]
+	spend := make(map[int64]captcha.Spend, len(daySpend))
+
+	for recipient, recipientSpend := range daySpend {
+		recipientID, err := strconv.ParseInt(recipient, decimal, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("parse recipient id - `%v`: %w", recipient, err)
+		}
+
+		spend[recipientID] = captcha.Spend{
+			Solves: recipientSpend.Solves,
+			Cost:   recipientSpend.Cost,
+		}
+	}
+
+	return spend, nil
+}
+
+// ledgerDay names the local day a time falls on, crawls run at UTC times while
+// the budget is counted per local day.
+func ledgerDay(at time.Time) string {
+	return at.Local().Format(time.DateOnly)
+}
+
+func (l *captchaLedgerFs) SaveBalance(_ context.Context, balance captcha.Balance) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.cache.Balance = &ledgerBalance{
+		Amount:    balance.Amount,
+		CheckedAt: balance.CheckedAt,
+	}
+
+	if err := l.writeCache(); err != nil {
+		return fmt.Errorf("failed to write captcha ledger to disk: %w", err)
+	}
+
+	return nil
+}
+
+func (l *captchaLedgerFs) Balance(_ context.Context) (captcha.Balance, error) {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	if l.cache.Balance == nil {
+		return captcha.Balance{}, nil
+	}
+
+	return captcha.Balance{
+		Amount:    l.cache.Balance.Amount,
+		CheckedAt: l.cache.Balance.CheckedAt,
+	}, nil
+}
+
+func (l *captchaLedgerFs) dropOldDays() {
+	if l.keepDays <= 0 || len(l.cache.Days) <= l.keepDays {
+		return
+	}
+
+	days := make([]string, 0, len(l.cache.Days))
+	for day := range l.cache.Days {
+		days = append(days, day)
+	}
+
+	sort.Strings(days)
+
+	for _, day := range days[:len(days)-l.keepDays] {
+		delete(l.cache.Days, day)
+	}
+}
+
+func (l *captchaLedgerFs) writeCache() error {
+	f, err := os.Create(l.storageFile)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			l.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	if err := json.NewEncoder(f).Encode(l.cache); err != nil {
+		return fmt.Errorf("failed to write captcha ledger: %w", err)
+	}
+
+	return nil
+}
+
+func (l *captchaLedgerFs) readAllToCache() error {
+	f, err := os.Open(l.storageFile)
+	if os.IsNotExist(err) {
+		return errNoFileExists
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			l.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	if err := json.NewDecoder(f).Decode(&l.cache); err != nil {
+		return fmt.Errorf("failed to decode file: %w", err)
+	}
+
+	if l.cache.Days == nil {
+		l.cache.Days = make(map[string]map[string]ledgerSpend)
+	}
+
+	return nil
+}
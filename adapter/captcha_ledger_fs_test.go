@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+)
+
+func TestCaptchaLedgerFs_RecordAndReload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.Local)
+
+	ledger := MustNewCaptchaLedgerFs(dir, 0, log.NewLogger())
+
+	for _, spend := range []captcha.Spend{{Solves: 1, Cost: 0.001}, {Solves: 2, Cost: 0.002}} {
+		if err := ledger.Record(ctx, 42, day, spend); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	if err := ledger.Record(ctx, 7, day.AddDate(0, 0, 1), captcha.Spend{Solves: 1}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	checkedAt := day.Truncate(time.Second)
+	if err := ledger.SaveBalance(ctx, captcha.Balance{Amount: 3.5, CheckedAt: checkedAt}); err != nil {
+		t.Fatalf("save balance: %v", err)
+	}
+
+	reloaded := MustNewCaptchaLedgerFs(dir, 0, log.NewLogger())
+
+	daily, err := reloaded.Daily(ctx, day)
+	if err != nil {
+		t.Fatalf("daily: %v", err)
+	}
+
+	if len(daily) != 1 || daily[42].Solves != 3 || daily[42].Cost < 0.0029 || daily[42].Cost > 0.0031 {
+		t.Errorf("unexpected daily spend %+v", daily)
+	}
+
+	balance, err := reloaded.Balance(ctx)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+
+	if balance.Amount != 3.5 || !balance.CheckedAt.Equal(checkedAt) {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+}
+
+func TestCaptchaLedgerFs_KeepDays(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.Local)
+
+	ledger := MustNewCaptchaLedgerFs(t.TempDir(), 2, log.NewLogger())
+
+	for i := 0; i < 3; i++ {
+		if err := ledger.Record(ctx, 42, day.AddDate(0, 0, i), captcha.Spend{Solves: 1}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	for i, want := range []int{0, 1, 1} {
+		daily, err := ledger.Daily(ctx, day.AddDate(0, 0, i))
+		if err != nil {
+			t.Fatalf("daily: %v", err)
+		}
+
+		if daily[42].Solves != want {
+			t.Errorf("day %d: expected %d solves, got %d", i, want, daily[42].Solves)
+		}
+	}
+}
+
+func TestCaptchaLedgerFs_Zones(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	at := time.Date(2024, time.March, 5, 23, 30, 0, 0, time.FixedZone("west", -12*60*60))
+
+	ledger := MustNewCaptchaLedgerFs(t.TempDir(), 0, log.NewLogger())
+
+	if err := ledger.Record(ctx, 42, at.UTC(), captcha.Spend{Solves: 1}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	// The same instant read in any zone falls on the same ledger day.
+	for _, day := range []time.Time{at, at.UTC(), at.In(time.FixedZone("east", 14*60*60))} {
+		daily, err := ledger.Daily(ctx, day)
+		if err != nil {
+			t.Fatalf("daily: %v", err)
+		}
+
+		if daily[42].Solves != 1 {
+			t.Errorf("expected the spend on %s, got %+v", day, daily)
+		}
+	}
+}
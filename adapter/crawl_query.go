@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+// dayStorage is what queryDays needs from a storage keeping crawls in
+// user/date directories.
+type dayStorage interface {
+	ListUsers(ctx context.Context) ([]int64, error)
+	listDays(ctx context.Context, userID int64) ([]string, error)
+	daySummaries(ctx context.Context, userID int64, dateDirName string) ([]crawl.Summary, error)
+}
+
+// queryDays answers a query by reading the day directories the query range
+// may touch. Days are named by UTC date, and by local date for crawls saved
+// before that, a day of slack on both sides of the range keeps crawls near
+// midnight, the exact times are matched afterwards.
+func queryDays(ctx context.Context, storage dayStorage, query crawl.Query) (crawl.Page, error) {
+	userIDs := query.UserIDs
+
+	if len(userIDs) == 0 {
+		var err error
+
+		userIDs, err = storage.ListUsers(ctx)
+		if err != nil {
+			return crawl.Page{}, fmt.Errorf("list users: %w", err)
+		}
+	}
+
+	matched := make([]crawl.Summary, 0)
+
+	for _, userID := range userIDs {
+		dateDirNames, err := storage.listDays(ctx, userID)
+		if err != nil {
+			return crawl.Page{}, fmt.Errorf("list days of %d: %w", userID, err)
+		}
+
+		for _, dateDirName := range dateDirNames {
+			if !dayInRange(dateDirName, query) {
+				continue
+			}
+
+			summaries, err := storage.daySummaries(ctx, userID, dateDirName)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err != nil {
+				return crawl.Page{}, fmt.Errorf("summaries of %d/%s: %w", userID, dateDirName, err)
+			}
+
+			for i := range summaries {
+				if query.Match(&summaries[i]) {
+					matched = append(matched, summaries[i])
+				}
+			}
+		}
+	}
+
+	return query.Paginate(matched), nil
+}
+
+func dayInRange(dateDirName string, query crawl.Query) bool {
+	if !query.From.IsZero() && dateDirName < query.From.AddDate(0, 0, -1).Format(time.DateOnly) {
+		return false
+	}
+
+	return query.To.IsZero() || dateDirName <= query.To.AddDate(0, 0, 1).Format(time.DateOnly)
+}
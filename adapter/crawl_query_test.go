@@ -0,0 +1,314 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+var queryDay = time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+// saveQueryFixture stores two days of crawls for users 42 and 7.
+func saveQueryFixture(t *testing.T, storage crawl.Storage) {
+	t.Helper()
+
+	fixture := []struct {
+		userID int64
+		result crawl.Result
+	}{
+		{42, crawl.Result{RanAt: queryDay.Add(23*time.Hour + 30*time.Minute), Err: errors.New("timeout")}},
+		{42, crawl.Result{RanAt: queryDay.Add(10 * time.Hour)}},
+		{42, crawl.Result{RanAt: queryDay.Add(11 * time.Hour), SomethingInteresting: true}},
+		{42, crawl.Result{RanAt: queryDay.Add(36 * time.Hour)}},
+		{7, crawl.Result{RanAt: queryDay.Add(12 * time.Hour)}},
+	}
+
+	for i := range fixture {
+		if err := storage.Save(context.Background(), fixture[i].userID, &fixture[i].result); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+}
+
+func testCrawlStorageQuery(t *testing.T, storage crawl.Storage) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    crawl.Query
+		expected []string
+		total    int
+	}{
+		{
+			name:     "Everything",
+			query:    crawl.Query{},
+			expected: []string{"42@36h0m0s", "42@23h30m0s", "7@12h0m0s", "42@11h0m0s", "42@10h0m0s"},
+			total:    5,
+		},
+		{
+			name:     "DayOfUser",
+			query:    crawl.Query{From: queryDay, To: queryDay.AddDate(0, 0, 1), UserIDs: []int64{42}},
+			expected: []string{"42@23h30m0s", "42@11h0m0s", "42@10h0m0s"},
+			total:    3,
+		},
+		{
+			name:     "Status",
+			query:    crawl.Query{Statuses: []crawl.Status{crawl.StatusError, crawl.StatusInteresting}},
+			expected: []string{"42@23h30m0s", "42@11h0m0s"},
+			total:    2,
+		},
+		{
+			name:     "PageOldestFirst",
+			query:    crawl.Query{Order: crawl.OldestFirst, Offset: 1, Limit: 2},
+			expected: []string{"42@11h0m0s", "7@12h0m0s"},
+			total:    5,
+		},
+		{
+			name:     "UnknownUser",
+			query:    crawl.Query{UserIDs: []int64{1}},
+			expected: []string{},
+			total:    0,
+		},
+	}
+
+	for _, test := range tests {
+		page, err := storage.Query(ctx, test.query)
+		if err != nil {
+			t.Fatalf("%s: query: %v", test.name, err)
+		}
+
+		got := make([]string, 0, len(page.Summaries))
+		for _, summary := range page.Summaries {
+			got = append(got, queryLabel(summary))
+		}
+
+		if !reflect.DeepEqual(got, test.expected) || page.Total != test.total {
+			t.Errorf("%s: expected %v of %d, got %v of %d", test.name, test.expected, test.total, got, page.Total)
+		}
+	}
+}
+
+func queryLabel(summary crawl.Summary) string {
+	return fmt.Sprintf("%d@%s", summary.UserID, summary.RanAt.Sub(queryDay))
+}
+
+func TestFileSystemCrawlStorage_Query(t *testing.T) {
+	t.Parallel()
+
+	storage := MustNewFileSystemCrawlStorage(t.TempDir(), ArchiveZstd, log.NewLogger())
+	saveQueryFixture(t, storage)
+
+	testCrawlStorageQuery(t, storage)
+
+	if _, err := storage.(crawl.Archiver).Archive(context.Background(), queryDay.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	testCrawlStorageQuery(t, storage)
+}
+
+func TestSQLiteCrawlStorage_Query(t *testing.T) {
+	t.Parallel()
+
+	storage := newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{Path: filepath.Join(t.TempDir(), "crawls.db")})
+	saveQueryFixture(t, storage)
+
+	testCrawlStorageQuery(t, storage)
+}
+
+func TestS3CrawlStorage_Query(t *testing.T) {
+	t.Parallel()
+
+	storage := newTestS3CrawlStorage(t, "kdmid")
+	saveQueryFixture(t, storage)
+
+	testCrawlStorageQuery(t, storage)
+}
+
+func testCrawlStorageArtifacts(t *testing.T, storage crawl.Storage, ranAt time.Time) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	screenshot := image.Image{MIMEType: image.MIMETypeWebP, Bytes: bytes.Repeat([]byte("s"), 64)}
+	captchaImage := image.Image{MIMEType: image.MIMETypePNG, Bytes: []byte("captcha")}
+	attemptImage := image.Image{MIMEType: image.MIMETypeJPEG, Bytes: []byte("attempt")}
+
+	if err := storage.Save(ctx, 42, &crawl.Result{
+		One: page.Stat{
+			HTML:       []byte("<html>one</html>"),
+			Screenshot: screenshot,
+			Captcha:    page.Captcha{Presented: true, Image: captchaImage},
+		},
+		RanAt: ranAt,
+		Attempts: []crawl.CaptchaAttempt{
+			{Image: attemptImage, Code: "123456", Solver: "ocr", Verdict: crawl.VerdictAccepted},
+		},
+	}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	crawlPage, err := storage.Query(ctx, crawl.Query{UserIDs: []int64{42}})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	if len(crawlPage.Summaries) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(crawlPage.Summaries))
+	}
+
+	summary := crawlPage.Summaries[0]
+
+	if len(summary.Attempts) != 1 || !summary.Attempts[0].Image.Empty() || summary.SubmittedCode() != "123456" {
+		t.Errorf("expected the attempt without its image, got %+v", summary.Attempts)
+	}
+
+	expected := []struct {
+		kind     crawl.ArtifactKind
+		index    int
+		mimeType string
+		bytes    []byte
+	}{
+		{crawl.ArtifactHTML, 1, htmlMIMEType, []byte("<html>one</html>")},
+		{crawl.ArtifactScreenshot, 1, screenshot.MIMEType, screenshot.Bytes},
+		{crawl.ArtifactCaptcha, 1, captchaImage.MIMEType, captchaImage.Bytes},
+		{crawl.ArtifactAttemptCaptcha, 1, attemptImage.MIMEType, attemptImage.Bytes},
+	}
+
+	for _, artifact := range expected {
+		ref, ok := summary.Artifact(artifact.kind, artifact.index)
+		if !ok || ref.MIMEType != artifact.mimeType {
+			t.Errorf("expected %s %d of %s, got %+v", artifact.kind, artifact.index, artifact.mimeType, summary.Artifacts)
+
+			continue
+		}
+
+		artifactBytes, err := storage.Artifact(ctx, ref)
+		if err != nil {
+			t.Errorf("artifact %s: %v", artifact.kind, err)
+
+			continue
+		}
+
+		if !bytes.Equal(artifactBytes, artifact.bytes) {
+			t.Errorf("expected %s bytes %q, got %q", artifact.kind, artifact.bytes, artifactBytes)
+		}
+	}
+
+	if _, ok := summary.Artifact(crawl.ArtifactScreenshot, 2); ok {
+		t.Errorf("expected no screenshot of the second step, got %+v", summary.Artifacts)
+	}
+
+	missing := crawl.ArtifactRef{Crawl: summary.Ref, Kind: crawl.ArtifactScreenshot, Index: 2, MIMEType: image.MIMETypePNG}
+	if _, err := storage.Artifact(ctx, missing); !errors.Is(err, crawl.ErrArtifactNotFound) {
+		t.Errorf("expected a missing artifact to be crawl.ErrArtifactNotFound, got %v", err)
+	}
+
+	for _, invalid := range []crawl.ArtifactRef{
+		{Crawl: summary.Ref, Kind: "page", Index: 1},
+		{Crawl: "../" + summary.Ref, Kind: crawl.ArtifactHTML, Index: 1},
+	} {
+		if _, err := storage.Artifact(ctx, invalid); !errors.Is(err, crawl.ErrInvalidArtifactRef) {
+			t.Errorf("expected %+v to be crawl.ErrInvalidArtifactRef, got %v", invalid, err)
+		}
+	}
+}
+
+func TestFileSystemCrawlStorage_Artifacts(t *testing.T) {
+	t.Parallel()
+
+	storage := MustNewFileSystemCrawlStorage(t.TempDir(), ArchiveGzip, log.NewLogger())
+	testCrawlStorageArtifacts(t, storage, queryDay)
+
+	if _, err := storage.(crawl.Archiver).Archive(context.Background(), queryDay.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	crawlPage, err := storage.Query(context.Background(), crawl.Query{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	ref, ok := crawlPage.Summaries[0].Artifact(crawl.ArtifactScreenshot, 1)
+	if !ok {
+		t.Fatalf("expected an archived screenshot, got %+v", crawlPage.Summaries[0].Artifacts)
+	}
+
+	if artifactBytes, err := storage.Artifact(context.Background(), ref); err != nil || len(artifactBytes) != 64 {
+		t.Errorf("expected the archived screenshot, got %d bytes, %v", len(artifactBytes), err)
+	}
+
+	if entries := storage.(*fileSystemCrawlStorage).archives.entries; len(entries) != 1 {
+		t.Errorf("expected the archived day cached, got %d entries", len(entries))
+	}
+
+	// The cached day is read again once its archive is rewritten.
+	fresh := crawl.Result{RanAt: queryDay.Add(time.Hour), One: page.Stat{HTML: []byte("<html>fresh</html>")}}
+	if err := storage.Save(context.Background(), 42, &fresh); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := storage.(crawl.Archiver).Archive(context.Background(), queryDay.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	crawlPage, err = storage.Query(context.Background(), crawl.Query{Order: crawl.OldestFirst})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	freshRef, ok := crawlPage.Summaries[1].Artifact(crawl.ArtifactHTML, 1)
+	if !ok {
+		t.Fatalf("expected the fresh crawl archived, got %+v", crawlPage.Summaries)
+	}
+
+	if artifactBytes, err := storage.Artifact(context.Background(), freshRef); err != nil ||
+		string(artifactBytes) != "<html>fresh</html>" {
+		t.Errorf("expected the fresh crawl page, got %q, %v", artifactBytes, err)
+	}
+}
+
+func TestSQLiteCrawlStorage_Artifacts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	storage := newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{
+		Path:          filepath.Join(dir, "crawls.db"),
+		BlobDirectory: filepath.Join(dir, "blobs"),
+		MaxBlobSize:   16,
+	})
+
+	testCrawlStorageArtifacts(t, storage, queryDay)
+}
+
+func TestS3CrawlStorage_Artifacts(t *testing.T) {
+	t.Parallel()
+
+	testCrawlStorageArtifacts(t, newTestS3CrawlStorage(t, ""), queryDay)
+}
+
+func TestFileSystemCrawlStorage_ArtifactOutsideStorage(t *testing.T) {
+	t.Parallel()
+
+	storage := MustNewFileSystemCrawlStorage(t.TempDir(), ArchiveZstd, log.NewLogger())
+
+	for _, crawlRef := range []string{"../42/2024-03-05/10:00:00", "42/2024-03-05/..", "42/../10:00:00", "/etc/passwd"} {
+		ref := crawl.ArtifactRef{Crawl: crawlRef, Kind: crawl.ArtifactHTML, Index: 1}
+		if _, err := storage.Artifact(context.Background(), ref); err == nil {
+			t.Errorf("expected %s to be refused", crawlRef)
+		}
+	}
+}
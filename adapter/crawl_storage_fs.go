@@ -2,23 +2,31 @@ package adapter
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/truewebber/gopkg/log"
 
 	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 	"github.com/truewebber/kdmid-queue-checker/domain/page"
 )
 
 type fileSystemCrawlStorage struct {
-	dir    string
-	logger log.Logger
+	dir           string
+	archiveFormat ArchiveFormat
+	archives      *archiveCache
+	logger        log.Logger
 }
 
 const (
@@ -26,19 +34,25 @@ const (
 	bitSize = 64
 )
 
-func NewFileSystemCrawlStorage(dir string, logger log.Logger) (crawl.Storage, error) {
+func NewFileSystemCrawlStorage(dir string, archiveFormat ArchiveFormat, logger log.Logger) (crawl.Storage, error) {
+	if _, ok := archiveExtensions[archiveFormat]; !ok {
+		return nil, fmt.Errorf("unsupported archive format `%s`", archiveFormat)
+	}
+
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("create home directory: %w", err)
 	}
 
 	return &fileSystemCrawlStorage{
-		logger: logger,
-		dir:    dir,
+		logger:        logger,
+		dir:           dir,
+		archiveFormat: archiveFormat,
+		archives:      &archiveCache{},
 	}, nil
 }
 
-func MustNewFileSystemCrawlStorage(dir string, logger log.Logger) crawl.Storage {
-	storage, err := NewFileSystemCrawlStorage(dir, logger)
+func MustNewFileSystemCrawlStorage(dir string, archiveFormat ArchiveFormat, logger log.Logger) crawl.Storage {
+	storage, err := NewFileSystemCrawlStorage(dir, archiveFormat, logger)
 	if err != nil {
 		panic(err)
 	}
@@ -47,75 +61,298 @@ func MustNewFileSystemCrawlStorage(dir string, logger log.Logger) crawl.Storage
 }
 
 func (f *fileSystemCrawlStorage) Save(_ context.Context, userID int64, result *crawl.Result) error {
-	userDirName := strconv.FormatInt(userID, decimal)
-	dateDirName := result.RanAt.Format(time.DateOnly)
-	timeDirName := result.RanAt.Format(time.TimeOnly)
+	if result.ID == "" {
+		result.ID = crawl.NewID()
+	}
+
+	crawlDir := filepath.ToSlash(filepath.Join(f.dir, crawlPath(userID, result)))
+
+	return writeCrawl(f.saveFile, crawlDir, result)
+}
+
+// crawlFileWriter stores a file of a crawl, paths are slash separated.
+type crawlFileWriter func(filePath string, fileBytes []byte) error
 
-	crawlDir := filepath.Join(f.dir, userDirName, dateDirName, timeDirName)
+const (
+	// crawlTimeLayout names a crawl directory in its UTC date directory, the
+	// crawl ID follows the separator so crawls of the same instant never share
+	// a directory.
+	crawlTimeLayout    = "15:04:05.000000000"
+	crawlDirSeparator  = "Z_"
+	legacyCrawlTimeLen = len(time.TimeOnly)
+)
+
+// crawlPath is the user/date/time_id layout crawls are stored under, in UTC.
+func crawlPath(userID int64, result *crawl.Result) string {
+	ranAt := result.RanAt.UTC()
+
+	return path.Join(
+		strconv.FormatInt(userID, decimal),
+		ranAt.Format(time.DateOnly),
+		ranAt.Format(crawlTimeLayout)+crawlDirSeparator+result.ID,
+	)
+}
+
+// parseCrawlDirName tells when a crawl ran from its directory names. Crawls
+// saved before the UTC layout are named after the local second they ran at.
+func parseCrawlDirName(dateDirName, timeDirName string) (time.Time, error) {
+	if timePart, _, ok := strings.Cut(timeDirName, crawlDirSeparator); ok {
+		return time.ParseInLocation(time.DateOnly+" "+crawlTimeLayout, dateDirName+" "+timePart, time.UTC)
+	}
 
-	firstDir := filepath.Join(crawlDir, "1")
-	if err := f.saveStat(firstDir, result.One); err != nil {
+	return time.ParseInLocation(time.DateTime, dateDirName+" "+timeDirName, time.Local)
+}
+
+func isLegacyCrawlDir(timeDirName string) bool {
+	return len(timeDirName) == legacyCrawlTimeLen && !strings.Contains(timeDirName, crawlDirSeparator)
+}
+
+func writeCrawl(write crawlFileWriter, crawlDir string, result *crawl.Result) error {
+	firstDir := path.Join(crawlDir, "1")
+	if err := writeStat(write, firstDir, result.One); err != nil {
 		return fmt.Errorf("save first stat: %w", err)
 	}
 
-	twoDir := filepath.Join(crawlDir, "2")
-	if err := f.saveStat(twoDir, result.Two); err != nil {
+	twoDir := path.Join(crawlDir, "2")
+	if err := writeStat(write, twoDir, result.Two); err != nil {
 		return fmt.Errorf("save second stat: %w", err)
 	}
 
-	threeDir := filepath.Join(crawlDir, "3")
-	if err := f.saveStat(threeDir, result.Three); err != nil {
+	threeDir := path.Join(crawlDir, "3")
+	if err := writeStat(write, threeDir, result.Three); err != nil {
 		return fmt.Errorf("save third stat: %w", err)
 	}
 
 	if result.Err != nil {
-		errFile := filepath.Join(crawlDir, "error.txt")
-		if err := f.saveFile(errFile, []byte(result.Err.Error())); err != nil {
+		errFile := path.Join(crawlDir, "error.txt")
+		if err := write(errFile, []byte(result.Err.Error())); err != nil {
 			return fmt.Errorf("save error file: %w", err)
 		}
 	}
 
+	if result.Proxy != "" {
+		proxyFile := path.Join(crawlDir, "proxy.txt")
+		if err := write(proxyFile, []byte(result.Proxy)); err != nil {
+			return fmt.Errorf("save proxy file: %w", err)
+		}
+	}
+
+	if result.CaptchaSolver != "" {
+		solverFile := path.Join(crawlDir, "solver.txt")
+		if err := write(solverFile, []byte(result.CaptchaSolver)); err != nil {
+			return fmt.Errorf("save solver file: %w", err)
+		}
+	}
+
+	if err := writeAttempts(write, crawlDir, result.Attempts); err != nil {
+		return fmt.Errorf("save attempts: %w", err)
+	}
+
 	if result.SomethingInteresting {
-		interestingFile := filepath.Join(crawlDir, "interesting.txt")
-		if err := f.saveFile(interestingFile, []byte{}); err != nil {
+		interestingFile := path.Join(crawlDir, "interesting.txt")
+		if err := write(interestingFile, []byte{}); err != nil {
 			return fmt.Errorf("save interesting file: %w", err)
 		}
 	}
 
+	manifestBytes, err := json.Marshal(newManifest(result))
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	manifestFile := path.Join(crawlDir, "manifest.json")
+	if err := write(manifestFile, manifestBytes); err != nil {
+		return fmt.Errorf("save manifest file: %w", err)
+	}
+
 	return nil
 }
 
-func (f *fileSystemCrawlStorage) saveStat(dir string, stat page.Stat) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("create directory: %w", err)
+// manifest keeps the crawl metadata that has no file of its own. Crawls
+// saved before manifests existed are read from the marker files alone.
+type manifest struct {
+	ID            string            `json:"id"`
+	RanAt         time.Time         `json:"ran_at"`
+	Retries       int               `json:"retries"`
+	Durations     manifestDurations `json:"durations"`
+	Proxy         string            `json:"proxy,omitempty"`
+	CaptchaSolver string            `json:"captcha_solver,omitempty"`
+	SubmittedCode string            `json:"submitted_code,omitempty"`
+	AppVersion    string            `json:"app_version,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Interesting   bool              `json:"interesting"`
+}
+
+type manifestDurations struct {
+	Authorize    string `json:"authorize"`
+	CaptchaSolve string `json:"captcha_solve"`
+	Submit       string `json:"submit"`
+	Calendar     string `json:"calendar"`
+	Total        string `json:"total"`
+}
+
+func newManifest(result *crawl.Result) manifest {
+	m := manifest{
+		ID:      result.ID,
+		RanAt:   result.RanAt.UTC(),
+		Retries: result.Retries,
+		Durations: manifestDurations{
+			Authorize:    result.Durations.Authorize.String(),
+			CaptchaSolve: result.Durations.CaptchaSolve.String(),
+			Submit:       result.Durations.Submit.String(),
+			Calendar:     result.Durations.Calendar.String(),
+			Total:        result.Durations.Total.String(),
+		},
+		Proxy:         result.Proxy,
+		CaptchaSolver: result.CaptchaSolver,
+		SubmittedCode: result.SubmittedCode(),
+		AppVersion:    result.AppVersion,
+		Interesting:   result.SomethingInteresting,
+	}
+
+	if result.Err != nil {
+		m.Error = result.Err.Error()
+	}
+
+	return m
+}
+
+func (m manifest) apply(result *crawl.Result) error {
+	durations := []struct {
+		value string
+		to    *time.Duration
+	}{
+		{m.Durations.Authorize, &result.Durations.Authorize},
+		{m.Durations.CaptchaSolve, &result.Durations.CaptchaSolve},
+		{m.Durations.Submit, &result.Durations.Submit},
+		{m.Durations.Calendar, &result.Durations.Calendar},
+		{m.Durations.Total, &result.Durations.Total},
 	}
 
-	htmlFile := filepath.Join(dir, "page.html")
-	if err := f.saveFile(htmlFile, stat.HTML); err != nil {
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return fmt.Errorf("parse duration `%s`: %w", duration.value, err)
+		}
+
+		*duration.to = parsed
+	}
+
+	result.ID = m.ID
+	result.RanAt = m.RanAt.UTC()
+	result.Retries = m.Retries
+	result.AppVersion = m.AppVersion
+
+	return nil
+}
+
+func writeStat(write crawlFileWriter, dir string, stat page.Stat) error {
+	htmlFile := path.Join(dir, "page.html")
+	if err := write(htmlFile, stat.HTML); err != nil {
 		return fmt.Errorf("save html file: %w", err)
 	}
 
-	networkFile := filepath.Join(dir, "network.txt")
-	if err := f.saveFile(networkFile, stat.Network); err != nil {
+	networkFile := path.Join(dir, "network.txt")
+	if err := write(networkFile, stat.Network); err != nil {
 		return fmt.Errorf("save network file: %w", err)
 	}
 
-	screenshotFile := filepath.Join(dir, "screenshot.png")
-	if err := f.saveFile(screenshotFile, stat.Screenshot); err != nil {
-		return fmt.Errorf("save screenshot file: %w", err)
+	if !stat.Screenshot.Empty() {
+		screenshotFile := path.Join(dir, "screenshot."+stat.Screenshot.Extension())
+		if err := write(screenshotFile, stat.Screenshot.Bytes); err != nil {
+			return fmt.Errorf("save screenshot file: %w", err)
+		}
 	}
 
 	if stat.Captcha.Presented {
-		captchaFile := filepath.Join(dir, "captcha.png")
-		if err := f.saveFile(captchaFile, stat.Captcha.Image); err != nil {
+		captchaFile := path.Join(dir, "captcha."+stat.Captcha.Image.Extension())
+		if err := write(captchaFile, stat.Captcha.Image.Bytes); err != nil {
 			return fmt.Errorf("save captcha file: %w", err)
 		}
 	}
 
+	trafficBytes, err := json.Marshal(traffic{
+		Requests:        stat.Traffic.Requests,
+		BlockedRequests: stat.Traffic.BlockedRequests,
+		Bytes:           stat.Traffic.Bytes,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal traffic: %w", err)
+	}
+
+	trafficFile := path.Join(dir, "traffic.json")
+	if err := write(trafficFile, trafficBytes); err != nil {
+		return fmt.Errorf("save traffic file: %w", err)
+	}
+
+	return nil
+}
+
+func writeAttempts(write crawlFileWriter, crawlDir string, attempts []crawl.CaptchaAttempt) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	records := make([]attempt, 0, len(attempts))
+
+	for i := range attempts {
+		if !attempts[i].Image.Empty() {
+			attemptDir := path.Join(crawlDir, "attempts", strconv.Itoa(i+1))
+			captchaFile := path.Join(attemptDir, "captcha."+attempts[i].Image.Extension())
+			if err := write(captchaFile, attempts[i].Image.Bytes); err != nil {
+				return fmt.Errorf("save captcha file: %w", err)
+			}
+		}
+
+		records = append(records, attempt{
+			Code:          attempts[i].Code,
+			Solver:        attempts[i].Solver,
+			Preprocessing: attempts[i].Preprocessing,
+			Confidence:    attempts[i].Confidence,
+			Cost:          attempts[i].Cost,
+			Verdict:       string(attempts[i].Verdict),
+		})
+	}
+
+	attemptsBytes, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("marshal attempts: %w", err)
+	}
+
+	attemptsFile := path.Join(crawlDir, "attempts.json")
+	if err := write(attemptsFile, attemptsBytes); err != nil {
+		return fmt.Errorf("save attempts file: %w", err)
+	}
+
 	return nil
 }
 
+type attempt struct {
+	Code          string  `json:"code"`
+	Solver        string  `json:"solver"`
+	Preprocessing string  `json:"preprocessing,omitempty"`
+	Confidence    float64 `json:"confidence"`
+	Cost          float64 `json:"cost,omitempty"`
+	Verdict       string  `json:"verdict"`
+}
+
+type traffic struct {
+	Requests        int   `json:"requests"`
+	BlockedRequests int   `json:"blocked_requests"`
+	Bytes           int64 `json:"bytes"`
+}
+
 func (f *fileSystemCrawlStorage) saveFile(filePath string, fileBytes []byte) error {
+	filePath = filepath.FromSlash(filePath)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
 	fd, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("create file: %w", err)
@@ -163,13 +400,145 @@ func (f *fileSystemCrawlStorage) ListResults(
 	ctx context.Context, userID int64, date time.Time,
 ) ([]crawl.Result, error) {
 	userDirName := strconv.FormatInt(userID, decimal)
-	dateDirName := date.Format(time.DateOnly)
 
-	dailyCrawlsDir := filepath.Join(f.dir, userDirName, dateDirName)
+	return readCalendarDay(ctx, date, func(dateDirName string) (fs.FS, error) {
+		return f.openDay(userDirName, dateDirName, nil)
+	})
+}
 
-	crawlTimes, osErr := os.ReadDir(dailyCrawlsDir)
-	if osErr != nil {
-		return nil, fmt.Errorf("read directory: %w", osErr)
+// readCalendarDay reads the crawls of the calendar day date falls on in its
+// own zone. Days are stored by UTC date, and by local date before that, so the
+// neighbouring days are read too and cut to the calendar day. fs.ErrNotExist
+// is returned when the day has no crawls.
+func readCalendarDay(
+	ctx context.Context, date time.Time, openDay func(dateDirName string) (fs.FS, error),
+) ([]crawl.Result, error) {
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	to := from.AddDate(0, 0, 1)
+
+	crawlResults := make([]crawl.Result, 0)
+
+	for day := from.AddDate(0, 0, -1); day.Before(to.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		dateDirName := day.Format(time.DateOnly)
+
+		dailyCrawls, err := openDay(dateDirName)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("open day %s: %w", dateDirName, err)
+		}
+
+		dayResults, err := readDay(ctx, dailyCrawls, dateDirName)
+		if err != nil {
+			return nil, fmt.Errorf("read day %s: %w", dateDirName, err)
+		}
+
+		for i := range dayResults {
+			if !dayResults[i].RanAt.Before(from) && dayResults[i].RanAt.Before(to) {
+				crawlResults = append(crawlResults, dayResults[i])
+			}
+		}
+	}
+
+	if len(crawlResults) == 0 {
+		return nil, fmt.Errorf("no crawls on %s: %w", from.Format(time.DateOnly), fs.ErrNotExist)
+	}
+
+	sort.SliceStable(crawlResults, func(i, j int) bool {
+		return crawlResults[i].RanAt.Before(crawlResults[j].RanAt)
+	})
+
+	return crawlResults, nil
+}
+
+func (f *fileSystemCrawlStorage) daySummaries(
+	ctx context.Context, userID int64, dateDirName string,
+) ([]crawl.Summary, error) {
+	dailyCrawls, err := f.openDay(strconv.FormatInt(userID, decimal), dateDirName, isMetadataFile)
+	if err != nil {
+		return nil, fmt.Errorf("open day: %w", err)
+	}
+
+	return readDaySummaries(ctx, dailyCrawls, userID, dateDirName)
+}
+
+func (f *fileSystemCrawlStorage) Artifact(ctx context.Context, ref crawl.ArtifactRef) ([]byte, error) {
+	userDirName, dateDirName, timeDirName, err := parseCrawlRef(ref.Crawl)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactFile, err := artifactPath(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath := path.Join(timeDirName, artifactFile)
+
+	dailyCrawls, err := f.openDay(userDirName, dateDirName, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("open day: %w", crawl.ErrArtifactNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open day: %w", err)
+	}
+
+	artifactBytes, err := fs.ReadFile(dailyCrawls, filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read %s: %w", filePath, crawl.ErrArtifactNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", filePath, err)
+	}
+
+	return artifactBytes, nil
+}
+
+func (f *fileSystemCrawlStorage) Query(ctx context.Context, query crawl.Query) (crawl.Page, error) {
+	return queryDays(ctx, f, query)
+}
+
+// listDays names the days of a user, packed or not, in order.
+func (f *fileSystemCrawlStorage) listDays(_ context.Context, userID int64) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(f.dir, strconv.FormatInt(userID, decimal)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read user directory: %w", err)
+	}
+
+	dateDirNames := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		dateDirName := entry.Name()
+
+		if !entry.IsDir() {
+			var isArchive bool
+			if dateDirName, _, isArchive = archiveFormatOf(dateDirName); !isArchive {
+				continue
+			}
+		}
+
+		dateDirNames = append(dateDirNames, dateDirName)
+	}
+
+	slices.Sort(dateDirNames)
+
+	return slices.Compact(dateDirNames), nil
+}
+
+// readDay reads every crawl of a day, the crawl directories are named after
+// the time the crawls ran at.
+func readDay(ctx context.Context, dailyCrawls fs.FS, dateDirName string) ([]crawl.Result, error) {
+	crawlTimes, err := fs.ReadDir(dailyCrawls, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
 	}
 
 	crawlResults := make([]crawl.Result, 0, len(crawlTimes))
@@ -179,16 +548,13 @@ func (f *fileSystemCrawlStorage) ListResults(
 			continue
 		}
 
-		crawlResultDir := filepath.Join(dailyCrawlsDir, crawlTimes[i].Name())
-
-		crawlResult, err := f.readCrawl(ctx, crawlResultDir)
+		crawlResult, err := readCrawl(ctx, dailyCrawls, crawlTimes[i].Name())
 		if err != nil {
 			return nil, fmt.Errorf("read crawl: %w", err)
 		}
 
-		crawlResult.RanAt, err = time.Parse(time.DateTime, dateDirName+" "+crawlTimes[i].Name())
-		if err != nil {
-			return nil, fmt.Errorf("time parse: %w", err)
+		if err := setRanAtFromDir(&crawlResult, dateDirName, crawlTimes[i].Name()); err != nil {
+			return nil, err
 		}
 
 		crawlResults = append(crawlResults, crawlResult)
@@ -197,32 +563,79 @@ func (f *fileSystemCrawlStorage) ListResults(
 	return crawlResults, nil
 }
 
-func (f *fileSystemCrawlStorage) readCrawl(ctx context.Context, crawlDir string) (crawl.Result, error) {
-	var (
-		result = crawl.Result{}
-		err    error
-	)
+// openDay reads the day directory, or its archive once the day is packed.
+// Only the archived files keep accepts are loaded, the others read empty, a
+// nil keep loads everything.
+func (f *fileSystemCrawlStorage) openDay(userDirName, dateDirName string, keep func(string) bool) (fs.FS, error) {
+	dailyCrawlsDir := filepath.Join(f.dir, userDirName, dateDirName)
+
+	_, err := os.Stat(dailyCrawlsDir)
+	if err == nil {
+		return os.DirFS(dailyCrawlsDir), nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("stat directory: %w", err)
+	}
+
+	archive, archiveErr := f.openArchive(filepath.Join(f.dir, userDirName), dateDirName, keep)
+	if errors.Is(archiveErr, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	if archiveErr != nil {
+		return nil, fmt.Errorf("open archive: %w", archiveErr)
+	}
+
+	return archive, nil
+}
 
-	firstDir := filepath.Join(crawlDir, "1")
-	result.One, err = f.readStat(ctx, firstDir)
+func readCrawl(ctx context.Context, fsys fs.FS, crawlDir string) (crawl.Result, error) {
+	result, err := readCrawlMetadata(ctx, fsys, crawlDir)
+	if err != nil {
+		return crawl.Result{}, err
+	}
+
+	for i := range result.Attempts {
+		attemptDir := path.Join(crawlDir, "attempts", strconv.Itoa(i+1))
+
+		result.Attempts[i].Image, err = readImage(ctx, fsys, attemptDir, "captcha")
+		if err != nil {
+			return crawl.Result{}, fmt.Errorf("read attempt captcha file: %w", err)
+		}
+	}
+
+	firstDir := path.Join(crawlDir, "1")
+	result.One, err = readStat(ctx, fsys, firstDir)
 	if err != nil {
 		return crawl.Result{}, fmt.Errorf("read first stat: %w", err)
 	}
 
-	twoDir := filepath.Join(crawlDir, "2")
-	result.Two, err = f.readStat(ctx, twoDir)
+	twoDir := path.Join(crawlDir, "2")
+	result.Two, err = readStat(ctx, fsys, twoDir)
 	if err != nil {
 		return crawl.Result{}, fmt.Errorf("read second stat: %w", err)
 	}
 
-	threeDir := filepath.Join(crawlDir, "3")
-	result.Three, err = f.readStat(ctx, threeDir)
+	threeDir := path.Join(crawlDir, "3")
+	result.Three, err = readStat(ctx, fsys, threeDir)
 	if err != nil {
 		return crawl.Result{}, fmt.Errorf("save third stat: %w", err)
 	}
 
-	errorFile := filepath.Join(crawlDir, "error.txt")
-	errText, err := f.readFile(ctx, errorFile)
+	return result, nil
+}
+
+// readCrawlMetadata reads everything but the artifacts, attempts come without
+// their images.
+func readCrawlMetadata(ctx context.Context, fsys fs.FS, crawlDir string) (crawl.Result, error) {
+	var (
+		result = crawl.Result{}
+		err    error
+	)
+
+	errorFile := path.Join(crawlDir, "error.txt")
+	errText, err := readFile(ctx, fsys, errorFile)
 	if err != nil {
 		return crawl.Result{}, fmt.Errorf("read error file: %w", err)
 	}
@@ -231,78 +644,176 @@ func (f *fileSystemCrawlStorage) readCrawl(ctx context.Context, crawlDir string)
 		result.Err = fmt.Errorf(string(errText))
 	}
 
-	interestingFile := filepath.Join(crawlDir, "interesting.txt")
-	result.SomethingInteresting, err = f.fileExists(ctx, interestingFile)
+	proxyFile := path.Join(crawlDir, "proxy.txt")
+	proxyName, err := readFile(ctx, fsys, proxyFile)
+	if err != nil {
+		return crawl.Result{}, fmt.Errorf("read proxy file: %w", err)
+	}
+
+	result.Proxy = string(proxyName)
+
+	solverFile := path.Join(crawlDir, "solver.txt")
+	solverName, err := readFile(ctx, fsys, solverFile)
+	if err != nil {
+		return crawl.Result{}, fmt.Errorf("read solver file: %w", err)
+	}
+
+	result.CaptchaSolver = string(solverName)
+
+	result.Attempts, err = readAttempts(ctx, fsys, crawlDir)
+	if err != nil {
+		return crawl.Result{}, fmt.Errorf("read attempts: %w", err)
+	}
+
+	interestingFile := path.Join(crawlDir, "interesting.txt")
+	result.SomethingInteresting, err = fileExists(ctx, fsys, interestingFile)
 	if err != nil {
 		return crawl.Result{}, fmt.Errorf("check interesting file exists: %w", err)
 	}
 
+	manifestFile := path.Join(crawlDir, "manifest.json")
+	manifestBytes, err := readFile(ctx, fsys, manifestFile)
+	if err != nil {
+		return crawl.Result{}, fmt.Errorf("read manifest file: %w", err)
+	}
+
+	if len(manifestBytes) > 0 {
+		crawlManifest := manifest{}
+		if err := json.Unmarshal(manifestBytes, &crawlManifest); err != nil {
+			return crawl.Result{}, fmt.Errorf("unmarshal manifest: %w", err)
+		}
+
+		if err := crawlManifest.apply(&result); err != nil {
+			return crawl.Result{}, fmt.Errorf("apply manifest: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
-func (f *fileSystemCrawlStorage) readStat(ctx context.Context, statDir string) (page.Stat, error) {
+func readStat(ctx context.Context, fsys fs.FS, statDir string) (page.Stat, error) {
 	var (
 		stat = page.Stat{}
 		err  error
 	)
 
-	htmlFile := filepath.Join(statDir, "page.html")
-	stat.HTML, err = f.readFile(ctx, htmlFile)
+	htmlFile := path.Join(statDir, "page.html")
+	stat.HTML, err = readFile(ctx, fsys, htmlFile)
 	if err != nil {
 		return page.Stat{}, fmt.Errorf("read html file: %w", err)
 	}
 
-	networkFile := filepath.Join(statDir, "network.txt")
-	stat.Network, err = f.readFile(ctx, networkFile)
+	networkFile := path.Join(statDir, "network.txt")
+	stat.Network, err = readFile(ctx, fsys, networkFile)
 	if err != nil {
 		return page.Stat{}, fmt.Errorf("read network file: %w", err)
 	}
 
-	screenshotFile := filepath.Join(statDir, "screenshot.png")
-	stat.Screenshot, err = f.readFile(ctx, screenshotFile)
+	stat.Screenshot, err = readImage(ctx, fsys, statDir, "screenshot")
 	if err != nil {
 		return page.Stat{}, fmt.Errorf("read screenshot file: %w", err)
 	}
 
-	captchaFile := filepath.Join(statDir, "captcha.png")
-	stat.Captcha.Image, err = f.readFile(ctx, captchaFile)
+	stat.Captcha.Image, err = readImage(ctx, fsys, statDir, "captcha")
 	if err != nil {
-		return page.Stat{}, fmt.Errorf("read screenshot file: %w", err)
+		return page.Stat{}, fmt.Errorf("read captcha file: %w", err)
+	}
+
+	stat.Captcha.Presented = !stat.Captcha.Image.Empty()
+
+	trafficFile := path.Join(statDir, "traffic.json")
+	trafficBytes, err := readFile(ctx, fsys, trafficFile)
+	if err != nil {
+		return page.Stat{}, fmt.Errorf("read traffic file: %w", err)
 	}
 
-	stat.Captcha.Presented = stat.Captcha.Image != nil
+	if len(trafficBytes) > 0 {
+		statTraffic := traffic{}
+		if err := json.Unmarshal(trafficBytes, &statTraffic); err != nil {
+			return page.Stat{}, fmt.Errorf("unmarshal traffic: %w", err)
+		}
+
+		stat.Traffic = page.Traffic{
+			Requests:        statTraffic.Requests,
+			BlockedRequests: statTraffic.BlockedRequests,
+			Bytes:           statTraffic.Bytes,
+		}
+	}
 
 	return stat, nil
 }
 
-func (f *fileSystemCrawlStorage) readFile(_ context.Context, filePath string) ([]byte, error) {
-	fd, err := os.Open(filePath)
-	if errors.Is(err, os.ErrNotExist) {
-		return []byte{}, nil
+// readAttempts reads the attempt records, the captcha images are left out.
+func readAttempts(ctx context.Context, fsys fs.FS, crawlDir string) ([]crawl.CaptchaAttempt, error) {
+	attemptsFile := path.Join(crawlDir, "attempts.json")
+	attemptsBytes, err := readFile(ctx, fsys, attemptsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read attempts file: %w", err)
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("open file: %w", err)
+	if len(attemptsBytes) == 0 {
+		return nil, nil
 	}
 
-	defer func() {
-		if err := fd.Close(); err != nil {
-			f.logger.Error("failed close", "error", err.Error())
+	var records []attempt
+	if err := json.Unmarshal(attemptsBytes, &records); err != nil {
+		return nil, fmt.Errorf("unmarshal attempts: %w", err)
+	}
+
+	attempts := make([]crawl.CaptchaAttempt, 0, len(records))
+
+	for i := range records {
+		attempts = append(attempts, crawl.CaptchaAttempt{
+			Code:          records[i].Code,
+			Solver:        records[i].Solver,
+			Preprocessing: records[i].Preprocessing,
+			Confidence:    records[i].Confidence,
+			Cost:          records[i].Cost,
+			Verdict:       crawl.Verdict(records[i].Verdict),
+		})
+	}
+
+	return attempts, nil
+}
+
+var imageMIMETypes = []string{image.MIMETypePNG, image.MIMETypeJPEG, image.MIMETypeWebP}
+
+func readImage(ctx context.Context, fsys fs.FS, dir, name string) (image.Image, error) {
+	for _, mimeType := range imageMIMETypes {
+		img := image.Image{MIMEType: mimeType}
+
+		imageBytes, err := readFile(ctx, fsys, path.Join(dir, name+"."+img.Extension()))
+		if err != nil {
+			return image.Image{}, err
 		}
-	}()
 
-	fileBytes, err := io.ReadAll(fd)
+		if len(imageBytes) != 0 {
+			img.Bytes = imageBytes
+
+			return img, nil
+		}
+	}
+
+	return image.Image{}, nil
+}
+
+func readFile(_ context.Context, fsys fs.FS, filePath string) ([]byte, error) {
+	fileBytes, err := fs.ReadFile(fsys, filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []byte{}, nil
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("open file: %w", err)
+		return nil, fmt.Errorf("read file: %w", err)
 	}
 
 	return fileBytes, nil
 }
 
-func (f *fileSystemCrawlStorage) fileExists(_ context.Context, filePath string) (bool, error) {
-	_, err := os.Stat(filePath)
+func fileExists(_ context.Context, fsys fs.FS, filePath string) (bool, error) {
+	_, err := fs.Stat(fsys, filePath)
 
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return false, nil
 	}
 
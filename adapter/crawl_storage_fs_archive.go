@@ -0,0 +1,428 @@
+package adapter
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveZstd ArchiveFormat = "zstd"
+	ArchiveGzip ArchiveFormat = "gzip"
+)
+
+// archiveExtensions are tried in order when a day is read, whatever format
+// new archives are written in.
+var archiveExtensions = map[ArchiveFormat]string{
+	ArchiveZstd: ".tar.zst",
+	ArchiveGzip: ".tar.gz",
+}
+
+var _ crawl.Archiver = (*fileSystemCrawlStorage)(nil)
+
+// Archive packs every user/date directory of a day before the given time into
+// <user>/<date>.tar.zst (or .tar.gz) and removes the directory.
+func (f *fileSystemCrawlStorage) Archive(ctx context.Context, before time.Time) (int, error) {
+	userIDs, err := f.ListUsers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list users: %w", err)
+	}
+
+	// Date directories are named after the UTC day.
+	lastDate := before.UTC().Format(time.DateOnly)
+	archived := 0
+
+	for _, userID := range userIDs {
+		userDir := filepath.Join(f.dir, strconv.FormatInt(userID, decimal))
+
+		dates, err := os.ReadDir(userDir)
+		if err != nil {
+			return archived, fmt.Errorf("read user directory: %w", err)
+		}
+
+		for _, date := range dates {
+			if !date.IsDir() || date.Name() >= lastDate {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return archived, err
+			}
+
+			if err := f.archiveDay(userDir, date.Name()); err != nil {
+				return archived, fmt.Errorf("archive %d/%s: %w", userID, date.Name(), err)
+			}
+
+			archived++
+		}
+	}
+
+	return archived, nil
+}
+
+func (f *fileSystemCrawlStorage) archiveDay(userDir, dateDirName string) error {
+	archivePath := filepath.Join(userDir, dateDirName+archiveExtensions[f.archiveFormat])
+	tmpPath := archivePath + ".tmp"
+
+	// Crawls may land in a day after it was archived, the ones already packed
+	// are carried over, whatever format they were written in.
+	previous := f.dayArchives(userDir, dateDirName)
+
+	if err := f.writeArchive(tmpPath, filepath.Join(userDir, dateDirName), previous); err != nil {
+		return errors.Join(err, removeIfExists(tmpPath))
+	}
+
+	// The archive replaces the directory only once it is complete, a crash in
+	// between leaves both and the directory wins when reading.
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return fmt.Errorf("rename archive: %w", err)
+	}
+
+	for _, previousPath := range previous {
+		if previousPath == archivePath {
+			continue
+		}
+
+		if err := removeIfExists(previousPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(filepath.Join(userDir, dateDirName)); err != nil {
+		return fmt.Errorf("remove directory: %w", err)
+	}
+
+	return nil
+}
+
+// dayArchives lists the archives of a day in the order they are read.
+func (f *fileSystemCrawlStorage) dayArchives(userDir, dateDirName string) []string {
+	var archives []string
+
+	for _, format := range []ArchiveFormat{ArchiveZstd, ArchiveGzip} {
+		archivePath := filepath.Join(userDir, dateDirName+archiveExtensions[format])
+
+		if _, err := os.Stat(archivePath); err == nil {
+			archives = append(archives, archivePath)
+		}
+	}
+
+	return archives
+}
+
+func (f *fileSystemCrawlStorage) writeArchive(archivePath, dayDir string, previous []string) error {
+	return writeTar(archivePath, f.archiveFormat, func(tarWriter *tar.Writer) error {
+		dayFS := os.DirFS(dayDir)
+
+		written := map[string]bool{}
+
+		if err := fs.WalkDir(dayFS, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				written[name] = true
+			}
+
+			return err
+		}); err != nil {
+			return fmt.Errorf("walk directory: %w", err)
+		}
+
+		if err := tarWriter.AddFS(dayFS); err != nil {
+			return fmt.Errorf("add files: %w", err)
+		}
+
+		for _, previousPath := range previous {
+			_, format, _ := archiveFormatOf(filepath.Base(previousPath))
+
+			if err := f.walkArchive(previousPath, format, func(header *tar.Header, content io.Reader) error {
+				name := path.Clean(header.Name)
+				if written[name] {
+					return nil
+				}
+
+				written[name] = true
+
+				return copyTarEntry(tarWriter, header, content)
+			}); err != nil {
+				return fmt.Errorf("merge %s: %w", filepath.Base(previousPath), err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// writeTar creates a compressed tar archive with whatever fill writes into it.
+func writeTar(archivePath string, format ArchiveFormat, fill func(tarWriter *tar.Writer) error) (err error) {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close archive: %w", closeErr)
+		}
+	}()
+
+	compressor, err := newCompressor(format, file)
+	if err != nil {
+		return fmt.Errorf("new compressor: %w", err)
+	}
+
+	tarWriter := tar.NewWriter(compressor)
+
+	if err := fill(tarWriter); err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("close tar: %w", err)
+	}
+
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("close compressor: %w", err)
+	}
+
+	return nil
+}
+
+func copyTarEntry(tarWriter *tar.Writer, header *tar.Header, content io.Reader) error {
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	if _, err := io.Copy(tarWriter, content); err != nil {
+		return fmt.Errorf("copy %s: %w", header.Name, err)
+	}
+
+	return nil
+}
+
+func newCompressor(format ArchiveFormat, w io.Writer) (io.WriteCloser, error) {
+	switch format {
+	case ArchiveZstd:
+		return zstd.NewWriter(w)
+	case ArchiveGzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format `%s`", format)
+	}
+}
+
+func newDecompressor(format ArchiveFormat, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case ArchiveZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	case ArchiveGzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported archive format `%s`", format)
+	}
+}
+
+// openArchive loads the archived day into memory, fs.ErrNotExist is returned
+// when the day has no archive. Days loaded whole are cached, the viewer asks
+// for the artifacts of a day one by one.
+func (f *fileSystemCrawlStorage) openArchive(userDir, dateDirName string, keep func(string) bool) (fs.FS, error) {
+	for _, format := range []ArchiveFormat{ArchiveZstd, ArchiveGzip} {
+		archivePath := filepath.Join(userDir, dateDirName+archiveExtensions[format])
+
+		info, err := os.Stat(archivePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("stat archive: %w", err)
+		}
+
+		if archive, ok := f.archives.get(archivePath, info); ok {
+			return archive, nil
+		}
+
+		archive, err := f.readArchive(archivePath, format, keep)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if keep == nil {
+			f.archives.put(archivePath, info, archive)
+		}
+
+		return archive, nil
+	}
+
+	return nil, fs.ErrNotExist
+}
+
+// archiveCacheDays bounds how many archived days are kept in memory.
+const archiveCacheDays = 2
+
+// archiveCache keeps the last archived days loaded whole, an entry is stale
+// once the archive is rewritten or removed.
+type archiveCache struct {
+	mu      sync.Mutex
+	entries []archiveCacheEntry
+}
+
+type archiveCacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+	archive memoryFS
+}
+
+func (c *archiveCache) get(archivePath string, info fs.FileInfo) (memoryFS, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		if entry.path == archivePath && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+			return entry.archive, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *archiveCache) put(archivePath string, info fs.FileInfo, archive memoryFS) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = slices.DeleteFunc(c.entries, func(entry archiveCacheEntry) bool {
+		return entry.path == archivePath
+	})
+
+	c.entries = append(c.entries, archiveCacheEntry{
+		path:    archivePath,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		archive: archive,
+	})
+
+	if len(c.entries) > archiveCacheDays {
+		c.entries = slices.Delete(c.entries, 0, len(c.entries)-archiveCacheDays)
+	}
+}
+
+func (f *fileSystemCrawlStorage) readArchive(
+	archivePath string, format ArchiveFormat, keep func(string) bool,
+) (memoryFS, error) {
+	archive := memoryFS{}
+
+	err := f.walkArchive(archivePath, format, func(header *tar.Header, content io.Reader) error {
+		if keep != nil && !keep(path.Clean(header.Name)) {
+			archive[path.Clean(header.Name)] = nil
+
+			return nil
+		}
+
+		fileBytes, err := io.ReadAll(content)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", header.Name, err)
+		}
+
+		archive[path.Clean(header.Name)] = fileBytes
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func (f *fileSystemCrawlStorage) walkArchive(
+	archivePath string, format ArchiveFormat, visit func(header *tar.Header, content io.Reader) error,
+) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			f.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	decompressor, err := newDecompressor(format, file)
+	if err != nil {
+		return fmt.Errorf("new decompressor: %w", err)
+	}
+
+	defer func() {
+		if err := decompressor.Close(); err != nil {
+			f.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	tarReader := tar.NewReader(decompressor)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := visit(header, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// archiveFormatOf tells the format of an archive file name, false for
+// anything else.
+func archiveFormatOf(name string) (string, ArchiveFormat, bool) {
+	for format, extension := range archiveExtensions {
+		if dateDirName, ok := strings.CutSuffix(name, extension); ok {
+			return dateDirName, format, true
+		}
+	}
+
+	return "", "", false
+}
+
+func removeIfExists(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", filePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+var _ crawl.Migrator = (*fileSystemCrawlStorage)(nil)
+
+// Migrate moves crawls saved under local, second precision names
+// (user/date/15:04:05) to the UTC user/date/time_id layout, giving them an ID
+// and a manifest on the way. Archived days stay packed, their crawls are read
+// with the old names. A crawl moved into an archived day is packed into the
+// archive, the day directory would hide it otherwise.
+func (f *fileSystemCrawlStorage) Migrate(ctx context.Context) (int, error) {
+	userIDs, err := f.ListUsers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list users: %w", err)
+	}
+
+	migrated := 0
+
+	for _, userID := range userIDs {
+		userDir := filepath.Join(f.dir, strconv.FormatInt(userID, decimal))
+
+		dates, err := os.ReadDir(userDir)
+		if err != nil {
+			return migrated, fmt.Errorf("read user directory: %w", err)
+		}
+
+		for _, date := range dates {
+			if !date.IsDir() {
+				continue
+			}
+
+			dayMigrated, err := f.migrateDay(ctx, userID, date.Name())
+			migrated += dayMigrated
+
+			if err != nil {
+				return migrated, fmt.Errorf("migrate %d/%s: %w", userID, date.Name(), err)
+			}
+		}
+
+		if err := f.packArchivedDays(userDir); err != nil {
+			return migrated, fmt.Errorf("pack archived days of %d: %w", userID, err)
+		}
+	}
+
+	return migrated, nil
+}
+
+// packArchivedDays merges the day directories which sit next to an archive
+// into it, including the ones an interrupted run left behind.
+func (f *fileSystemCrawlStorage) packArchivedDays(userDir string) error {
+	dates, err := os.ReadDir(userDir)
+	if err != nil {
+		return fmt.Errorf("read user directory: %w", err)
+	}
+
+	for _, date := range dates {
+		if !date.IsDir() || len(f.dayArchives(userDir, date.Name())) == 0 {
+			continue
+		}
+
+		if err := f.archiveDay(userDir, date.Name()); err != nil {
+			return fmt.Errorf("archive %s: %w", date.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func (f *fileSystemCrawlStorage) migrateDay(ctx context.Context, userID int64, dateDirName string) (int, error) {
+	dayDir := filepath.Join(f.dir, strconv.FormatInt(userID, decimal), dateDirName)
+
+	crawlTimes, err := os.ReadDir(dayDir)
+	if err != nil {
+		return 0, fmt.Errorf("read date directory: %w", err)
+	}
+
+	migrated := 0
+
+	for _, crawlTime := range crawlTimes {
+		if !crawlTime.IsDir() || !isLegacyCrawlDir(crawlTime.Name()) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return migrated, err
+		}
+
+		if err := f.migrateCrawl(ctx, userID, dayDir, crawlTime.Name()); err != nil {
+			return migrated, fmt.Errorf("migrate crawl %s: %w", crawlTime.Name(), err)
+		}
+
+		migrated++
+	}
+
+	if migrated == 0 {
+		return 0, nil
+	}
+
+	// Every crawl may have moved to another UTC date.
+	if entries, err := os.ReadDir(dayDir); err == nil && len(entries) == 0 {
+		if err := os.Remove(dayDir); err != nil {
+			return migrated, fmt.Errorf("remove date directory: %w", err)
+		}
+	}
+
+	return migrated, nil
+}
+
+func (f *fileSystemCrawlStorage) migrateCrawl(ctx context.Context, userID int64, dayDir, timeDirName string) error {
+	result, err := readCrawlMetadata(ctx, os.DirFS(dayDir), timeDirName)
+	if err != nil {
+		return fmt.Errorf("read crawl: %w", err)
+	}
+
+	if err := setRanAtFromDir(&result, filepath.Base(dayDir), timeDirName); err != nil {
+		return err
+	}
+
+	if result.ID == "" {
+		result.ID = crawl.NewID()
+	}
+
+	manifestBytes, err := json.Marshal(newManifest(&result))
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	// The manifest is written before the move, a crash in between keeps the
+	// ID the next run moves the crawl with.
+	crawlDir := filepath.Join(dayDir, timeDirName)
+	if err := os.WriteFile(filepath.Join(crawlDir, "manifest.json"), manifestBytes, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	target := filepath.Join(f.dir, filepath.FromSlash(crawlPath(userID, &result)))
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("create date directory: %w", err)
+	}
+
+	if err := os.Rename(crawlDir, target); err != nil {
+		return fmt.Errorf("move crawl: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package adapter
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+var _ crawl.Pruner = (*fileSystemCrawlStorage)(nil)
+
+// notableFiles mark a crawl that found something or failed.
+var notableFiles = []string{"interesting.txt", "error.txt"}
+
+func (f *fileSystemCrawlStorage) Footprints(ctx context.Context) ([]crawl.Footprint, error) {
+	userIDs, err := f.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+
+	footprints := make([]crawl.Footprint, 0)
+
+	for _, userID := range userIDs {
+		userDirName := strconv.FormatInt(userID, decimal)
+
+		dates, err := os.ReadDir(filepath.Join(f.dir, userDirName))
+		if err != nil {
+			return nil, fmt.Errorf("read user directory: %w", err)
+		}
+
+		for _, date := range dates {
+			if !date.IsDir() {
+				archiveFootprints, err := f.archiveFootprints(userID, filepath.Join(userDirName, date.Name()))
+				if err != nil {
+					return nil, fmt.Errorf("footprints of %s: %w", date.Name(), err)
+				}
+
+				footprints = append(footprints, archiveFootprints...)
+
+				continue
+			}
+
+			dateFootprints, err := f.dateFootprints(ctx, userID, filepath.Join(userDirName, date.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("footprints of %s: %w", date.Name(), err)
+			}
+
+			footprints = append(footprints, dateFootprints...)
+		}
+	}
+
+	return footprints, nil
+}
+
+func (f *fileSystemCrawlStorage) dateFootprints(
+	ctx context.Context, userID int64, dateDir string,
+) ([]crawl.Footprint, error) {
+	crawlTimes, err := os.ReadDir(filepath.Join(f.dir, dateDir))
+	if err != nil {
+		return nil, fmt.Errorf("read date directory: %w", err)
+	}
+
+	footprints := make([]crawl.Footprint, 0, len(crawlTimes))
+
+	for _, crawlTime := range crawlTimes {
+		if !crawlTime.IsDir() {
+			continue
+		}
+
+		ranAt, err := parseCrawlDirName(filepath.Base(dateDir), crawlTime.Name())
+		if err != nil {
+			return nil, fmt.Errorf("time parse: %w", err)
+		}
+
+		ref := filepath.Join(dateDir, crawlTime.Name())
+		crawlDir := filepath.Join(f.dir, ref)
+
+		notable, err := f.notable(ctx, os.DirFS(crawlDir))
+		if err != nil {
+			return nil, fmt.Errorf("check notable: %w", err)
+		}
+
+		size, err := dirSize(crawlDir)
+		if err != nil {
+			return nil, fmt.Errorf("directory size: %w", err)
+		}
+
+		footprints = append(footprints, crawl.Footprint{
+			Ref:     ref,
+			UserID:  userID,
+			RanAt:   ranAt,
+			Notable: notable,
+			Bytes:   size,
+		})
+	}
+
+	return footprints, nil
+}
+
+// archiveFootprints gives every crawl of an archived day its own footprint,
+// the archive size is shared out by how much each crawl takes unpacked.
+func (f *fileSystemCrawlStorage) archiveFootprints(userID int64, archiveRef string) ([]crawl.Footprint, error) {
+	dateDirName, format, ok := archiveFormatOf(filepath.Base(archiveRef))
+	if !ok {
+		return nil, nil
+	}
+
+	archivePath := filepath.Join(f.dir, archiveRef)
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat archive: %w", err)
+	}
+
+	var (
+		crawlDirs     []string
+		unpackedTotal int64
+	)
+
+	unpacked := map[string]int64{}
+	notable := map[string]bool{}
+
+	if err := f.walkArchive(archivePath, format, func(header *tar.Header, _ io.Reader) error {
+		name := path.Clean(header.Name)
+		crawlDir, _, _ := strings.Cut(name, "/")
+
+		if _, seen := unpacked[crawlDir]; !seen {
+			crawlDirs = append(crawlDirs, crawlDir)
+		}
+
+		unpacked[crawlDir] += header.Size
+		unpackedTotal += header.Size
+		notable[crawlDir] = notable[crawlDir] || slices.Contains(notableFiles, strings.TrimPrefix(name, crawlDir+"/"))
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("walk archive: %w", err)
+	}
+
+	footprints := make([]crawl.Footprint, 0, len(crawlDirs))
+
+	for _, crawlDir := range crawlDirs {
+		ranAt, err := parseCrawlDirName(dateDirName, crawlDir)
+		if err != nil {
+			return nil, fmt.Errorf("time parse: %w", err)
+		}
+
+		size := info.Size() / int64(len(crawlDirs))
+		if unpackedTotal > 0 {
+			size = info.Size() * unpacked[crawlDir] / unpackedTotal
+		}
+
+		footprints = append(footprints, crawl.Footprint{
+			Ref:     filepath.Join(archiveRef, crawlDir),
+			UserID:  userID,
+			RanAt:   ranAt,
+			Notable: notable[crawlDir],
+			Bytes:   size,
+		})
+	}
+
+	return footprints, nil
+}
+
+func (f *fileSystemCrawlStorage) notable(ctx context.Context, crawlDir fs.FS) (bool, error) {
+	for _, name := range notableFiles {
+		exists, err := fileExists(ctx, crawlDir, name)
+		if err != nil {
+			return false, err
+		}
+
+		if exists {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("file info: %w", err)
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+// Remove deletes the crawl directories and the date and user directories they
+// leave empty. Archived crawls are dropped from their archive, which is
+// rewritten once however many of its crawls go.
+func (f *fileSystemCrawlStorage) Remove(_ context.Context, footprints []crawl.Footprint) error {
+	var archiveRefs []string
+
+	archivedCrawls := map[string]map[string]bool{}
+
+	for _, footprint := range footprints {
+		archiveRef, crawlDir := filepath.Dir(footprint.Ref), filepath.Base(footprint.Ref)
+
+		if _, _, ok := archiveFormatOf(filepath.Base(archiveRef)); ok {
+			if archivedCrawls[archiveRef] == nil {
+				archiveRefs = append(archiveRefs, archiveRef)
+				archivedCrawls[archiveRef] = map[string]bool{}
+			}
+
+			archivedCrawls[archiveRef][crawlDir] = true
+
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(f.dir, footprint.Ref)); err != nil {
+			return fmt.Errorf("remove crawl directory: %w", err)
+		}
+
+		if err := f.removeEmptyParents(filepath.Join(f.dir, footprint.Ref)); err != nil {
+			return err
+		}
+	}
+
+	for _, archiveRef := range archiveRefs {
+		if err := f.dropFromArchive(archiveRef, archivedCrawls[archiveRef]); err != nil {
+			return fmt.Errorf("drop crawls from %s: %w", archiveRef, err)
+		}
+	}
+
+	return nil
+}
+
+// dropFromArchive rewrites the archive without the given crawls, an archive
+// left with no crawls is removed.
+func (f *fileSystemCrawlStorage) dropFromArchive(archiveRef string, crawlDirs map[string]bool) error {
+	archivePath := filepath.Join(f.dir, archiveRef)
+	tmpPath := archivePath + ".tmp"
+	_, format, _ := archiveFormatOf(filepath.Base(archivePath))
+
+	kept := 0
+
+	err := writeTar(tmpPath, format, func(tarWriter *tar.Writer) error {
+		return f.walkArchive(archivePath, format, func(header *tar.Header, content io.Reader) error {
+			crawlDir, _, _ := strings.Cut(path.Clean(header.Name), "/")
+			if crawlDirs[crawlDir] {
+				return nil
+			}
+
+			kept++
+
+			return copyTarEntry(tarWriter, header, content)
+		})
+	})
+	if err != nil {
+		return errors.Join(err, removeIfExists(tmpPath))
+	}
+
+	if kept == 0 {
+		if err := errors.Join(removeIfExists(tmpPath), removeIfExists(archivePath)); err != nil {
+			return err
+		}
+
+		return f.removeEmptyParents(archivePath)
+	}
+
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return fmt.Errorf("rename archive: %w", err)
+	}
+
+	return nil
+}
+
+// removeEmptyParents removes the directories above the removed path up to the
+// storage root, stopping at the first one that is not empty.
+func (f *fileSystemCrawlStorage) removeEmptyParents(removed string) error {
+	root := filepath.Clean(f.dir)
+
+	for dir := filepath.Dir(removed); dir != root && dir != "."; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			if errors.Is(err, fs.ErrNotExist) || isDirNotEmpty(dir) {
+				return nil
+			}
+
+			return fmt.Errorf("remove empty directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func isDirNotEmpty(dir string) bool {
+	entries, err := os.ReadDir(dir)
+
+	return err == nil && len(entries) > 0
+}
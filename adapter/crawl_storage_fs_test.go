@@ -0,0 +1,470 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+func TestFileSystemCrawlStorage_Attempts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := MustNewFileSystemCrawlStorage(t.TempDir(), ArchiveZstd, log.NewLogger())
+
+	rejected := image.Image{MIMEType: image.MIMETypePNG, Bytes: []byte("rejected")}
+	accepted := image.Image{MIMEType: image.MIMETypeJPEG, Bytes: []byte("accepted")}
+
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC)
+	attempts := []crawl.CaptchaAttempt{
+		{Image: rejected, Code: "111111", Solver: "ocr", Preprocessing: "contrast+denoise", Confidence: 0.4, Verdict: crawl.VerdictRejected},
+		{Image: accepted, Code: "222222", Solver: "2captcha", Confidence: 1, Cost: 0.001, Verdict: crawl.VerdictAccepted},
+	}
+
+	if err := storage.Save(ctx, 42, &crawl.Result{RanAt: ranAt, Attempts: attempts}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	results, err := storage.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	if !reflect.DeepEqual(results[0].Attempts, attempts) {
+		t.Errorf("expected attempts %+v, got %+v", attempts, results[0].Attempts)
+	}
+}
+
+func TestFileSystemCrawlStorage_NoAttempts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC)
+
+	result := crawl.Result{RanAt: ranAt}
+	if err := storage.Save(ctx, 42, &result); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	crawlDir := filepath.Join(dir, "42", "2024-03-05", "10:20:30.000000000Z_"+result.ID)
+	if _, err := os.Stat(crawlDir); err != nil {
+		t.Fatalf("expected the crawl directory: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(crawlDir, "attempts.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no attempts file, got %v", err)
+	}
+
+	results, err := storage.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 || results[0].Attempts != nil {
+		t.Errorf("expected a result without attempts, got %+v", results)
+	}
+}
+
+func TestFileSystemCrawlStorage_Manifest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 123456789, time.UTC)
+	result := crawl.Result{
+		ID:            crawl.NewID(),
+		RanAt:         ranAt,
+		Proxy:         "http://proxy:8080",
+		CaptchaSolver: "ocr",
+		Retries:       2,
+		Durations: crawl.Durations{
+			Authorize:    time.Second,
+			CaptchaSolve: 2 * time.Second,
+			Submit:       300 * time.Millisecond,
+			Calendar:     400 * time.Millisecond,
+			Total:        time.Minute,
+		},
+		AppVersion: "v1.2.3",
+		Attempts: []crawl.CaptchaAttempt{
+			{Code: "123456", Solver: "ocr", Verdict: crawl.VerdictAccepted},
+		},
+	}
+
+	if err := storage.Save(ctx, 42, &result); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	manifestBytes, err := os.ReadFile(filepath.Join(dir, "42", "2024-03-05", "10:20:30.123456789Z_"+result.ID, "manifest.json"))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+
+	saved := manifest{}
+	if err := json.Unmarshal(manifestBytes, &saved); err != nil {
+		t.Fatalf("unmarshal manifest: %v", err)
+	}
+
+	if saved.ID != result.ID || saved.SubmittedCode != "123456" || saved.AppVersion != "v1.2.3" {
+		t.Errorf("unexpected manifest %s", manifestBytes)
+	}
+
+	results, err := storage.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	got := results[0]
+
+	if got.ID != result.ID || !got.RanAt.Equal(ranAt) || got.Retries != result.Retries ||
+		got.Durations != result.Durations || got.AppVersion != result.AppVersion {
+		t.Errorf("expected %+v, got %+v", result, got)
+	}
+}
+
+func TestFileSystemCrawlStorage_Prune(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+	pruner := storage.(crawl.Pruner)
+
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	results := []crawl.Result{
+		{RanAt: day},
+		{RanAt: day.Add(time.Hour), SomethingInteresting: true},
+		{RanAt: day.AddDate(0, 0, 1), Err: errors.New("timeout")},
+	}
+
+	for i := range results {
+		if err := storage.Save(ctx, 42, &results[i]); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	footprints, err := pruner.Footprints(ctx)
+	if err != nil {
+		t.Fatalf("footprints: %v", err)
+	}
+
+	if len(footprints) != 3 {
+		t.Fatalf("expected 3 footprints, got %d", len(footprints))
+	}
+
+	notable := map[string]bool{}
+	for _, footprint := range footprints {
+		notable[footprint.Ref] = footprint.Notable
+
+		if footprint.UserID != 42 || footprint.Bytes == 0 {
+			t.Errorf("unexpected footprint %+v", footprint)
+		}
+	}
+
+	expectedNotable := map[string]bool{
+		filepath.Join("42", "2024-03-05", "10:00:00.000000000Z_"+results[0].ID): false,
+		filepath.Join("42", "2024-03-05", "11:00:00.000000000Z_"+results[1].ID): true,
+		filepath.Join("42", "2024-03-06", "10:00:00.000000000Z_"+results[2].ID): true,
+	}
+	if !reflect.DeepEqual(notable, expectedNotable) {
+		t.Errorf("expected %v, got %v", expectedNotable, notable)
+	}
+
+	var removed []crawl.Footprint
+
+	for _, footprint := range footprints {
+		if footprint.RanAt.Day() == 5 {
+			removed = append(removed, footprint)
+		}
+	}
+
+	if err := pruner.Remove(ctx, removed); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "42", "2024-03-05")); !os.IsNotExist(err) {
+		t.Errorf("expected emptied date directory removed, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "42", "2024-03-06", "10:00:00.000000000Z_"+results[2].ID)); err != nil {
+		t.Errorf("expected other crawls kept: %v", err)
+	}
+}
+
+func TestFileSystemCrawlStorage_Archive(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []ArchiveFormat{ArchiveZstd, ArchiveGzip} {
+		format := format
+
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			dir := t.TempDir()
+			storage := MustNewFileSystemCrawlStorage(dir, format, log.NewLogger())
+
+			oldDay := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+			newDay := oldDay.AddDate(0, 0, 1)
+
+			results := []crawl.Result{
+				{
+					RanAt: oldDay,
+					One: page.Stat{
+						HTML:       []byte("<html></html>"),
+						Screenshot: image.Image{MIMEType: image.MIMETypePNG, Bytes: []byte("screenshot")},
+					},
+					Attempts: []crawl.CaptchaAttempt{{
+						Image:   image.Image{MIMEType: image.MIMETypePNG, Bytes: []byte("captcha")},
+						Code:    "123456",
+						Solver:  "ocr",
+						Verdict: crawl.VerdictAccepted,
+					}},
+				},
+				{RanAt: oldDay.Add(time.Hour), SomethingInteresting: true},
+				{RanAt: newDay},
+			}
+
+			for i := range results {
+				if err := storage.Save(ctx, 42, &results[i]); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+			}
+
+			before, err := storage.ListResults(ctx, 42, oldDay)
+			if err != nil {
+				t.Fatalf("list results: %v", err)
+			}
+
+			archived, err := storage.(crawl.Archiver).Archive(ctx, newDay)
+			if err != nil {
+				t.Fatalf("archive: %v", err)
+			}
+
+			if archived != 1 {
+				t.Fatalf("expected 1 archived day, got %d", archived)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "42", "2024-03-05")); !os.IsNotExist(err) {
+				t.Errorf("expected archived directory removed, got %v", err)
+			}
+
+			after, err := storage.ListResults(ctx, 42, oldDay)
+			if err != nil {
+				t.Fatalf("list archived results: %v", err)
+			}
+
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("expected archived results %+v, got %+v", before, after)
+			}
+
+			if _, err := storage.ListResults(ctx, 42, newDay); err != nil {
+				t.Errorf("expected the fresh day left as is: %v", err)
+			}
+
+			footprints, err := storage.(crawl.Pruner).Footprints(ctx)
+			if err != nil {
+				t.Fatalf("footprints: %v", err)
+			}
+
+			if len(footprints) != 3 ||
+				footprints[0].Notable || !footprints[0].RanAt.Equal(oldDay) ||
+				!footprints[1].Notable || !footprints[1].RanAt.Equal(oldDay.Add(time.Hour)) {
+				t.Errorf("expected a footprint for every archived crawl, got %+v", footprints)
+			}
+		})
+	}
+}
+
+func TestFileSystemCrawlStorage_ArchiveMerge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	gzipStorage := MustNewFileSystemCrawlStorage(dir, ArchiveGzip, log.NewLogger())
+	zstdStorage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	results := []crawl.Result{
+		{RanAt: day, SomethingInteresting: true},
+		{RanAt: day.Add(time.Hour)},
+	}
+
+	if err := gzipStorage.Save(ctx, 42, &results[0]); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := gzipStorage.(crawl.Archiver).Archive(ctx, day.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	// A crawl lands in the archived day, which is archived again after the
+	// format changed.
+	if err := zstdStorage.Save(ctx, 42, &results[1]); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := zstdStorage.(crawl.Archiver).Archive(ctx, day.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "42", "2024-03-05.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected the merged gzip archive removed, got %v", err)
+	}
+
+	got, err := zstdStorage.ListResults(ctx, 42, day)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(got) != len(results) {
+		t.Fatalf("expected %d results, got %d", len(results), len(got))
+	}
+
+	for i := range results {
+		if got[i].ID != results[i].ID {
+			t.Errorf("expected result %d to be %s, got %s", i, results[i].ID, got[i].ID)
+		}
+	}
+}
+
+func TestFileSystemCrawlStorage_PruneArchivedDay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	now := day.AddDate(0, 0, 30)
+
+	tests := []struct {
+		name      string
+		retention crawl.Retention
+		reason    crawl.ExpiryReason
+	}{
+		{name: "KeepDays", retention: crawl.Retention{KeepDays: 7}, reason: crawl.ExpiryAge},
+		{name: "MaxBytes", retention: crawl.Retention{MaxBytes: 1}, reason: crawl.ExpirySize},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+			pruner := storage.(crawl.Pruner)
+
+			results := []crawl.Result{
+				{RanAt: day, One: page.Stat{HTML: []byte("<html>one</html>")}},
+				{RanAt: day.Add(time.Hour), Err: errors.New("proxy failed")},
+				{RanAt: day.Add(2 * time.Hour), One: page.Stat{HTML: []byte("<html>three</html>")}},
+			}
+
+			for i := range results {
+				if err := storage.Save(ctx, 42, &results[i]); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+			}
+
+			if _, err := storage.(crawl.Archiver).Archive(ctx, day.AddDate(0, 0, 1)); err != nil {
+				t.Fatalf("archive: %v", err)
+			}
+
+			footprints, err := pruner.Footprints(ctx)
+			if err != nil {
+				t.Fatalf("footprints: %v", err)
+			}
+
+			expired := test.retention.Expired(footprints, now)
+
+			removed := make([]crawl.Footprint, 0, len(expired))
+			for _, crawlExpired := range expired {
+				if crawlExpired.Reason != test.reason {
+					t.Errorf("expected %s expiry, got %+v", test.reason, crawlExpired)
+				}
+
+				removed = append(removed, crawlExpired.Footprint)
+			}
+
+			if len(removed) != 2 {
+				t.Fatalf("expected the ordinary archived crawls expired, got %+v", expired)
+			}
+
+			if err := pruner.Remove(ctx, removed); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+
+			left, err := storage.ListResults(ctx, 42, day)
+			if err != nil {
+				t.Fatalf("list results: %v", err)
+			}
+
+			if len(left) != 1 || left[0].ID != results[1].ID {
+				t.Errorf("expected only the failed crawl kept, got %+v", left)
+			}
+
+			footprints, err = pruner.Footprints(ctx)
+			if err != nil {
+				t.Fatalf("footprints: %v", err)
+			}
+
+			if len(footprints) != 1 || !footprints[0].Notable {
+				t.Errorf("expected the failed crawl footprint left, got %+v", footprints)
+			}
+
+			if err := pruner.Remove(ctx, footprints); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "42")); !os.IsNotExist(err) {
+				t.Errorf("expected the emptied archive and user directory removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileSystemCrawlStorage_ArchiveBeforeInAnotherZone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	result := crawl.Result{RanAt: time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)}
+	if err := storage.Save(ctx, 42, &result); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Still the 5th west of UTC, the UTC day of the crawl is over already.
+	before := time.Date(2024, time.March, 5, 23, 30, 0, 0, time.FixedZone("west", -2*60*60))
+
+	archived, err := storage.(crawl.Archiver).Archive(ctx, before)
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	if archived != 1 {
+		t.Errorf("expected the finished UTC day archived, got %d days", archived)
+	}
+}
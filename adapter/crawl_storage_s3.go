@@ -0,0 +1,362 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+// S3CrawlStorageConfig points at an S3 compatible bucket, AWS, MinIO and the
+// like. Objects are keyed <prefix>/<user>/<date>/<time>/<step>/<file>, the same
+// layout the file system storage uses.
+type S3CrawlStorageConfig struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+type s3CrawlStorage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	logger log.Logger
+}
+
+func NewS3CrawlStorage(cfg S3CrawlStorageConfig, logger log.Logger) (crawl.Storage, error) {
+	const setupTimeout = 30 * time.Second
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket exists: %w", err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("make bucket: %w", err)
+		}
+	}
+
+	return &s3CrawlStorage{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+		logger: logger,
+	}, nil
+}
+
+func MustNewS3CrawlStorage(cfg S3CrawlStorageConfig, logger log.Logger) crawl.Storage {
+	storage, err := NewS3CrawlStorage(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return storage
+}
+
+func (s *s3CrawlStorage) key(elem ...string) string {
+	return path.Join(append([]string{s.prefix}, elem...)...)
+}
+
+// dirKey is the key prefix listing the objects under a directory.
+func (s *s3CrawlStorage) dirKey(elem ...string) string {
+	if key := s.key(elem...); key != "" {
+		return key + "/"
+	}
+
+	return ""
+}
+
+func (s *s3CrawlStorage) Save(ctx context.Context, userID int64, result *crawl.Result) error {
+	if result.ID == "" {
+		result.ID = crawl.NewID()
+	}
+
+	return writeCrawl(func(filePath string, fileBytes []byte) error {
+		if _, err := s.client.PutObject(
+			ctx, s.bucket, s.key(filePath), bytes.NewReader(fileBytes), int64(len(fileBytes)),
+			minio.PutObjectOptions{ContentType: mime.TypeByExtension(path.Ext(filePath))},
+		); err != nil {
+			return fmt.Errorf("put object: %w", err)
+		}
+
+		return nil
+	}, crawlPath(userID, result), result)
+}
+
+func (s *s3CrawlStorage) ListUsers(ctx context.Context) ([]int64, error) {
+	// Cancelling stops the listing goroutine when returning early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	userIDs := make([]int64, 0)
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.dirKey()}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("list objects: %w", object.Err)
+		}
+
+		dirName, ok := strings.CutSuffix(strings.TrimPrefix(object.Key, s.dirKey()), "/")
+		if !ok {
+			continue
+		}
+
+		userID, err := strconv.ParseInt(dirName, decimal, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("parse user id - `%v`: %w", dirName, err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
+
+func (s *s3CrawlStorage) ListResults(ctx context.Context, userID int64, date time.Time) ([]crawl.Result, error) {
+	userDirName := strconv.FormatInt(userID, decimal)
+
+	return readCalendarDay(ctx, date, func(dateDirName string) (fs.FS, error) {
+		return s.loadDay(ctx, userDirName, dateDirName, nil)
+	})
+}
+
+func (s *s3CrawlStorage) daySummaries(
+	ctx context.Context, userID int64, dateDirName string,
+) ([]crawl.Summary, error) {
+	dailyCrawls, err := s.loadDay(ctx, strconv.FormatInt(userID, decimal), dateDirName, isMetadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return readDaySummaries(ctx, dailyCrawls, userID, dateDirName)
+}
+
+// loadDay fetches the objects of a user day keep accepts, the others are only
+// listed and read empty. A nil keep fetches everything.
+func (s *s3CrawlStorage) loadDay(
+	ctx context.Context, userDirName, dateDirName string, keep func(string) bool,
+) (memoryFS, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dayKey := s.dirKey(userDirName, dateDirName)
+
+	dailyCrawls := memoryFS{}
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    dayKey,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("list objects: %w", object.Err)
+		}
+
+		filePath := strings.TrimPrefix(object.Key, dayKey)
+
+		if keep != nil && !keep(filePath) {
+			dailyCrawls[filePath] = nil
+
+			continue
+		}
+
+		objectBytes, err := s.getObject(ctx, object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", object.Key, err)
+		}
+
+		dailyCrawls[filePath] = objectBytes
+	}
+
+	if len(dailyCrawls) == 0 {
+		return nil, fmt.Errorf("read day %s: %w", dayKey, fs.ErrNotExist)
+	}
+
+	return dailyCrawls, nil
+}
+
+func (s *s3CrawlStorage) Artifact(ctx context.Context, ref crawl.ArtifactRef) ([]byte, error) {
+	if _, _, _, err := parseCrawlRef(ref.Crawl); err != nil {
+		return nil, err
+	}
+
+	artifactFile, err := artifactPath(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	key := s.key(ref.Crawl, artifactFile)
+
+	artifactBytes, err := s.getObject(ctx, key)
+	if errorResponse := (minio.ErrorResponse{}); errors.As(err, &errorResponse) && errorResponse.Code == "NoSuchKey" {
+		return nil, fmt.Errorf("get %s: %w", key, crawl.ErrArtifactNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", key, err)
+	}
+
+	return artifactBytes, nil
+}
+
+func (s *s3CrawlStorage) Query(ctx context.Context, query crawl.Query) (crawl.Page, error) {
+	return queryDays(ctx, s, query)
+}
+
+func (s *s3CrawlStorage) listDays(ctx context.Context, userID int64) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	userKey := s.dirKey(strconv.FormatInt(userID, decimal))
+	dateDirNames := make([]string, 0)
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: userKey}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("list objects: %w", object.Err)
+		}
+
+		if dateDirName, ok := strings.CutSuffix(strings.TrimPrefix(object.Key, userKey), "/"); ok {
+			dateDirNames = append(dateDirNames, dateDirName)
+		}
+	}
+
+	return dateDirNames, nil
+}
+
+func (s *s3CrawlStorage) getObject(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+
+	defer func() {
+		if err := object.Close(); err != nil {
+			s.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	objectBytes, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	return objectBytes, nil
+}
+
+var _ crawl.Pruner = (*s3CrawlStorage)(nil)
+
+// Footprints groups the objects by crawl, listing is enough to size them.
+func (s *s3CrawlStorage) Footprints(ctx context.Context) ([]crawl.Footprint, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	footprints := make([]crawl.Footprint, 0)
+	index := make(map[string]int)
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    s.dirKey(),
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("list objects: %w", object.Err)
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(object.Key, s.dirKey()), "/", 4)
+		if len(parts) < 4 {
+			continue
+		}
+
+		ref := path.Join(parts[0], parts[1], parts[2])
+
+		i, ok := index[ref]
+		if !ok {
+			footprint, err := s3Footprint(parts[0], parts[1], parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("footprint of %s: %w", ref, err)
+			}
+
+			footprint.Ref = ref
+			i = len(footprints)
+			index[ref] = i
+			footprints = append(footprints, footprint)
+		}
+
+		footprints[i].Bytes += object.Size
+		footprints[i].Notable = footprints[i].Notable || slices.Contains(notableFiles, parts[3])
+	}
+
+	return footprints, nil
+}
+
+func s3Footprint(userDirName, dateDirName, timeDirName string) (crawl.Footprint, error) {
+	userID, err := strconv.ParseInt(userDirName, decimal, bitSize)
+	if err != nil {
+		return crawl.Footprint{}, fmt.Errorf("parse user id - `%v`: %w", userDirName, err)
+	}
+
+	ranAt, err := parseCrawlDirName(dateDirName, timeDirName)
+	if err != nil {
+		return crawl.Footprint{}, fmt.Errorf("time parse: %w", err)
+	}
+
+	return crawl.Footprint{UserID: userID, RanAt: ranAt}, nil
+}
+
+func (s *s3CrawlStorage) Remove(ctx context.Context, footprints []crawl.Footprint) error {
+	for _, footprint := range footprints {
+		if err := s.removeCrawl(ctx, footprint); err != nil {
+			return fmt.Errorf("remove crawl %s: %w", footprint.Ref, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *s3CrawlStorage) removeCrawl(ctx context.Context, footprint crawl.Footprint) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    s.dirKey(footprint.Ref),
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return fmt.Errorf("list objects: %w", object.Err)
+		}
+
+		if err := s.client.RemoveObject(ctx, s.bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("remove %s: %w", object.Key, err)
+		}
+	}
+
+	return nil
+}
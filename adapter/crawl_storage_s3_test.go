@@ -0,0 +1,319 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+// fakeS3 speaks just enough of the S3 API for the minio client: buckets,
+// put/get/delete object and ListObjectsV2 with a delimiter.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fake := &fakeS3{buckets: make(map[string]map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	bucket, exists := f.buckets[bucketName]
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case key == "" && r.Method == http.MethodPut:
+		f.buckets[bucketName] = make(map[string][]byte)
+	case !exists:
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, bucket, r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter"))
+	case r.Method == http.MethodPut:
+		body, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+
+			return
+		}
+
+		bucket[key] = body
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodGet:
+		body, ok := bucket[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+
+			return
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	case r.Method == http.MethodDelete:
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+type s3ListResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Prefix         string
+	KeyCount       int
+	IsTruncated    bool
+	Contents       []s3Object
+	CommonPrefixes []s3Prefix
+}
+
+type s3Object struct {
+	Key          string
+	Size         int
+	ETag         string
+	LastModified string
+}
+
+type s3Prefix struct {
+	Prefix string
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, bucket map[string][]byte, prefix, delimiter string) {
+	result := s3ListResult{Prefix: prefix}
+	seen := make(map[string]bool)
+
+	keys := make([]string, 0, len(bucket))
+	for key := range bucket {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+
+		if dir, _, nested := strings.Cut(rest, delimiter); delimiter != "" && nested {
+			if !seen[dir] {
+				seen[dir] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, s3Prefix{Prefix: prefix + dir + delimiter})
+			}
+
+			continue
+		}
+
+		result.Contents = append(result.Contents, s3Object{
+			Key:          key,
+			Size:         len(bucket[key]),
+			ETag:         `"etag"`,
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+// readS3Body decodes aws-chunked bodies the client streams over plain http.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	reader := bufio.NewReader(r.Body)
+	body := bytes.Buffer{}
+
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read chunk header: %w", err)
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse chunk size: %w", err)
+		}
+
+		if size == 0 {
+			return body.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+
+		if _, err := reader.Discard(len("\r\n")); err != nil {
+			return nil, fmt.Errorf("read chunk end: %w", err)
+		}
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func newTestS3CrawlStorage(t *testing.T, prefix string) crawl.Storage {
+	t.Helper()
+
+	server := newFakeS3(t)
+
+	storage, err := NewS3CrawlStorage(S3CrawlStorageConfig{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Region:    "us-east-1",
+		Bucket:    "artifacts",
+		Prefix:    prefix,
+		AccessKey: "access",
+		SecretKey: "secret",
+	}, log.NewLogger())
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+
+	return storage
+}
+
+func TestS3CrawlStorage_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := newTestS3CrawlStorage(t, "kdmid")
+
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC)
+	captchaImage := image.Image{MIMEType: image.MIMETypePNG, Bytes: []byte("captcha")}
+
+	result := crawl.Result{
+		One: page.Stat{
+			HTML:       []byte("<html>one</html>"),
+			Network:    []byte("GET /"),
+			Screenshot: image.Image{MIMEType: image.MIMETypeWebP, Bytes: []byte("screenshot")},
+			Captcha:    page.Captcha{Presented: true, Image: captchaImage},
+			Traffic:    page.Traffic{Requests: 3, BlockedRequests: 1, Bytes: 2048},
+		},
+		Two:           page.Stat{HTML: []byte("<html>two</html>"), Network: []byte{}},
+		Three:         page.Stat{HTML: []byte{}, Network: []byte{}},
+		ID:            crawl.NewID(),
+		RanAt:         ranAt,
+		Proxy:         "http://proxy:8080",
+		CaptchaSolver: "ocr",
+		Retries:       1,
+		Durations:     crawl.Durations{CaptchaSolve: 3 * time.Second, Calendar: time.Second, Total: 5 * time.Second},
+		AppVersion:    "v1.2.3",
+		Attempts: []crawl.CaptchaAttempt{
+			{Image: captchaImage, Code: "123456", Solver: "ocr", Confidence: 0.9, Verdict: crawl.VerdictAccepted},
+		},
+		SomethingInteresting: true,
+	}
+
+	if err := storage.Save(ctx, 42, &result); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := storage.Save(ctx, 7, &crawl.Result{RanAt: ranAt, Err: errors.New("timeout")}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	results, err := storage.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	result.Two.Network, result.Three.HTML, result.Three.Network = []byte{}, []byte{}, []byte{}
+
+	if len(results) != 1 || !reflect.DeepEqual(results[0], result) {
+		t.Errorf("expected %+v, got %+v", result, results)
+	}
+
+	users, err := storage.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+
+	if !reflect.DeepEqual(users, []int64{7, 42}) {
+		t.Errorf("expected users [7 42], got %v", users)
+	}
+
+	if _, err := storage.ListResults(ctx, 42, ranAt.AddDate(0, 0, 1)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing day to be fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestS3CrawlStorage_Prune(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := newTestS3CrawlStorage(t, "")
+	pruner := storage.(crawl.Pruner)
+
+	day := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	for _, result := range []crawl.Result{{RanAt: day}, {RanAt: day.Add(time.Hour), SomethingInteresting: true}} {
+		if err := storage.Save(ctx, 42, &result); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	footprints, err := pruner.Footprints(ctx)
+	if err != nil {
+		t.Fatalf("footprints: %v", err)
+	}
+
+	if len(footprints) != 2 || footprints[0].Notable || !footprints[1].Notable || footprints[0].Bytes == 0 {
+		t.Fatalf("unexpected footprints %+v", footprints)
+	}
+
+	if err := pruner.Remove(ctx, footprints[:1]); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	results, err := storage.ListResults(ctx, 42, day)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 || !results[0].SomethingInteresting {
+		t.Errorf("expected only the interesting crawl left, got %+v", results)
+	}
+}
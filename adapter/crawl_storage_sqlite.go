@@ -0,0 +1,885 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+	_ "modernc.org/sqlite"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type SQLiteCrawlStorageConfig struct {
+	Path string
+	// BlobDirectory keeps artifacts bigger than MaxBlobSize on disk, when it
+	// is empty every artifact goes to the database.
+	BlobDirectory string
+	MaxBlobSize   int
+}
+
+type sqliteCrawlStorage struct {
+	db          *sql.DB
+	blobDir     string
+	maxBlobSize int
+	logger      log.Logger
+}
+
+// sqliteMigrations are applied in order, PRAGMA user_version keeps how many
+// of them the database has seen. Never edit a released migration, add one.
+var sqliteMigrations = []string{
+	`CREATE TABLE crawls (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id        INTEGER NOT NULL,
+		ran_at         INTEGER NOT NULL,
+		proxy          TEXT    NOT NULL DEFAULT '',
+		captcha_solver TEXT    NOT NULL DEFAULT '',
+		error          TEXT,
+		interesting    INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX crawls_user_ran_at ON crawls (user_id, ran_at);
+	CREATE INDEX crawls_interesting_ran_at ON crawls (interesting, ran_at);
+
+	CREATE TABLE steps (
+		crawl_id         INTEGER NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+		step             INTEGER NOT NULL,
+		requests         INTEGER NOT NULL DEFAULT 0,
+		blocked_requests INTEGER NOT NULL DEFAULT 0,
+		bytes            INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (crawl_id, step)
+	);
+
+	CREATE TABLE attempts (
+		crawl_id      INTEGER NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+		idx           INTEGER NOT NULL,
+		code          TEXT    NOT NULL,
+		solver        TEXT    NOT NULL,
+		preprocessing TEXT    NOT NULL DEFAULT '',
+		confidence    REAL    NOT NULL DEFAULT 0,
+		cost          REAL    NOT NULL DEFAULT 0,
+		verdict       TEXT    NOT NULL,
+		PRIMARY KEY (crawl_id, idx)
+	);
+
+	CREATE TABLE artifacts (
+		crawl_id  INTEGER NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+		kind      TEXT    NOT NULL,
+		idx       INTEGER NOT NULL,
+		mime_type TEXT    NOT NULL DEFAULT '',
+		data      BLOB,
+		path      TEXT,
+		PRIMARY KEY (crawl_id, kind, idx)
+	);`,
+	`ALTER TABLE crawls ADD COLUMN uid TEXT NOT NULL DEFAULT '';
+	ALTER TABLE crawls ADD COLUMN retries INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawls ADD COLUMN app_version TEXT NOT NULL DEFAULT '';
+	ALTER TABLE crawls ADD COLUMN authorize_ns INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawls ADD COLUMN captcha_solve_ns INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawls ADD COLUMN submit_ns INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawls ADD COLUMN calendar_ns INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawls ADD COLUMN total_ns INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX crawls_uid ON crawls (uid);`,
+}
+
+const (
+	artifactHTML           = "page.html"
+	artifactNetwork        = "network.txt"
+	artifactScreenshot     = "screenshot"
+	artifactCaptcha        = "captcha"
+	artifactAttemptCaptcha = "attempt_captcha"
+
+	defaultMaxBlobSize = 256 << 10
+)
+
+func NewSQLiteCrawlStorage(cfg SQLiteCrawlStorageConfig, logger log.Logger) (crawl.Storage, error) {
+	if cfg.MaxBlobSize <= 0 {
+		cfg.MaxBlobSize = defaultMaxBlobSize
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("create database directory: %w", err)
+	}
+
+	dsn := "file:" + cfg.Path +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	// sqlite allows a single writer, one connection keeps writes from
+	// failing with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	storage := &sqliteCrawlStorage{
+		db:          db,
+		blobDir:     cfg.BlobDirectory,
+		maxBlobSize: cfg.MaxBlobSize,
+		logger:      logger,
+	}
+
+	if err := storage.migrate(context.Background()); err != nil {
+		return nil, errors.Join(fmt.Errorf("migrate: %w", err), db.Close())
+	}
+
+	return storage, nil
+}
+
+func MustNewSQLiteCrawlStorage(cfg SQLiteCrawlStorageConfig, logger log.Logger) crawl.Storage {
+	storage, err := NewSQLiteCrawlStorage(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return storage
+}
+
+var _ io.Closer = (*sqliteCrawlStorage)(nil)
+
+func (s *sqliteCrawlStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteCrawlStorage) migrate(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than %d known", version, len(sqliteMigrations))
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		if err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, sqliteMigrations[i]); err != nil {
+				return fmt.Errorf("apply: %w", err)
+			}
+
+			// PRAGMA does not take parameters.
+			if _, err := tx.ExecContext(ctx, "PRAGMA user_version = "+strconv.Itoa(i+1)); err != nil {
+				return fmt.Errorf("set schema version: %w", err)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		s.logger.Info("crawl storage migrated", "version", i+1)
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			s.logger.Error("failed rollback", "error", rollbackErr.Error())
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) Save(ctx context.Context, userID int64, result *crawl.Result) error {
+	if result.ID == "" {
+		result.ID = crawl.NewID()
+	}
+
+	// Blob files live outside the transaction, the ones of a crawl that is
+	// not committed are removed again.
+	var blobs []string
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var errText sql.NullString
+		if result.Err != nil {
+			errText = sql.NullString{String: result.Err.Error(), Valid: true}
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO crawls (
+				uid, user_id, ran_at, proxy, captcha_solver, error, interesting, retries, app_version,
+				authorize_ns, captcha_solve_ns, submit_ns, calendar_ns, total_ns
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			result.ID, userID, result.RanAt.UnixNano(), result.Proxy, result.CaptchaSolver, errText,
+			result.SomethingInteresting, result.Retries, result.AppVersion,
+			result.Durations.Authorize, result.Durations.CaptchaSolve, result.Durations.Submit,
+			result.Durations.Calendar, result.Durations.Total,
+		)
+		if err != nil {
+			return fmt.Errorf("insert crawl: %w", err)
+		}
+
+		crawlID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("crawl id: %w", err)
+		}
+
+		for i, stat := range []page.Stat{result.One, result.Two, result.Three} {
+			if err := s.saveStat(ctx, tx, &blobs, userID, crawlID, i+1, stat); err != nil {
+				return fmt.Errorf("save stat %d: %w", i+1, err)
+			}
+		}
+
+		for i, attempt := range result.Attempts {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO attempts (crawl_id, idx, code, solver, preprocessing, confidence, cost, verdict)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				crawlID, i+1, attempt.Code, attempt.Solver, attempt.Preprocessing,
+				attempt.Confidence, attempt.Cost, string(attempt.Verdict),
+			); err != nil {
+				return fmt.Errorf("insert attempt: %w", err)
+			}
+
+			if err := s.saveArtifact(
+				ctx, tx, &blobs, userID, crawlID, artifactAttemptCaptcha, i+1, attempt.Image,
+			); err != nil {
+				return fmt.Errorf("save attempt captcha: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.removeBlobs(blobs)
+	}
+
+	return err
+}
+
+func (s *sqliteCrawlStorage) removeBlobs(blobs []string) {
+	for _, blob := range blobs {
+		if err := os.Remove(blob); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("failed remove blob", "path", blob, "error", err.Error())
+		}
+
+		// The crawl directory is left when it is not empty.
+		_ = os.Remove(filepath.Dir(blob))
+	}
+}
+
+func (s *sqliteCrawlStorage) saveStat(
+	ctx context.Context, tx *sql.Tx, blobs *[]string, userID, crawlID int64, step int, stat page.Stat,
+) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO steps (crawl_id, step, requests, blocked_requests, bytes) VALUES (?, ?, ?, ?, ?)`,
+		crawlID, step, stat.Traffic.Requests, stat.Traffic.BlockedRequests, stat.Traffic.Bytes,
+	); err != nil {
+		return fmt.Errorf("insert step: %w", err)
+	}
+
+	artifacts := []struct {
+		kind string
+		img  image.Image
+	}{
+		{artifactHTML, image.Image{Bytes: stat.HTML}},
+		{artifactNetwork, image.Image{Bytes: stat.Network}},
+		{artifactScreenshot, stat.Screenshot},
+	}
+
+	if stat.Captcha.Presented {
+		artifacts = append(artifacts, struct {
+			kind string
+			img  image.Image
+		}{artifactCaptcha, stat.Captcha.Image})
+	}
+
+	for _, artifact := range artifacts {
+		if err := s.saveArtifact(ctx, tx, blobs, userID, crawlID, artifact.kind, step, artifact.img); err != nil {
+			return fmt.Errorf("save %s: %w", artifact.kind, err)
+		}
+	}
+
+	return nil
+}
+
+// saveArtifact stores the bytes in the database or, when they are too big and
+// a blob directory is configured, in a file the row points to. Written files
+// are added to blobs.
+func (s *sqliteCrawlStorage) saveArtifact(
+	ctx context.Context, tx *sql.Tx, blobs *[]string, userID, crawlID int64, kind string, idx int, artifact image.Image,
+) error {
+	if artifact.Empty() {
+		return nil
+	}
+
+	var (
+		data     []byte
+		blobPath sql.NullString
+	)
+
+	if s.blobDir != "" && len(artifact.Bytes) > s.maxBlobSize {
+		relPath := filepath.Join(
+			strconv.FormatInt(userID, decimal),
+			strconv.FormatInt(crawlID, decimal),
+			fmt.Sprintf("%s-%d", kind, idx),
+		)
+
+		fullPath := filepath.Join(s.blobDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("create blob directory: %w", err)
+		}
+
+		*blobs = append(*blobs, fullPath)
+
+		if err := os.WriteFile(fullPath, artifact.Bytes, 0644); err != nil {
+			return fmt.Errorf("write blob: %w", err)
+		}
+
+		blobPath = sql.NullString{String: relPath, Valid: true}
+	} else {
+		data = artifact.Bytes
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO artifacts (crawl_id, kind, idx, mime_type, data, path) VALUES (?, ?, ?, ?, ?, ?)`,
+		crawlID, kind, idx, artifact.MIMEType, data, blobPath,
+	); err != nil {
+		return fmt.Errorf("insert artifact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) ListUsers(ctx context.Context) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM crawls ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	userIDs := make([]int64, 0)
+
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate users: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// ListResults returns crawls of the calendar day date falls on in its own
+// zone, the same day the file system storage cuts out.
+func (s *sqliteCrawlStorage) ListResults(ctx context.Context, userID int64, date time.Time) ([]crawl.Result, error) {
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	to := from.AddDate(0, 0, 1)
+
+	const crawlsFilter = `c.user_id = ? AND c.ran_at >= ? AND c.ran_at < ?`
+
+	args := []any{userID, from.UnixNano(), to.UnixNano()}
+
+	results, _, err := s.readResults(ctx, crawlsFilter, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no crawls on %s: %w", from.Format(time.DateOnly), fs.ErrNotExist)
+	}
+
+	return results, nil
+}
+
+// Query filters, orders and pages in the database, only the crawls of the
+// page are read.
+func (s *sqliteCrawlStorage) Query(ctx context.Context, query crawl.Query) (crawl.Page, error) {
+	filter, args := sqliteQueryFilter(query)
+
+	page := crawl.Page{}
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM crawls c WHERE `+filter, args...,
+	).Scan(&page.Total); err != nil {
+		return crawl.Page{}, fmt.Errorf("count crawls: %w", err)
+	}
+
+	order := "DESC"
+	if query.Order == crawl.OldestFirst {
+		order = "ASC"
+	}
+
+	limit := -1
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT c.id, c.user_id FROM crawls c WHERE `+filter+
+			` ORDER BY c.ran_at `+order+`, c.id `+order+` LIMIT ? OFFSET ?`,
+		append(args, limit, max(query.Offset, 0))...,
+	)
+	if err != nil {
+		return crawl.Page{}, fmt.Errorf("query crawls: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	var (
+		crawlIDs []int64
+		userIDs  []int64
+	)
+
+	for rows.Next() {
+		var crawlID, userID int64
+		if err := rows.Scan(&crawlID, &userID); err != nil {
+			return crawl.Page{}, fmt.Errorf("scan crawl: %w", err)
+		}
+
+		crawlIDs = append(crawlIDs, crawlID)
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return crawl.Page{}, fmt.Errorf("iterate crawls: %w", err)
+	}
+
+	if len(crawlIDs) == 0 {
+		return page, nil
+	}
+
+	idFilter, idArgs := sqliteIn("c.id", crawlIDs)
+
+	results, index, err := s.readCrawls(ctx, idFilter, idArgs)
+	if err != nil {
+		return crawl.Page{}, fmt.Errorf("read crawls: %w", err)
+	}
+
+	if err := s.readAttempts(ctx, idFilter, idArgs, results, index); err != nil {
+		return crawl.Page{}, fmt.Errorf("read attempts: %w", err)
+	}
+
+	refs, err := s.readArtifactRefs(ctx, idFilter, idArgs)
+	if err != nil {
+		return crawl.Page{}, fmt.Errorf("read artifacts: %w", err)
+	}
+
+	page.Summaries = make([]crawl.Summary, 0, len(crawlIDs))
+
+	for i, crawlID := range crawlIDs {
+		summary := crawl.NewSummary(strconv.FormatInt(crawlID, decimal), userIDs[i], &results[index[crawlID]])
+		summary.Artifacts = refs[crawlID]
+
+		page.Summaries = append(page.Summaries, summary)
+	}
+
+	return page, nil
+}
+
+// sqliteArtifactKinds maps the artifact kinds to what the artifacts table
+// stores.
+var sqliteArtifactKinds = map[crawl.ArtifactKind]string{
+	crawl.ArtifactHTML:           artifactHTML,
+	crawl.ArtifactNetwork:        artifactNetwork,
+	crawl.ArtifactScreenshot:     artifactScreenshot,
+	crawl.ArtifactCaptcha:        artifactCaptcha,
+	crawl.ArtifactAttemptCaptcha: artifactAttemptCaptcha,
+}
+
+func (s *sqliteCrawlStorage) readArtifactRefs(
+	ctx context.Context, filter string, args []any,
+) (map[int64][]crawl.ArtifactRef, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT a.crawl_id, a.kind, a.idx, a.mime_type
+		FROM artifacts a JOIN crawls c ON c.id = a.crawl_id WHERE `+filter+` ORDER BY a.crawl_id, a.idx, a.kind`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	refs := make(map[int64][]crawl.ArtifactRef)
+
+	for rows.Next() {
+		var (
+			crawlID int64
+			kind    string
+			ref     crawl.ArtifactRef
+		)
+
+		if err := rows.Scan(&crawlID, &kind, &ref.Index, &ref.MIMEType); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		for artifactKind, storedKind := range sqliteArtifactKinds {
+			if storedKind == kind {
+				ref.Kind = artifactKind
+			}
+		}
+
+		switch ref.Kind {
+		case crawl.ArtifactHTML:
+			ref.MIMEType = htmlMIMEType
+		case crawl.ArtifactNetwork:
+			ref.MIMEType = textMIMEType
+		}
+
+		ref.Crawl = strconv.FormatInt(crawlID, decimal)
+		refs[crawlID] = append(refs[crawlID], ref)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate: %w", err)
+	}
+
+	return refs, nil
+}
+
+func (s *sqliteCrawlStorage) Artifact(ctx context.Context, ref crawl.ArtifactRef) ([]byte, error) {
+	crawlID, err := strconv.ParseInt(ref.Crawl, decimal, bitSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: crawl `%s`", crawl.ErrInvalidArtifactRef, ref.Crawl)
+	}
+
+	kind, ok := sqliteArtifactKinds[ref.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kind `%s`", crawl.ErrInvalidArtifactRef, ref.Kind)
+	}
+
+	var (
+		data     []byte
+		blobPath sql.NullString
+	)
+
+	err = s.db.QueryRowContext(ctx,
+		`SELECT data, path FROM artifacts WHERE crawl_id = ? AND kind = ? AND idx = ?`,
+		crawlID, kind, ref.Index,
+	).Scan(&data, &blobPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("artifact %s/%s/%d: %w", ref.Crawl, ref.Kind, ref.Index, crawl.ErrArtifactNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("query artifact: %w", err)
+	}
+
+	if !blobPath.Valid {
+		return data, nil
+	}
+
+	data, err = os.ReadFile(filepath.Join(s.blobDir, blobPath.String))
+	if err != nil {
+		return nil, fmt.Errorf("read blob: %w", err)
+	}
+
+	return data, nil
+}
+
+func sqliteQueryFilter(query crawl.Query) (string, []any) {
+	filter := "1 = 1"
+	args := make([]any, 0)
+
+	if len(query.UserIDs) > 0 {
+		userFilter, userArgs := sqliteIn("c.user_id", query.UserIDs)
+		filter += " AND " + userFilter
+		args = append(args, userArgs...)
+	}
+
+	if !query.From.IsZero() {
+		filter += " AND c.ran_at >= ?"
+		args = append(args, query.From.UnixNano())
+	}
+
+	if !query.To.IsZero() {
+		filter += " AND c.ran_at < ?"
+		args = append(args, query.To.UnixNano())
+	}
+
+	if len(query.Statuses) > 0 {
+		conditions := make([]string, 0, len(query.Statuses))
+
+		for _, status := range query.Statuses {
+			switch status {
+			case crawl.StatusError:
+				conditions = append(conditions, "c.error IS NOT NULL")
+			case crawl.StatusInteresting:
+				conditions = append(conditions, "(c.error IS NULL AND c.interesting)")
+			case crawl.StatusOK:
+				conditions = append(conditions, "(c.error IS NULL AND NOT c.interesting)")
+			}
+		}
+
+		if len(conditions) == 0 {
+			conditions = append(conditions, "0 = 1")
+		}
+
+		filter += " AND (" + strings.Join(conditions, " OR ") + ")"
+	}
+
+	return filter, args
+}
+
+func sqliteIn(column string, values []int64) (string, []any) {
+	args := make([]any, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+
+	return column + " IN (?" + strings.Repeat(", ?", len(values)-1) + ")", args
+}
+
+func (s *sqliteCrawlStorage) readResults(
+	ctx context.Context, filter string, args []any,
+) ([]crawl.Result, map[int64]int, error) {
+	results, index, err := s.readCrawls(ctx, filter, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read crawls: %w", err)
+	}
+
+	if err := s.readSteps(ctx, filter, args, results, index); err != nil {
+		return nil, nil, fmt.Errorf("read steps: %w", err)
+	}
+
+	if err := s.readAttempts(ctx, filter, args, results, index); err != nil {
+		return nil, nil, fmt.Errorf("read attempts: %w", err)
+	}
+
+	if err := s.readArtifacts(ctx, filter, args, results, index); err != nil {
+		return nil, nil, fmt.Errorf("read artifacts: %w", err)
+	}
+
+	return results, index, nil
+}
+
+func (s *sqliteCrawlStorage) readCrawls(
+	ctx context.Context, filter string, args []any,
+) ([]crawl.Result, map[int64]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT c.id, c.uid, c.ran_at, c.proxy, c.captcha_solver, c.error, c.interesting, c.retries, c.app_version,
+			c.authorize_ns, c.captcha_solve_ns, c.submit_ns, c.calendar_ns, c.total_ns
+		FROM crawls c WHERE `+filter+` ORDER BY c.ran_at`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	results := make([]crawl.Result, 0)
+	index := make(map[int64]int)
+
+	for rows.Next() {
+		var (
+			crawlID int64
+			ranAt   int64
+			errText sql.NullString
+			result  crawl.Result
+		)
+
+		if err := rows.Scan(
+			&crawlID, &result.ID, &ranAt, &result.Proxy, &result.CaptchaSolver, &errText,
+			&result.SomethingInteresting, &result.Retries, &result.AppVersion,
+			&result.Durations.Authorize, &result.Durations.CaptchaSolve, &result.Durations.Submit,
+			&result.Durations.Calendar, &result.Durations.Total,
+		); err != nil {
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+
+		result.RanAt = time.Unix(0, ranAt).UTC()
+
+		if errText.Valid {
+			result.Err = errors.New(errText.String)
+		}
+
+		index[crawlID] = len(results)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate: %w", err)
+	}
+
+	return results, index, nil
+}
+
+func (s *sqliteCrawlStorage) readSteps(
+	ctx context.Context, filter string, args []any, results []crawl.Result, index map[int64]int,
+) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT s.crawl_id, s.step, s.requests, s.blocked_requests, s.bytes
+		FROM steps s JOIN crawls c ON c.id = s.crawl_id WHERE `+filter, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	for rows.Next() {
+		var (
+			crawlID int64
+			step    int
+			traffic page.Traffic
+		)
+
+		if err := rows.Scan(&crawlID, &step, &traffic.Requests, &traffic.BlockedRequests, &traffic.Bytes); err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+
+		if stat := resultStat(&results[index[crawlID]], step); stat != nil {
+			stat.Traffic = traffic
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) readAttempts(
+	ctx context.Context, filter string, args []any, results []crawl.Result, index map[int64]int,
+) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT a.crawl_id, a.code, a.solver, a.preprocessing, a.confidence, a.cost, a.verdict
+		FROM attempts a JOIN crawls c ON c.id = a.crawl_id WHERE `+filter+` ORDER BY a.crawl_id, a.idx`, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	for rows.Next() {
+		var (
+			crawlID int64
+			attempt crawl.CaptchaAttempt
+			verdict string
+		)
+
+		if err := rows.Scan(
+			&crawlID, &attempt.Code, &attempt.Solver, &attempt.Preprocessing,
+			&attempt.Confidence, &attempt.Cost, &verdict,
+		); err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+
+		attempt.Verdict = crawl.Verdict(verdict)
+
+		result := &results[index[crawlID]]
+		result.Attempts = append(result.Attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) readArtifacts(
+	ctx context.Context, filter string, args []any, results []crawl.Result, index map[int64]int,
+) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT a.crawl_id, a.kind, a.idx, a.mime_type, a.data, a.path
+		FROM artifacts a JOIN crawls c ON c.id = a.crawl_id WHERE `+filter, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	for rows.Next() {
+		var (
+			crawlID  int64
+			kind     string
+			idx      int
+			artifact image.Image
+			blobPath sql.NullString
+		)
+
+		if err := rows.Scan(&crawlID, &kind, &idx, &artifact.MIMEType, &artifact.Bytes, &blobPath); err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+
+		if blobPath.Valid {
+			artifact.Bytes, err = os.ReadFile(filepath.Join(s.blobDir, blobPath.String))
+			if err != nil {
+				return fmt.Errorf("read blob: %w", err)
+			}
+		}
+
+		placeArtifact(&results[index[crawlID]], kind, idx, artifact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate: %w", err)
+	}
+
+	return nil
+}
+
+func placeArtifact(result *crawl.Result, kind string, idx int, artifact image.Image) {
+	if kind == artifactAttemptCaptcha {
+		if idx >= 1 && idx <= len(result.Attempts) {
+			result.Attempts[idx-1].Image = artifact
+		}
+
+		return
+	}
+
+	stat := resultStat(result, idx)
+	if stat == nil {
+		return
+	}
+
+	switch kind {
+	case artifactHTML:
+		stat.HTML = artifact.Bytes
+	case artifactNetwork:
+		stat.Network = artifact.Bytes
+	case artifactScreenshot:
+		stat.Screenshot = artifact
+	case artifactCaptcha:
+		stat.Captcha = page.Captcha{Image: artifact, Presented: true}
+	}
+}
+
+func resultStat(result *crawl.Result, step int) *page.Stat {
+	switch step {
+	case 1:
+		return &result.One
+	case 2:
+		return &result.Two
+	case 3:
+		return &result.Three
+	default:
+		return nil
+	}
+}
+
+func (s *sqliteCrawlStorage) closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		s.logger.Error("failed close", "error", err.Error())
+	}
+}
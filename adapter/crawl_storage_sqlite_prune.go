@@ -0,0 +1,122 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+var _ crawl.Pruner = (*sqliteCrawlStorage)(nil)
+
+func (s *sqliteCrawlStorage) Footprints(ctx context.Context) ([]crawl.Footprint, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT c.id, c.user_id, c.ran_at, c.error IS NOT NULL OR c.interesting,
+			COALESCE(SUM(length(a.data)), 0), GROUP_CONCAT(a.path, char(10))
+		FROM crawls c LEFT JOIN artifacts a ON a.crawl_id = c.id
+		GROUP BY c.id ORDER BY c.ran_at`)
+	if err != nil {
+		return nil, fmt.Errorf("query footprints: %w", err)
+	}
+
+	defer s.closeRows(rows)
+
+	footprints := make([]crawl.Footprint, 0)
+
+	for rows.Next() {
+		var (
+			crawlID   int64
+			ranAt     int64
+			footprint crawl.Footprint
+			blobPaths sql.NullString
+		)
+
+		if err := rows.Scan(
+			&crawlID, &footprint.UserID, &ranAt, &footprint.Notable, &footprint.Bytes, &blobPaths,
+		); err != nil {
+			return nil, fmt.Errorf("scan footprint: %w", err)
+		}
+
+		blobBytes, err := s.blobsSize(blobPaths.String)
+		if err != nil {
+			return nil, fmt.Errorf("blobs size: %w", err)
+		}
+
+		footprint.Ref = strconv.FormatInt(crawlID, decimal)
+		footprint.RanAt = time.Unix(0, ranAt).UTC()
+		footprint.Bytes += blobBytes
+
+		footprints = append(footprints, footprint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate footprints: %w", err)
+	}
+
+	return footprints, nil
+}
+
+func (s *sqliteCrawlStorage) blobsSize(blobPaths string) (int64, error) {
+	if blobPaths == "" {
+		return 0, nil
+	}
+
+	var size int64
+
+	for _, blobPath := range strings.Split(blobPaths, "\n") {
+		info, err := os.Stat(filepath.Join(s.blobDir, blobPath))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("stat blob: %w", err)
+		}
+
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+// Remove deletes the crawl rows and their blobs on disk. The database file does
+// not shrink, sqlite reuses the freed pages for the next crawls.
+func (s *sqliteCrawlStorage) Remove(ctx context.Context, footprints []crawl.Footprint) error {
+	for _, footprint := range footprints {
+		if err := s.removeCrawl(ctx, footprint); err != nil {
+			return fmt.Errorf("remove crawl %s: %w", footprint.Ref, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sqliteCrawlStorage) removeCrawl(ctx context.Context, footprint crawl.Footprint) error {
+	crawlID, err := strconv.ParseInt(footprint.Ref, decimal, bitSize)
+	if err != nil {
+		return fmt.Errorf("parse crawl id `%s`: %w", footprint.Ref, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM crawls WHERE id = ?`, crawlID); err != nil {
+		return fmt.Errorf("delete crawl: %w", err)
+	}
+
+	if s.blobDir == "" {
+		return nil
+	}
+
+	crawlBlobDir := filepath.Join(s.blobDir, strconv.FormatInt(footprint.UserID, decimal), footprint.Ref)
+	if err := os.RemoveAll(crawlBlobDir); err != nil {
+		return fmt.Errorf("remove blobs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+func newTestSQLiteCrawlStorage(t *testing.T, cfg SQLiteCrawlStorageConfig) crawl.Storage {
+	t.Helper()
+
+	storage, err := NewSQLiteCrawlStorage(cfg, log.NewLogger())
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := storage.(*sqliteCrawlStorage).Close(); err != nil {
+			t.Errorf("close: %v", err)
+		}
+	})
+
+	return storage
+}
+
+func TestSQLiteCrawlStorage_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{
+		Path:          filepath.Join(dir, "crawls.db"),
+		BlobDirectory: filepath.Join(dir, "blobs"),
+		MaxBlobSize:   16,
+	})
+
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 0, time.Local)
+	screenshot := image.Image{MIMEType: image.MIMETypePNG, Bytes: bytes.Repeat([]byte("s"), 64)}
+	captchaImage := image.Image{MIMEType: image.MIMETypeJPEG, Bytes: []byte("captcha")}
+
+	result := crawl.Result{
+		One: page.Stat{
+			HTML:       []byte("<html>one</html>"),
+			Screenshot: screenshot,
+			Captcha:    page.Captcha{Presented: true, Image: captchaImage},
+			Traffic:    page.Traffic{Requests: 10, BlockedRequests: 2, Bytes: 1024},
+		},
+		Two:           page.Stat{Network: []byte("GET /")},
+		RanAt:         ranAt,
+		ID:            crawl.NewID(),
+		Proxy:         "socks5://host:1080",
+		CaptchaSolver: "2captcha",
+		Retries:       2,
+		Durations:     crawl.Durations{Authorize: time.Second, Submit: time.Millisecond, Total: time.Minute},
+		AppVersion:    "v1.2.3",
+		Attempts: []crawl.CaptchaAttempt{
+			{Image: captchaImage, Code: "123456", Solver: "2captcha", Confidence: 1, Cost: 0.001, Verdict: crawl.VerdictAccepted},
+		},
+		Err:                  errors.New("calendar is empty"),
+		SomethingInteresting: true,
+	}
+
+	if err := storage.Save(ctx, 42, &result); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	results, err := storage.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	got := results[0]
+
+	if got.Err == nil || got.Err.Error() != result.Err.Error() {
+		t.Errorf("expected error %v, got %v", result.Err, got.Err)
+	}
+
+	got.Err, result.Err = nil, nil
+
+	if !got.RanAt.Equal(ranAt) {
+		t.Errorf("expected ran at %s, got %s", ranAt, got.RanAt)
+	}
+
+	got.RanAt = ranAt
+
+	if !reflect.DeepEqual(got, result) {
+		t.Errorf("expected %+v, got %+v", result, got)
+	}
+
+	blobs, err := filepath.Glob(filepath.Join(dir, "blobs", "42", "*", "*"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+
+	if len(blobs) != 1 || filepath.Base(blobs[0]) != "screenshot-1" {
+		t.Errorf("expected only the screenshot on disk, got %v", blobs)
+	}
+}
+
+func TestSQLiteCrawlStorage_SaveRollbackRemovesBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{
+		Path:          filepath.Join(dir, "crawls.db"),
+		BlobDirectory: filepath.Join(dir, "blobs"),
+		MaxBlobSize:   16,
+	})
+
+	// The attempts are saved after the screenshots, failing them rolls back
+	// a crawl whose blobs are already on disk.
+	if _, err := storage.(*sqliteCrawlStorage).db.ExecContext(ctx,
+		`CREATE TRIGGER fail_attempts BEFORE INSERT ON attempts BEGIN SELECT RAISE(ABORT, 'boom'); END`,
+	); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	result := crawl.Result{
+		One: page.Stat{
+			Screenshot: image.Image{MIMEType: image.MIMETypePNG, Bytes: bytes.Repeat([]byte("s"), 64)},
+		},
+		RanAt:    time.Date(2024, time.March, 5, 10, 20, 30, 0, time.Local),
+		ID:       crawl.NewID(),
+		Attempts: []crawl.CaptchaAttempt{{Code: "123456", Verdict: crawl.VerdictRejected}},
+	}
+
+	if err := storage.Save(ctx, 42, &result); err == nil {
+		t.Fatal("expected save to fail")
+	}
+
+	blobs, err := filepath.Glob(filepath.Join(dir, "blobs", "42", "*", "*"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+
+	if len(blobs) != 0 {
+		t.Errorf("expected no blobs after rollback, got %v", blobs)
+	}
+
+	crawlDirs, err := filepath.Glob(filepath.Join(dir, "blobs", "42", "*"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+
+	if len(crawlDirs) != 0 {
+		t.Errorf("expected no crawl directories after rollback, got %v", crawlDirs)
+	}
+}
+
+func TestSQLiteCrawlStorage_ListByDay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "crawls.db")
+	storage := newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{Path: path})
+
+	day := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.Local)
+	saves := []struct {
+		userID int64
+		ranAt  time.Time
+	}{
+		{42, day.Add(-time.Second)},
+		{42, day.Add(time.Hour)},
+		{42, day.Add(2 * time.Hour)},
+		{7, day.Add(time.Hour)},
+		{42, day.AddDate(0, 0, 1)},
+	}
+
+	for _, save := range saves {
+		if err := storage.Save(ctx, save.userID, &crawl.Result{RanAt: save.ranAt}); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	results, err := storage.ListResults(ctx, 42, day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 2 || !results[0].RanAt.Equal(day.Add(time.Hour)) || !results[1].RanAt.Equal(day.Add(2*time.Hour)) {
+		t.Errorf("expected the two crawls of the day in order, got %+v", results)
+	}
+
+	if _, err := storage.ListResults(ctx, 42, day.AddDate(0, 0, 2)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a day without crawls to be fs.ErrNotExist, got %v", err)
+	}
+
+	users, err := storage.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+
+	if !reflect.DeepEqual(users, []int64{7, 42}) {
+		t.Errorf("expected users [7 42], got %v", users)
+	}
+}
+
+func TestSQLiteCrawlStorage_Reopen(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := SQLiteCrawlStorageConfig{Path: filepath.Join(t.TempDir(), "nested", "crawls.db")}
+	ranAt := time.Date(2024, time.March, 5, 10, 20, 30, 0, time.Local)
+
+	storage, err := NewSQLiteCrawlStorage(cfg, log.NewLogger())
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+
+	if err := storage.Save(ctx, 42, &crawl.Result{RanAt: ranAt}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := storage.(*sqliteCrawlStorage).Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if _, err := os.Stat(cfg.Path); err != nil {
+		t.Fatalf("expected database file: %v", err)
+	}
+
+	reopened := newTestSQLiteCrawlStorage(t, cfg)
+
+	results, err := reopened.ListResults(ctx, 42, ranAt)
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Errorf("expected the saved crawl after reopening, got %d", len(results))
+	}
+}
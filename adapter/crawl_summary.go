@@ -0,0 +1,192 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+const (
+	htmlMIMEType = "text/html; charset=utf-8"
+	textMIMEType = "text/plain; charset=utf-8"
+
+	crawlSteps = 3
+)
+
+// metadataFiles are what summaries read, every other file of a crawl is an
+// artifact and is only checked for.
+var metadataFiles = []string{
+	"error.txt", "proxy.txt", "solver.txt", "interesting.txt", "attempts.json", "manifest.json",
+}
+
+func isMetadataFile(filePath string) bool {
+	return slices.Contains(metadataFiles, path.Base(filePath))
+}
+
+// readDaySummaries summarises the crawls of a user day, artifacts are
+// referenced by the crawl path user/date/time.
+func readDaySummaries(
+	ctx context.Context, dailyCrawls fs.FS, userID int64, dateDirName string,
+) ([]crawl.Summary, error) {
+	crawlTimes, err := fs.ReadDir(dailyCrawls, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	summaries := make([]crawl.Summary, 0, len(crawlTimes))
+
+	for i := range crawlTimes {
+		if !crawlTimes[i].IsDir() {
+			continue
+		}
+
+		timeDirName := crawlTimes[i].Name()
+
+		crawlResult, err := readCrawlMetadata(ctx, dailyCrawls, timeDirName)
+		if err != nil {
+			return nil, fmt.Errorf("read crawl: %w", err)
+		}
+
+		if err := setRanAtFromDir(&crawlResult, dateDirName, timeDirName); err != nil {
+			return nil, err
+		}
+
+		ref := path.Join(strconv.FormatInt(userID, decimal), dateDirName, timeDirName)
+		summary := crawl.NewSummary(ref, userID, &crawlResult)
+
+		summary.Artifacts, err = readArtifactRefs(ctx, dailyCrawls, timeDirName, ref, len(crawlResult.Attempts))
+		if err != nil {
+			return nil, fmt.Errorf("read artifacts: %w", err)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// setRanAtFromDir falls back to the directory names for crawls saved before
+// manifests existed.
+func setRanAtFromDir(result *crawl.Result, dateDirName, timeDirName string) error {
+	if !result.RanAt.IsZero() {
+		return nil
+	}
+
+	ranAt, err := parseCrawlDirName(dateDirName, timeDirName)
+	if err != nil {
+		return fmt.Errorf("time parse: %w", err)
+	}
+
+	result.RanAt = ranAt.UTC()
+
+	return nil
+}
+
+func readArtifactRefs(
+	ctx context.Context, fsys fs.FS, crawlDir, ref string, attempts int,
+) ([]crawl.ArtifactRef, error) {
+	candidates := make([]crawl.ArtifactRef, 0)
+
+	for step := 1; step <= crawlSteps; step++ {
+		candidates = append(candidates,
+			crawl.ArtifactRef{Kind: crawl.ArtifactHTML, Index: step, MIMEType: htmlMIMEType},
+			crawl.ArtifactRef{Kind: crawl.ArtifactNetwork, Index: step, MIMEType: textMIMEType},
+		)
+
+		for _, kind := range []crawl.ArtifactKind{crawl.ArtifactScreenshot, crawl.ArtifactCaptcha} {
+			for _, mimeType := range imageMIMETypes {
+				candidates = append(candidates, crawl.ArtifactRef{Kind: kind, Index: step, MIMEType: mimeType})
+			}
+		}
+	}
+
+	for i := 1; i <= attempts; i++ {
+		for _, mimeType := range imageMIMETypes {
+			candidates = append(candidates, crawl.ArtifactRef{
+				Kind: crawl.ArtifactAttemptCaptcha, Index: i, MIMEType: mimeType,
+			})
+		}
+	}
+
+	type slot struct {
+		kind  crawl.ArtifactKind
+		index int
+	}
+
+	refs := make([]crawl.ArtifactRef, 0)
+	found := make(map[slot]bool)
+
+	for _, candidate := range candidates {
+		candidate.Crawl = ref
+
+		artifactFile, err := artifactPath(candidate)
+		if err != nil {
+			return nil, err
+		}
+
+		exists, err := fileExists(ctx, fsys, path.Join(crawlDir, artifactFile))
+		if err != nil {
+			return nil, err
+		}
+
+		// The first image type found wins, the same way readImage picks it.
+		if key := (slot{candidate.Kind, candidate.Index}); exists && !found[key] {
+			found[key] = true
+			refs = append(refs, candidate)
+		}
+	}
+
+	return refs, nil
+}
+
+// artifactPath is where the artifact lives inside the crawl directory.
+func artifactPath(ref crawl.ArtifactRef) (string, error) {
+	if ref.Index < 1 || ref.Kind != crawl.ArtifactAttemptCaptcha && ref.Index > crawlSteps {
+		return "", fmt.Errorf("%w: %s index %d", crawl.ErrInvalidArtifactRef, ref.Kind, ref.Index)
+	}
+
+	index := strconv.Itoa(ref.Index)
+	extension := image.Image{MIMEType: ref.MIMEType}.Extension()
+
+	switch ref.Kind {
+	case crawl.ArtifactHTML:
+		return path.Join(index, "page.html"), nil
+	case crawl.ArtifactNetwork:
+		return path.Join(index, "network.txt"), nil
+	case crawl.ArtifactScreenshot:
+		return path.Join(index, "screenshot."+extension), nil
+	case crawl.ArtifactCaptcha:
+		return path.Join(index, "captcha."+extension), nil
+	case crawl.ArtifactAttemptCaptcha:
+		return path.Join("attempts", index, "captcha."+extension), nil
+	default:
+		return "", fmt.Errorf("%w: unknown kind `%s`", crawl.ErrInvalidArtifactRef, ref.Kind)
+	}
+}
+
+// parseCrawlRef splits a user/date/time crawl reference, refusing anything
+// that would step out of the storage.
+func parseCrawlRef(ref string) (userDirName, dateDirName, timeDirName string, err error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || !fs.ValidPath(ref) {
+		return "", "", "", fmt.Errorf("%w: crawl `%s`", crawl.ErrInvalidArtifactRef, ref)
+	}
+
+	if _, err := strconv.ParseInt(parts[0], decimal, bitSize); err != nil {
+		return "", "", "", fmt.Errorf("%w: user id `%s`", crawl.ErrInvalidArtifactRef, parts[0])
+	}
+
+	if _, err := time.Parse(time.DateOnly, parts[1]); err != nil {
+		return "", "", "", fmt.Errorf("%w: date `%s`", crawl.ErrInvalidArtifactRef, parts[1])
+	}
+
+	return parts[0], parts[1], parts[2], nil
+}
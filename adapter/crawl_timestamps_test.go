@@ -0,0 +1,246 @@
+package adapter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+// testCrawlStorageTimestamps saves crawls sharing a start time and crawls
+// around the New York DST switches, every one of them must come back apart
+// and at the exact instant it ran.
+func testCrawlStorageTimestamps(t *testing.T, storage crawl.Storage) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	collision := time.Date(2024, time.March, 5, 10, 20, 30, 123456789, time.UTC)
+	// 02:30 does not exist on the spring forward day, it is 03:30 EDT.
+	springForward := time.Date(2024, time.March, 10, 1, 59, 59, 999000000, newYork)
+	// 01:30 happens twice on the fall back day, an hour apart.
+	fallBackEDT := time.Date(2024, time.November, 3, 5, 30, 0, 1000, time.UTC)
+	fallBackEST := fallBackEDT.Add(time.Hour)
+
+	results := []crawl.Result{
+		{RanAt: collision, Proxy: "first"},
+		{RanAt: collision, Proxy: "second"},
+		{RanAt: springForward, Proxy: "spring"},
+		{RanAt: springForward.Add(time.Millisecond), Proxy: "spring-after"},
+		{RanAt: fallBackEST.In(newYork), Proxy: "fall-est"},
+		{RanAt: fallBackEDT.In(newYork), Proxy: "fall-edt"},
+	}
+
+	for i := range results {
+		if err := storage.Save(ctx, 42, &results[i]); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	page, err := storage.Query(ctx, crawl.Query{Order: crawl.OldestFirst})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	expected := []string{"first", "second", "spring", "spring-after", "fall-edt", "fall-est"}
+	if len(page.Summaries) != len(expected) {
+		t.Fatalf("expected %d crawls, got %d", len(expected), len(page.Summaries))
+	}
+
+	byProxy := make(map[string]crawl.Result, len(results))
+	for _, result := range results {
+		byProxy[result.Proxy] = result
+	}
+
+	for i, summary := range page.Summaries {
+		// The two collided crawls may come back in either order.
+		if i > 1 && summary.Proxy != expected[i] {
+			t.Errorf("expected %s at %d, got %s", expected[i], i, summary.Proxy)
+		}
+
+		saved := byProxy[summary.Proxy]
+
+		if !summary.RanAt.Equal(saved.RanAt) {
+			t.Errorf("%s: expected ran at %v, got %v", summary.Proxy, saved.RanAt, summary.RanAt)
+		}
+
+		if summary.ID != saved.ID {
+			t.Errorf("%s: expected id %s, got %s", summary.Proxy, saved.ID, summary.ID)
+		}
+	}
+
+	if results[0].ID == results[1].ID {
+		t.Errorf("expected collided crawls to get different ids, both got %s", results[0].ID)
+	}
+
+	// The New York calendar day of the fall back holds both 01:30 crawls.
+	fallBackDay, err := storage.ListResults(ctx, 42, time.Date(2024, time.November, 3, 0, 0, 0, 0, newYork))
+	if err != nil {
+		t.Fatalf("list results: %v", err)
+	}
+
+	if len(fallBackDay) != 2 {
+		t.Fatalf("expected 2 crawls on the fall back day, got %d", len(fallBackDay))
+	}
+}
+
+func TestFileSystemCrawlStorage_Timestamps(t *testing.T) {
+	t.Parallel()
+
+	testCrawlStorageTimestamps(t, MustNewFileSystemCrawlStorage(t.TempDir(), ArchiveZstd, log.NewLogger()))
+}
+
+func TestSQLiteCrawlStorage_Timestamps(t *testing.T) {
+	t.Parallel()
+
+	testCrawlStorageTimestamps(t, newTestSQLiteCrawlStorage(t, SQLiteCrawlStorageConfig{
+		Path: filepath.Join(t.TempDir(), "crawls.db"),
+	}))
+}
+
+func TestS3CrawlStorage_Timestamps(t *testing.T) {
+	t.Parallel()
+
+	testCrawlStorageTimestamps(t, newTestS3CrawlStorage(t, ""))
+}
+
+func TestFileSystemCrawlStorage_MigrateLayout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	legacyDir := filepath.Join(dir, "42", "2024-03-05", "23:30:15")
+	if err := os.MkdirAll(filepath.Join(legacyDir, "1"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(legacyDir, "proxy.txt"), []byte("proxy"), 0644); err != nil {
+		t.Fatalf("write proxy: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(legacyDir, "1", "page.html"), []byte("<html/>"), 0644); err != nil {
+		t.Fatalf("write page: %v", err)
+	}
+
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	if _, err := os.Stat(legacyDir); err != nil {
+		t.Fatalf("expected opening the storage to leave the legacy crawl as is: %v", err)
+	}
+
+	migrated, err := storage.(crawl.Migrator).Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if _, err := os.Stat(legacyDir); migrated != 1 || !os.IsNotExist(err) {
+		t.Fatalf("expected legacy crawl directory to be moved, migrated %d, stat: %v", migrated, err)
+	}
+
+	ranAt := time.Date(2024, time.March, 5, 23, 30, 15, 0, time.Local)
+
+	page, err := storage.Query(context.Background(), crawl.Query{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	if len(page.Summaries) != 1 {
+		t.Fatalf("expected 1 crawl, got %d", len(page.Summaries))
+	}
+
+	summary := page.Summaries[0]
+
+	if !summary.RanAt.Equal(ranAt) || summary.ID == "" || summary.Proxy != "proxy" {
+		t.Errorf("expected crawl at %v with an id, got %v with id `%s` and proxy `%s`",
+			ranAt, summary.RanAt, summary.ID, summary.Proxy)
+	}
+
+	expectedDir := filepath.Join(dir, "42", ranAt.UTC().Format(time.DateOnly),
+		ranAt.UTC().Format(crawlTimeLayout)+crawlDirSeparator+summary.ID)
+	if _, err := os.Stat(filepath.Join(expectedDir, "1", "page.html")); err != nil {
+		t.Errorf("expected crawl moved to %s: %v", expectedDir, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(expectedDir, "manifest.json")); err != nil {
+		t.Errorf("expected manifest written: %v", err)
+	}
+
+	// A second run finds nothing left to move.
+	migrated, err = storage.(crawl.Migrator).Migrate(context.Background())
+	if err != nil || migrated != 0 {
+		t.Fatalf("expected nothing left to migrate, migrated %d: %v", migrated, err)
+	}
+
+	page, err = storage.Query(context.Background(), crawl.Query{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	if len(page.Summaries) != 1 || page.Summaries[0].ID != summary.ID {
+		t.Errorf("expected the migrated crawl kept as is, got %+v", page.Summaries)
+	}
+}
+
+func TestFileSystemCrawlStorage_MigrateLayoutIntoArchivedDay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage := MustNewFileSystemCrawlStorage(dir, ArchiveZstd, log.NewLogger())
+
+	ranAt := time.Date(2024, time.March, 5, 23, 30, 15, 0, time.Local)
+
+	// The day the legacy crawl moves to is archived already.
+	neighbour := crawl.Result{RanAt: ranAt.Add(-time.Second)}
+	if err := storage.Save(ctx, 42, &neighbour); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := storage.(crawl.Archiver).Archive(ctx, ranAt.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	legacyDir := filepath.Join(dir, "42", ranAt.Format(time.DateOnly), ranAt.Format(time.TimeOnly))
+	if err := os.MkdirAll(filepath.Join(legacyDir, "1"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(legacyDir, "1", "page.html"), []byte("<html/>"), 0644); err != nil {
+		t.Fatalf("write page: %v", err)
+	}
+
+	migrated, err := storage.(crawl.Migrator).Migrate(ctx)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if migrated != 1 {
+		t.Fatalf("expected 1 migrated crawl, got %d", migrated)
+	}
+
+	targetDay := ranAt.UTC().Format(time.DateOnly)
+
+	if _, err := os.Stat(filepath.Join(dir, "42", targetDay)); !os.IsNotExist(err) {
+		t.Errorf("expected the migrated crawl packed into the archive, stat: %v", err)
+	}
+
+	page, err := storage.Query(ctx, crawl.Query{Order: crawl.OldestFirst})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	if len(page.Summaries) != 2 || page.Summaries[0].ID != neighbour.ID || !page.Summaries[1].RanAt.Equal(ranAt) {
+		t.Errorf("expected the archived and the migrated crawls, got %+v", page.Summaries)
+	}
+}
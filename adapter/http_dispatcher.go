@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type HTTPDispatcherConfig struct {
+	BaseURL *url.URL
+	Timeout time.Duration
+}
+
+type httpDispatcher struct {
+	baseURL   *url.URL
+	timeout   time.Duration
+	startedAt time.Time
+}
+
+func NewHTTPDispatcher(cfg HTTPDispatcherConfig) (page.Dispatcher, error) {
+	if cfg.BaseURL == nil {
+		return nil, fmt.Errorf("no base URL provided")
+	}
+
+	return &httpDispatcher{
+		baseURL:   cfg.BaseURL,
+		timeout:   cfg.Timeout,
+		startedAt: time.Now(),
+	}, nil
+}
+
+func MustNewHTTPDispatcher(cfg HTTPDispatcherConfig) page.Dispatcher {
+	dispatcher, err := NewHTTPDispatcher(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return dispatcher
+}
+
+func (d *httpDispatcher) NewNavigator(id, cd string, options page.NavigatorOptions) (page.Navigator, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cookie jar: %w", err)
+	}
+
+	transport := &http.Transport{}
+
+	if options.Proxy != nil {
+		transport.Proxy = http.ProxyURL(options.Proxy.URL)
+	}
+
+	navigator := &httpNavigator{
+		baseURL: d.baseURL,
+		id:      id,
+		cd:      cd,
+	}
+
+	navigator.client = &http.Client{
+		Jar:     jar,
+		Timeout: d.timeout,
+		Transport: &networkLogTransport{
+			next:    transport,
+			buffer:  &navigator.network,
+			traffic: &navigator.traffic,
+		},
+	}
+
+	return navigator, nil
+}
+
+func (d *httpDispatcher) Health() page.Health {
+	return page.Health{
+		Healthy: true,
+		Since:   d.startedAt,
+	}
+}
+
+func (d *httpDispatcher) Close() error {
+	return nil
+}
+
+type networkLogTransport struct {
+	next    http.RoundTripper
+	buffer  *bytesBuffer
+	traffic *page.Traffic
+}
+
+func (t *networkLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.buffer.log(fmt.Sprintf("Request: %v, headers: %v\n", req.URL, req.Header))
+	t.traffic.Requests++
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.buffer.log(fmt.Sprintf("Response: %v, Status: %v, headers: %v\n", req.URL, resp.StatusCode, resp.Header))
+
+	resp.Body = &countingReadCloser{ReadCloser: resp.Body, bytes: &t.traffic.Bytes}
+
+	return resp, nil
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	bytes *int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	*c.bytes += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,534 @@
+package adapter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type httpNavigator struct {
+	client  *http.Client
+	baseURL *url.URL
+	id, cd  string
+	network bytesBuffer
+	traffic page.Traffic
+	current *htmlPage
+}
+
+type htmlPage struct {
+	url  *url.URL
+	body []byte
+	doc  *html.Node
+}
+
+func (c *httpNavigator) buildURL() *url.URL {
+	query := url.Values{}
+	query.Set("id", c.id)
+	query.Set("cd", c.cd)
+
+	orderInfoURL := c.baseURL.JoinPath(orderInfoPath)
+	orderInfoURL.RawQuery = query.Encode()
+
+	return orderInfoURL
+}
+
+func (c *httpNavigator) OpenPageToAuthorize() (page.Stat, error) {
+	if c.current != nil {
+		return page.Stat{}, fmt.Errorf("page is already opened")
+	}
+
+	c.network.Reset()
+	c.traffic = page.Traffic{}
+
+	authorizationPage, err := c.get(c.buildURL())
+	if err != nil {
+		return c.stat(authorizationPage), fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
+	}
+
+	if _, err := c.findForm(authorizationPage); err != nil {
+		return c.stat(authorizationPage), fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
+	}
+
+	captchaImage, err := c.fetchCaptcha(authorizationPage)
+	if err != nil {
+		return c.stat(authorizationPage), fmt.Errorf("could not fetch captcha: %w", err)
+	}
+
+	c.current = authorizationPage
+
+	stat := c.stat(authorizationPage)
+	stat.Captcha = page.Captcha{
+		Presented: true,
+		Image:     captchaImage,
+	}
+
+	return stat, nil
+}
+
+func (c *httpNavigator) fetchCaptcha(authorizationPage *htmlPage) (image.Image, error) {
+	images := findElements(authorizationPage.doc, func(n *html.Node) bool {
+		return isElement(n, "img")
+	})
+
+	if len(images) != 1 {
+		return image.Image{}, fmt.Errorf("expected 1 image, got %d", len(images))
+	}
+
+	src, _ := attr(images[0], "src")
+
+	imageURL, err := authorizationPage.url.Parse(src)
+	if err != nil {
+		return image.Image{}, fmt.Errorf("parse image url: %w", err)
+	}
+
+	resp, err := c.client.Get(imageURL.String())
+	if err != nil {
+		return image.Image{}, fmt.Errorf("get image: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return image.Image{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	imageBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return image.Image{}, fmt.Errorf("read image: %w", err)
+	}
+
+	croppedImage, err := image.CropCaptcha(image.Detect(imageBytes), captchaCroppingRect)
+	if err != nil {
+		return image.Image{}, fmt.Errorf("crop image: %w", err)
+	}
+
+	return croppedImage, nil
+}
+
+func (c *httpNavigator) SubmitAuthorization(code string) (page.Stat, error) {
+	if c.current == nil {
+		return page.Stat{}, fmt.Errorf("no page opened")
+	}
+
+	c.network.Reset()
+	c.traffic = page.Traffic{}
+
+	form, err := c.findForm(c.current)
+	if err != nil {
+		return page.Stat{}, fmt.Errorf("could not get form: %w", err)
+	}
+
+	inputs := findElements(form, func(n *html.Node) bool {
+		return isElement(n, "input") && n.Parent != nil && hasClass(n.Parent, "inp")
+	})
+
+	if len(inputs) != 3 {
+		return page.Stat{}, fmt.Errorf("expected 3 input, got %d", len(inputs))
+	}
+
+	submitButton, err := c.findSingleInput(form, "submit")
+	if err != nil {
+		return page.Stat{}, fmt.Errorf("could not get submit button: %w", err)
+	}
+
+	values := formValues(form)
+
+	captchaName, _ := attr(inputs[2], "name")
+	values.Set(captchaName, code)
+
+	submitName, _ := attr(submitButton, "name")
+	submitValue, _ := attr(submitButton, "value")
+	values.Set(submitName, submitValue)
+
+	nextPage, err := c.submit(c.current, form, values)
+	if err != nil {
+		return c.stat(nextPage), fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
+	}
+
+	if !c.isAt(nextPage, orderInfoPath) {
+		return c.stat(nextPage), fmt.Errorf("%w: unexpected page %s", page.ErrNavigationFailed, nextPage.url.Path)
+	}
+
+	if err := c.checkCaptchaSolved(nextPage); err != nil {
+		return c.stat(nil), fmt.Errorf("check captcha solved: %w", err)
+	}
+
+	c.current = nextPage
+
+	return c.stat(nextPage), nil
+}
+
+func (c *httpNavigator) checkCaptchaSolved(authorizationPage *htmlPage) error {
+	panels := findElements(authorizationPage.doc, func(n *html.Node) bool {
+		id, _ := attr(n, "id")
+
+		return n.Type == html.ElementNode && id == "center-panel"
+	})
+
+	for _, panel := range panels {
+		children := elementChildren(panel)
+
+		const captchaErrBlockIdx = 10
+
+		if len(children) <= captchaErrBlockIdx || !isElement(children[captchaErrBlockIdx], "div") {
+			continue
+		}
+
+		for _, child := range elementChildren(children[captchaErrBlockIdx]) {
+			if isElement(child, "span") {
+				return page.ErrCaptchaNotSolved
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *httpNavigator) OpenSlotBookingPage() (page.Stat, error) {
+	if c.current == nil {
+		return page.Stat{}, fmt.Errorf("no page opened")
+	}
+
+	c.network.Reset()
+	c.traffic = page.Traffic{}
+
+	form, err := c.findForm(c.current)
+	if err != nil {
+		return page.Stat{}, fmt.Errorf("could not get form: %w", err)
+	}
+
+	button, err := c.findSingleInput(form, "image")
+	if err != nil {
+		return page.Stat{}, fmt.Errorf("could not get input: %w", err)
+	}
+
+	values := formValues(form)
+
+	buttonName, _ := attr(button, "name")
+	values.Set(buttonName+".x", "1")
+	values.Set(buttonName+".y", "1")
+
+	calendarPage, err := c.submit(c.current, form, values)
+	if err != nil {
+		return c.stat(calendarPage), fmt.Errorf("%w: %w", page.ErrNavigationFailed, err)
+	}
+
+	if !c.isAt(calendarPage, spCalendarPath) {
+		return c.stat(calendarPage), fmt.Errorf("%w: unexpected page %s", page.ErrNavigationFailed, calendarPage.url.Path)
+	}
+
+	c.current = calendarPage
+
+	somethingInteresting, err := c.isSomethingInteresting(calendarPage)
+	if err != nil {
+		return c.stat(calendarPage), fmt.Errorf("check if somthing interesting: %w", err)
+	}
+
+	stat := c.stat(calendarPage)
+	stat.SomethingInteresting = somethingInteresting
+
+	return stat, nil
+}
+
+func (c *httpNavigator) isSomethingInteresting(calendarPage *htmlPage) (bool, error) {
+	button, err := c.findSingleInput(calendarPage.doc, "submit")
+	if err != nil {
+		return false, err
+	}
+
+	if _, disabled := attr(button, "disabled"); !disabled {
+		return true, nil
+	}
+
+	panels := findElements(calendarPage.doc, func(n *html.Node) bool {
+		id, _ := attr(n, "id")
+
+		return n.Type == html.ElementNode && id == "center-panel"
+	})
+
+	if len(panels) != 1 {
+		return false, fmt.Errorf("expected 1 center panel, got %d", len(panels))
+	}
+
+	if !strings.Contains(strings.ToLower(textContent(panels[0])), "нет свободного времени") {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (c *httpNavigator) findForm(htmlPage *htmlPage) (*html.Node, error) {
+	forms := findElements(htmlPage.doc, func(n *html.Node) bool {
+		name, _ := attr(n, "name")
+
+		return isElement(n, "form") && name == "aspnetForm"
+	})
+
+	if len(forms) != 1 {
+		return nil, fmt.Errorf("expected 1 form, got %d", len(forms))
+	}
+
+	return forms[0], nil
+}
+
+func (c *httpNavigator) findSingleInput(root *html.Node, inputType string) (*html.Node, error) {
+	inputs := findElements(root, func(n *html.Node) bool {
+		t, _ := attr(n, "type")
+
+		return isElement(n, "input") && strings.EqualFold(t, inputType)
+	})
+
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("expected 1 input, got %d", len(inputs))
+	}
+
+	return inputs[0], nil
+}
+
+func (c *httpNavigator) isAt(htmlPage *htmlPage, path string) bool {
+	expectedPath := c.baseURL.JoinPath(path).Path
+
+	return strings.EqualFold(strings.TrimPrefix(htmlPage.url.Path, "/"), strings.TrimPrefix(expectedPath, "/"))
+}
+
+func (c *httpNavigator) get(pageURL *url.URL) (*htmlPage, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	return c.do(req)
+}
+
+func (c *httpNavigator) submit(current *htmlPage, form *html.Node, values url.Values) (*htmlPage, error) {
+	action, _ := attr(form, "action")
+
+	actionURL, err := current.url.Parse(action)
+	if err != nil {
+		return nil, fmt.Errorf("parse form action: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, actionURL.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.do(req)
+}
+
+func (c *httpNavigator) do(req *http.Request) (*htmlPage, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+
+	result := &htmlPage{
+		url:  resp.Request.URL,
+		body: body,
+		doc:  doc,
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return result, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return result, nil
+}
+
+func (c *httpNavigator) stat(htmlPage *htmlPage) page.Stat {
+	stat := page.Stat{
+		Network: c.networkBytes(),
+		Traffic: c.traffic,
+	}
+
+	if htmlPage != nil {
+		stat.HTML = htmlPage.body
+	}
+
+	return stat
+}
+
+func (c *httpNavigator) networkBytes() []byte {
+	return bytes.Clone(c.network.Bytes())
+}
+
+func (c *httpNavigator) Close() error {
+	c.client.CloseIdleConnections()
+
+	return nil
+}
+
+func formValues(form *html.Node) url.Values {
+	values := url.Values{}
+
+	fields := findElements(form, func(n *html.Node) bool {
+		return isElement(n, "input") || isElement(n, "select") || isElement(n, "textarea")
+	})
+
+	for _, field := range fields {
+		name, ok := attr(field, "name")
+		if !ok || name == "" {
+			continue
+		}
+
+		if _, disabled := attr(field, "disabled"); disabled {
+			continue
+		}
+
+		switch field.Data {
+		case "select":
+			for _, value := range selectValues(field) {
+				values.Add(name, value)
+			}
+		case "textarea":
+			values.Add(name, textContent(field))
+		default:
+			if value, ok := inputValue(field); ok {
+				values.Add(name, value)
+			}
+		}
+	}
+
+	return values
+}
+
+func inputValue(input *html.Node) (string, bool) {
+	inputType, _ := attr(input, "type")
+	value, _ := attr(input, "value")
+
+	switch strings.ToLower(inputType) {
+	case "submit", "image", "button", "reset", "file":
+		return "", false
+	case "checkbox", "radio":
+		if _, checked := attr(input, "checked"); !checked {
+			return "", false
+		}
+	}
+
+	return value, true
+}
+
+// selectValues returns the selected options of a select, a single select with
+// none selected submits its first option as browsers do.
+func selectValues(sel *html.Node) []string {
+	options := findElements(sel, func(n *html.Node) bool {
+		return isElement(n, "option")
+	})
+
+	_, multiple := attr(sel, "multiple")
+	values := make([]string, 0)
+
+	for _, option := range options {
+		if _, selected := attr(option, "selected"); selected {
+			values = append(values, optionValue(option))
+		}
+	}
+
+	if len(values) == 0 && !multiple && len(options) > 0 {
+		values = append(values, optionValue(options[0]))
+	}
+
+	return values
+}
+
+func optionValue(option *html.Node) string {
+	if value, ok := attr(option, "value"); ok {
+		return value
+	}
+
+	return strings.TrimSpace(textContent(option))
+}
+
+func findElements(root *html.Node, match func(*html.Node) bool) []*html.Node {
+	found := make([]*html.Node, 0)
+
+	var walk func(n *html.Node)
+
+	walk = func(n *html.Node) {
+		if match(n) {
+			found = append(found, n)
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(root)
+
+	return found
+}
+
+func elementChildren(n *html.Node) []*html.Node {
+	children := make([]*html.Node, 0)
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+func isElement(n *html.Node, tag string) bool {
+	return n.Type == html.ElementNode && n.Data == tag
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func hasClass(n *html.Node, class string) bool {
+	classes, _ := attr(n, "class")
+
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var builder strings.Builder
+
+	for _, textNode := range findElements(n, func(n *html.Node) bool { return n.Type == html.TextNode }) {
+		builder.WriteString(textNode.Data)
+	}
+
+	return builder.String()
+}
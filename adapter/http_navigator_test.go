@@ -0,0 +1,167 @@
+package adapter
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+func newTestHTTPNavigator(t *testing.T, baseURL *url.URL) page.Navigator {
+	t.Helper()
+
+	dispatcher, err := NewHTTPDispatcher(HTTPDispatcherConfig{
+		BaseURL: baseURL,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("new http dispatcher: %v", err)
+	}
+
+	navigator, err := dispatcher.NewNavigator("12345", "abcdef", page.NavigatorOptions{})
+	if err != nil {
+		t.Fatalf("new navigator: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := navigator.Close(); err != nil {
+			t.Errorf("close navigator: %v", err)
+		}
+	})
+
+	return navigator
+}
+
+func TestHTTPNavigator_Flow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		calendar fakeCalendar
+		want     bool
+	}{
+		{name: "No Free Time", calendar: fakeCalendarNoFreeTime, want: false},
+		{name: "Slots Available", calendar: fakeCalendarSlotsAvailable, want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := newFakeKdmid(t, tt.calendar)
+			navigator := newTestHTTPNavigator(t, fake.baseURL(t))
+
+			one, err := navigator.OpenPageToAuthorize()
+			if err != nil {
+				t.Fatalf("open page to authorize: %v", err)
+			}
+
+			if !one.Captcha.Presented || one.Captcha.Image.Empty() {
+				t.Fatal("expected captcha to be presented")
+			}
+
+			if len(one.HTML) == 0 || len(one.Network) == 0 {
+				t.Error("expected html and network artifacts")
+			}
+
+			if !one.Screenshot.Empty() {
+				t.Error("expected no screenshot")
+			}
+
+			if one.Traffic.Requests != 2 || one.Traffic.Bytes == 0 {
+				t.Errorf("expected page and captcha requests to be counted, got %+v", one.Traffic)
+			}
+
+			if fake.captchaRequests() != 1 {
+				t.Errorf("expected captcha to be fetched once, got %d", fake.captchaRequests())
+			}
+
+			if _, err := navigator.SubmitAuthorization(fakeCaptchaCode); err != nil {
+				t.Fatalf("submit authorization: %v", err)
+			}
+
+			three, err := navigator.OpenSlotBookingPage()
+			if err != nil {
+				t.Fatalf("open slot booking page: %v", err)
+			}
+
+			if three.SomethingInteresting != tt.want {
+				t.Errorf("expected something interesting %v, got %v", tt.want, three.SomethingInteresting)
+			}
+		})
+	}
+}
+
+func TestHTTPNavigator_WrongCaptcha(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeKdmid(t, fakeCalendarNoFreeTime)
+	navigator := newTestHTTPNavigator(t, fake.baseURL(t))
+
+	if _, err := navigator.OpenPageToAuthorize(); err != nil {
+		t.Fatalf("open page to authorize: %v", err)
+	}
+
+	_, err := navigator.SubmitAuthorization("000000")
+	if !errors.Is(err, page.ErrCaptchaNotSolved) {
+		t.Fatalf("expected ErrCaptchaNotSolved, got %v", err)
+	}
+}
+
+func TestHTTPNavigator_ErrorPage(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeKdmid(t, fakeCalendarNoFreeTime)
+	fake.broken = true
+
+	navigator := newTestHTTPNavigator(t, fake.baseURL(t))
+
+	stat, err := navigator.OpenPageToAuthorize()
+	if !errors.Is(err, page.ErrNavigationFailed) {
+		t.Fatalf("expected ErrNavigationFailed, got %v", err)
+	}
+
+	if len(stat.HTML) == 0 {
+		t.Error("expected error page html to be captured")
+	}
+}
+
+func TestFormValues(t *testing.T) {
+	t.Parallel()
+
+	doc, err := html.Parse(strings.NewReader(`<form>
+		<input type="hidden" name="token" value="abc">
+		<input type="submit" name="go" value="Go">
+		<input type="checkbox" name="agree" value="yes" checked>
+		<input type="checkbox" name="news" value="yes">
+		<select name="city"><option value="1">One</option><option value="2" selected>Two</option></select>
+		<select name="lang"><option> en </option><option>ru</option></select>
+		<select name="tags" multiple><option value="a">A</option></select>
+		<select name="off" disabled><option value="x">X</option></select>
+		<textarea name="note">hello
+world</textarea>
+	</form>`))
+	if err != nil {
+		t.Fatalf("parse html: %v", err)
+	}
+
+	expected := url.Values{
+		"token": {"abc"},
+		"agree": {"yes"},
+		"city":  {"2"},
+		"lang":  {"en"},
+		"note":  {"hello\nworld"},
+	}
+
+	if got := formValues(doc); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
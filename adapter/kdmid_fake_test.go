@@ -0,0 +1,323 @@
+package adapter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type fakeCalendar int
+
+const (
+	fakeCalendarNoFreeTime fakeCalendar = iota
+	fakeCalendarSlotsAvailable
+)
+
+const (
+	fakeCaptchaCode   = "123456"
+	fakeSessionCookie = "ASP.NET_SessionId"
+	fakeViewState     = "fake-view-state"
+	fakeValidation    = "fake-event-validation"
+)
+
+type fakeKdmid struct {
+	server   *httptest.Server
+	calendar fakeCalendar
+	broken   bool
+
+	m          sync.Mutex
+	sessions   map[string]bool
+	nextID     int
+	captchaHit int
+}
+
+func newFakeKdmid(t *testing.T, calendar fakeCalendar) *fakeKdmid {
+	t.Helper()
+
+	fake := &fakeKdmid{
+		calendar: calendar,
+		sessions: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /queue/OrderInfo.aspx", fake.orderInfo)
+	mux.HandleFunc("POST /queue/OrderInfo.aspx", fake.orderInfoPostBack)
+	mux.HandleFunc("GET /queue/CodeImage.aspx", fake.codeImage)
+	mux.HandleFunc("GET /queue/images/zapis.gif", fake.buttonImage)
+	mux.HandleFunc("GET /queue/SPCalendar.aspx", fake.spCalendar)
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeKdmid) baseURL(t *testing.T) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(f.server.URL)
+	if err != nil {
+		t.Fatalf("parse fake server url: %v", err)
+	}
+
+	return u
+}
+
+func (f *fakeKdmid) captchaRequests() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return f.captchaHit
+}
+
+func (f *fakeKdmid) session(w http.ResponseWriter, r *http.Request) string {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if cookie, err := r.Cookie(fakeSessionCookie); err == nil {
+		if _, ok := f.sessions[cookie.Value]; ok {
+			return cookie.Value
+		}
+	}
+
+	f.nextID++
+	sessionID := "session" + strconv.Itoa(f.nextID)
+	f.sessions[sessionID] = false
+
+	http.SetCookie(w, &http.Cookie{Name: fakeSessionCookie, Value: sessionID, Path: "/"})
+
+	return sessionID
+}
+
+func (f *fakeKdmid) authorize(sessionID string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.sessions[sessionID] = true
+}
+
+func (f *fakeKdmid) authorized(r *http.Request) bool {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	cookie, err := r.Cookie(fakeSessionCookie)
+	if err != nil {
+		return false
+	}
+
+	return f.sessions[cookie.Value]
+}
+
+func (f *fakeKdmid) orderInfo(w http.ResponseWriter, r *http.Request) {
+	if f.broken {
+		http.Error(w, "Server Error in '/queue' Application.", http.StatusInternalServerError)
+
+		return
+	}
+
+	f.session(w, r)
+
+	f.render(w, fakeOrderInfoTemplate, fakeOrderInfoData{
+		ID:         r.URL.Query().Get("id"),
+		CD:         r.URL.Query().Get("cd"),
+		ViewState:  fakeViewState,
+		Validation: fakeValidation,
+	})
+}
+
+func (f *fakeKdmid) orderInfoPostBack(w http.ResponseWriter, r *http.Request) {
+	sessionID := f.session(w, r)
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if r.PostForm.Get("__VIEWSTATE") != fakeViewState || r.PostForm.Get("__EVENTVALIDATION") != fakeValidation {
+		http.Error(w, "Invalid postback or callback argument.", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := fakeOrderInfoData{
+		ID:         r.URL.Query().Get("id"),
+		CD:         r.URL.Query().Get("cd"),
+		ViewState:  fakeViewState,
+		Validation: fakeValidation,
+	}
+
+	if r.PostForm.Has("ctl00$MainContent$ButtonB.x") {
+		http.Redirect(w, r, "SPCalendar.aspx", http.StatusFound)
+
+		return
+	}
+
+	if r.PostForm.Get("ctl00$MainContent$txtCode") != fakeCaptchaCode {
+		data.WrongCaptcha = true
+
+		f.render(w, fakeOrderInfoTemplate, data)
+
+		return
+	}
+
+	f.authorize(sessionID)
+
+	f.render(w, fakeOrderAuthorizedTemplate, data)
+}
+
+func (f *fakeKdmid) codeImage(w http.ResponseWriter, _ *http.Request) {
+	f.m.Lock()
+	f.captchaHit++
+	f.m.Unlock()
+
+	w.Header().Set("Content-Type", "image/png")
+
+	if _, err := w.Write(fakeCaptchaPNG()); err != nil {
+		return
+	}
+}
+
+// buttonImage serves the picture of the booking button, the real site loads it
+// like any other image.
+func (f *fakeKdmid) buttonImage(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "image/gif")
+
+	if _, err := w.Write(fakeButtonGIF); err != nil {
+		return
+	}
+}
+
+var fakeButtonGIF = []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x00;")
+
+func (f *fakeKdmid) spCalendar(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Redirect(w, r, "ErrorPage.aspx", http.StatusFound)
+
+		return
+	}
+
+	f.render(w, fakeCalendarTemplate, fakeCalendarData{
+		SlotsAvailable: f.calendar == fakeCalendarSlotsAvailable,
+		ViewState:      fakeViewState,
+		Validation:     fakeValidation,
+	})
+}
+
+func (f *fakeKdmid) render(w http.ResponseWriter, tpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := tpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func fakeCaptchaPNG() []byte {
+	const (
+		width  = 600
+		height = 100
+	)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	for digit := 0; digit < len(fakeCaptchaCode); digit++ {
+		x0 := width/3 + 10 + digit*30
+
+		for x := x0; x < x0+20; x++ {
+			for y := 30; y < 70; y++ {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	if err := png.Encode(&buffer, img); err != nil {
+		panic(fmt.Sprintf("encode fake captcha: %v", err))
+	}
+
+	return buffer.Bytes()
+}
+
+type fakeOrderInfoData struct {
+	ID, CD                string
+	ViewState, Validation string
+	WrongCaptcha          bool
+}
+
+type fakeCalendarData struct {
+	SlotsAvailable        bool
+	ViewState, Validation string
+}
+
+var fakeOrderInfoTemplate = template.Must(template.New("order_info").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Электронная очередь</title></head>
+<body>
+<form name="aspnetForm" method="post" action="./OrderInfo.aspx?id={{.ID}}&amp;cd={{.CD}}" id="aspnetForm">
+<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="{{.ViewState}}" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="{{.Validation}}" />
+<div id="center-panel">
+<h1>Запись на прием</h1>
+<p>1</p><p>2</p><p>3</p><p>4</p><p>5</p><p>6</p>
+<div class="inp"><input name="ctl00$MainContent$txtID" type="text" value="{{.ID}}" /></div>
+<div class="inp"><input name="ctl00$MainContent$txtUniqueID" type="text" value="{{.CD}}" /></div>
+<div class="inp"><input name="ctl00$MainContent$txtCode" type="text" /></div>
+<div>{{if .WrongCaptcha}}<span id="ctl00_MainContent_Label_Message">Символы с картинки введены неправильно</span>{{end}}</div>
+<div><img src="CodeImage.aspx?id={{.ID}}" alt="" /></div>
+<input type="submit" name="ctl00$MainContent$ButtonA" value="Далее" />
+</div>
+</form>
+</body>
+</html>`))
+
+var fakeOrderAuthorizedTemplate = template.Must(template.New("order_authorized").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Электронная очередь</title></head>
+<body>
+<form name="aspnetForm" method="post" action="./OrderInfo.aspx?id={{.ID}}&amp;cd={{.CD}}" id="aspnetForm">
+<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="{{.ViewState}}" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="{{.Validation}}" />
+<div id="center-panel">
+<h1>Заявка {{.ID}}</h1>
+<p>Для записи на прием нажмите кнопку ниже</p>
+<input type="image" name="ctl00$MainContent$ButtonB" src="images/zapis.gif" alt="Записаться" />
+</div>
+</form>
+</body>
+</html>`))
+
+var fakeCalendarTemplate = template.Must(template.New("calendar").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Электронная очередь</title></head>
+<body>
+<form name="aspnetForm" method="post" action="./SPCalendar.aspx" id="aspnetForm">
+<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="{{.ViewState}}" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="{{.Validation}}" />
+<div id="center-panel">
+{{if .SlotsAvailable}}
+<table><tr><td><input type="radio" name="ctl00$MainContent$RadioButtonList1" value="1" />01.02.2025 10:00</td></tr></table>
+<input type="submit" name="ctl00$MainContent$Button1" value="Записаться" />
+{{else}}
+<p>Извините, но в настоящий момент на интересующее Вас консульское действие в системе предварительной записи нет свободного времени.</p>
+<input type="submit" name="ctl00$MainContent$Button1" value="Записаться" disabled="disabled" />
+{{end}}
+</div>
+</form>
+</body>
+</html>`))
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memoryFS holds the files of a crawl day loaded from an archive or object
+// storage, keyed by slash separated paths. Directories are implied by the
+// files under them.
+type memoryFS map[string][]byte
+
+func (a memoryFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fileBytes, ok := a[name]; ok {
+		return &memoryFile{
+			info:   memoryFileInfo{name: path.Base(name), size: int64(len(fileBytes))},
+			Reader: bytes.NewReader(fileBytes),
+		}, nil
+	}
+
+	if a.isDir(name) {
+		return &memoryFile{info: memoryFileInfo{name: path.Base(name), dir: true}, Reader: bytes.NewReader(nil)}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (a memoryFS) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+
+	for filePath := range a {
+		if strings.HasPrefix(filePath, name+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a memoryFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !a.isDir(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	entries := make(map[string]memoryFileInfo)
+
+	for filePath, fileBytes := range a {
+		rest, ok := strings.CutPrefix(filePath, prefix)
+		if !ok {
+			continue
+		}
+
+		if child, _, nested := strings.Cut(rest, "/"); nested {
+			entries[child] = memoryFileInfo{name: child, dir: true}
+		} else {
+			entries[child] = memoryFileInfo{name: child, size: int64(len(fileBytes))}
+		}
+	}
+
+	dirEntries := make([]fs.DirEntry, 0, len(entries))
+	for _, info := range entries {
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(info))
+	}
+
+	sort.Slice(dirEntries, func(i, j int) bool { return dirEntries[i].Name() < dirEntries[j].Name() })
+
+	return dirEntries, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+func (a *memoryFile) Stat() (fs.FileInfo, error) { return a.info, nil }
+func (a *memoryFile) Close() error               { return nil }
+
+type memoryFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (a memoryFileInfo) Name() string       { return a.name }
+func (a memoryFileInfo) Size() int64        { return a.size }
+func (a memoryFileInfo) ModTime() time.Time { return time.Time{} }
+func (a memoryFileInfo) IsDir() bool        { return a.dir }
+func (a memoryFileInfo) Sys() any           { return nil }
+
+func (a memoryFileInfo) Mode() fs.FileMode {
+	if a.dir {
+		return fs.ModeDir | 0o555
+	}
+
+	return 0o444
+}
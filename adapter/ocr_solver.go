@@ -0,0 +1,287 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	goimage "image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+const (
+	ocrGridCols     = 6
+	ocrGridRows     = 9
+	ocrAspectWeight = 2
+)
+
+//go:embed ocr_templates.json
+var defaultOCRTemplates []byte
+
+type OCRTemplates struct {
+	Cols   int                  `json:"cols"`
+	Rows   int                  `json:"rows"`
+	Digits map[string][]float64 `json:"digits"`
+}
+
+type OCRSample struct {
+	Image image.Image
+	Code  string
+}
+
+// OCRAccuracy is the share of codes and of single digits recognized right.
+type OCRAccuracy struct {
+	Samples int
+	Codes   float64
+	Digits  float64
+}
+
+type ocrSolver struct {
+	templates OCRTemplates
+}
+
+func DefaultOCRTemplates() (OCRTemplates, error) {
+	var templates OCRTemplates
+
+	if err := json.Unmarshal(defaultOCRTemplates, &templates); err != nil {
+		return OCRTemplates{}, fmt.Errorf("unmarshal ocr templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+func NewOCRSolver(templates OCRTemplates) (captcha.Solver, error) {
+	if len(templates.Digits) != 10 {
+		return nil, fmt.Errorf("expected templates for 10 digits, got %d", len(templates.Digits))
+	}
+
+	for digit, template := range templates.Digits {
+		if len(template) != templates.Cols*templates.Rows+1 {
+			return nil, fmt.Errorf("template of digit `%s` has %d features", digit, len(template))
+		}
+	}
+
+	return &ocrSolver{
+		templates: templates,
+	}, nil
+}
+
+func MustNewOCRSolver() captcha.Solver {
+	templates, err := DefaultOCRTemplates()
+	if err != nil {
+		panic(err)
+	}
+
+	solver, err := NewOCRSolver(templates)
+	if err != nil {
+		panic(err)
+	}
+
+	return solver
+}
+
+func (s *ocrSolver) Solve(_ context.Context, img image.Image) (captcha.Solution, error) {
+	code, confidence, err := s.recognize(img)
+	if err != nil {
+		return captcha.Solution{}, fmt.Errorf("could not recognize captcha: %w", err)
+	}
+
+	return captcha.Solution{Code: code, Confidence: confidence}, nil
+}
+
+// recognize classifies every digit by the nearest template, the confidence of
+// a digit is how much closer the nearest template is than the second one, and
+// the confidence of the code is the one of its least certain digit.
+func (s *ocrSolver) recognize(img image.Image) (string, float64, error) {
+	digits, err := segmentCaptcha(img)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var code strings.Builder
+
+	confidence := 1.0
+
+	for _, digit := range digits {
+		features := ocrFeatures(digit, s.templates.Cols, s.templates.Rows)
+
+		best, bestDistance, secondDistance := "", math.Inf(1), math.Inf(1)
+
+		for label, template := range s.templates.Digits {
+			distance := euclidean(features, template)
+
+			switch {
+			case distance < bestDistance:
+				best, bestDistance, secondDistance = label, distance, bestDistance
+			case distance < secondDistance:
+				secondDistance = distance
+			}
+		}
+
+		code.WriteString(best)
+
+		if secondDistance > 0 {
+			confidence = min(confidence, 1-bestDistance/secondDistance)
+		}
+	}
+
+	return code.String(), confidence, nil
+}
+
+func TrainOCRTemplates(samples []OCRSample) (OCRTemplates, error) {
+	templates := OCRTemplates{
+		Cols:   ocrGridCols,
+		Rows:   ocrGridRows,
+		Digits: make(map[string][]float64),
+	}
+
+	counts := make(map[string]int)
+
+	for _, sample := range samples {
+		if len(sample.Code) != captcha.CodeLength {
+			return OCRTemplates{}, fmt.Errorf("sample code `%s` is not %d digits", sample.Code, captcha.CodeLength)
+		}
+
+		digits, err := segmentCaptcha(sample.Image)
+		if err != nil {
+			continue
+		}
+
+		for i, digit := range digits {
+			label := sample.Code[i : i+1]
+
+			features := ocrFeatures(digit, templates.Cols, templates.Rows)
+
+			if templates.Digits[label] == nil {
+				templates.Digits[label] = make([]float64, len(features))
+			}
+
+			for j, feature := range features {
+				templates.Digits[label][j] += feature
+			}
+
+			counts[label]++
+		}
+	}
+
+	if len(templates.Digits) != 10 {
+		return OCRTemplates{}, fmt.Errorf("samples cover %d digits out of 10", len(templates.Digits))
+	}
+
+	for label, template := range templates.Digits {
+		for j := range template {
+			template[j] /= float64(counts[label])
+		}
+	}
+
+	return templates, nil
+}
+
+// EvaluateOCRTemplates measures the templates on samples they were not
+// trained on, a captcha that can not be segmented counts as wrong.
+func EvaluateOCRTemplates(templates OCRTemplates, samples []OCRSample) (OCRAccuracy, error) {
+	solver, err := NewOCRSolver(templates)
+	if err != nil {
+		return OCRAccuracy{}, err
+	}
+
+	if len(samples) == 0 {
+		return OCRAccuracy{}, fmt.Errorf("no samples to evaluate on")
+	}
+
+	var codes, digits int
+
+	for _, sample := range samples {
+		solution, err := solver.Solve(context.Background(), sample.Image)
+		if err != nil {
+			continue
+		}
+
+		if solution.Code == sample.Code {
+			codes++
+		}
+
+		for i := range min(len(solution.Code), len(sample.Code)) {
+			if solution.Code[i] == sample.Code[i] {
+				digits++
+			}
+		}
+	}
+
+	return OCRAccuracy{
+		Samples: len(samples),
+		Codes:   float64(codes) / float64(len(samples)),
+		Digits:  float64(digits) / float64(len(samples)*captcha.CodeLength),
+	}, nil
+}
+
+// LoadOCRSamples reads a directory of captchas named after their code, e.g.
+// 123456_0001.png, the way captcha-export writes them.
+func LoadOCRSamples(dir string) ([]OCRSample, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read corpus dir: %w", err)
+	}
+
+	samples := make([]OCRSample, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		code, _, _ := strings.Cut(strings.TrimSuffix(name, filepath.Ext(name)), "_")
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+
+		samples = append(samples, OCRSample{Image: image.Detect(data), Code: code})
+	}
+
+	return samples, nil
+}
+
+func segmentCaptcha(img image.Image) ([]image.Mask, error) {
+	decoded, _, err := goimage.Decode(bytes.NewReader(img.Bytes))
+	if err != nil {
+		return nil, fmt.Errorf("decode captcha: %w", err)
+	}
+
+	digits, err := image.SegmentDigits(image.InkMask(decoded), captcha.CodeLength)
+	if err != nil {
+		return nil, fmt.Errorf("segment digits: %w", err)
+	}
+
+	return digits, nil
+}
+
+func ocrFeatures(digit image.Mask, cols, rows int) []float64 {
+	features := digit.Density(cols, rows)
+
+	aspect := 0.0
+	if digit.Height != 0 {
+		aspect = float64(digit.Width) / float64(digit.Height)
+	}
+
+	return append(features, aspect*ocrAspectWeight)
+}
+
+func euclidean(a, b []float64) float64 {
+	var sum float64
+
+	for i := range a {
+		sum += (a[i] - b[i]) * (a[i] - b[i])
+	}
+
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,110 @@
+package adapter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+const minOCRAccuracy = 0.9
+
+var (
+	ocrTrainCorpus = filepath.Join("testdata", "captcha", "train")
+	ocrEvalCorpus  = filepath.Join("testdata", "captcha", "eval")
+)
+
+func loadOCRCorpus(t *testing.T, dir string) []OCRSample {
+	t.Helper()
+
+	samples, err := LoadOCRSamples(dir)
+	if err != nil {
+		t.Fatalf("load corpus: %v", err)
+	}
+
+	return samples
+}
+
+func checkOCRAccuracy(t *testing.T, templates OCRTemplates, samples []OCRSample, minAccuracy float64) {
+	t.Helper()
+
+	accuracy, err := EvaluateOCRTemplates(templates, samples)
+	if err != nil {
+		t.Fatalf("evaluate templates: %v", err)
+	}
+
+	t.Logf("accuracy on %d held-out captchas: codes %.1f%%, digits %.1f%%",
+		accuracy.Samples, accuracy.Codes*100, accuracy.Digits*100)
+
+	if accuracy.Codes < minAccuracy {
+		t.Errorf("code accuracy %.2f is below %.2f", accuracy.Codes, minAccuracy)
+	}
+}
+
+func TestOCRSolver_HeldOutAccuracy(t *testing.T) {
+	t.Parallel()
+
+	templates, err := TrainOCRTemplates(loadOCRCorpus(t, ocrTrainCorpus))
+	if err != nil {
+		t.Fatalf("train templates: %v", err)
+	}
+
+	checkOCRAccuracy(t, templates, loadOCRCorpus(t, ocrEvalCorpus), minOCRAccuracy)
+}
+
+// The default templates are trained on the train corpus only.
+func TestOCRSolver_DefaultTemplatesAccuracy(t *testing.T) {
+	t.Parallel()
+
+	templates, err := DefaultOCRTemplates()
+	if err != nil {
+		t.Fatalf("default templates: %v", err)
+	}
+
+	checkOCRAccuracy(t, templates, loadOCRCorpus(t, ocrEvalCorpus), minOCRAccuracy)
+}
+
+// OCR_EVAL_CORPUS points at stored captchas, e.g. the accepted directory of
+// captcha-export, to report the accuracy on what kdmid really serves.
+func TestOCRSolver_StoredCaptchasAccuracy(t *testing.T) {
+	t.Parallel()
+
+	dir := os.Getenv("OCR_EVAL_CORPUS")
+	if dir == "" {
+		t.Skip("OCR_EVAL_CORPUS is not set")
+	}
+
+	templates, err := DefaultOCRTemplates()
+	if err != nil {
+		t.Fatalf("default templates: %v", err)
+	}
+
+	checkOCRAccuracy(t, templates, loadOCRCorpus(t, dir), 0)
+}
+
+func TestOCRSolver_Confidence(t *testing.T) {
+	t.Parallel()
+
+	solver := MustNewOCRSolver()
+
+	solution, err := solver.Solve(context.Background(), loadOCRCorpus(t, ocrEvalCorpus)[0].Image)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Confidence <= 0 || solution.Confidence > 1 {
+		t.Errorf("expected confidence in (0, 1], got %.2f", solution.Confidence)
+	}
+}
+
+func TestOCRSolver_NoDigits(t *testing.T) {
+	t.Parallel()
+
+	solver := MustNewOCRSolver()
+
+	if _, err := solver.Solve(context.Background(), image.Image{}); err == nil {
+		t.Fatal("expected error for empty image")
+	}
+}
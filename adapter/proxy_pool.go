@@ -0,0 +1,296 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
+)
+
+type ProxyRotation string
+
+const (
+	ProxyRotationPerCrawl     ProxyRotation = "crawl"
+	ProxyRotationPerRecipient ProxyRotation = "recipient"
+)
+
+type ProxyPoolConfig struct {
+	URLs           []*url.URL
+	Rotation       ProxyRotation
+	Quarantine     time.Duration
+	HealthCheckURL string
+	// NoSOCKS5Auth refuses SOCKS5 proxies with credentials, browsers can't
+	// authenticate to them while the health check can.
+	NoSOCKS5Auth bool
+}
+
+type proxyPool struct {
+	entries        []*proxyEntry
+	next           int
+	rotation       ProxyRotation
+	quarantine     time.Duration
+	healthCheckURL string
+	m              sync.Mutex
+	logger         log.Logger
+	now            func() time.Time
+}
+
+type proxyEntry struct {
+	proxy               *proxy.Proxy
+	quarantinedTil      time.Time
+	consecutiveFailures int
+}
+
+var (
+	proxyResultsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "proxy_results_total",
+		Help:      "Outcomes of requests made through proxies.",
+	}, []string{"proxy", "source", "result"})
+	proxyQuarantinedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kdmid",
+		Name:      "proxy_quarantined",
+		Help:      "Whether the proxy is currently quarantined.",
+	}, []string{"proxy"})
+)
+
+const (
+	proxySourceCrawl       = "crawl"
+	proxySourceHealthCheck = "health_check"
+	proxyResultSuccess     = "success"
+	proxyResultFailure     = "failure"
+)
+
+var supportedProxySchemes = map[string]struct{}{
+	"http":   {},
+	"https":  {},
+	"socks5": {},
+}
+
+func NewProxyPool(cfg ProxyPoolConfig, logger log.Logger) (proxy.Pool, error) {
+	switch cfg.Rotation {
+	case ProxyRotationPerCrawl, ProxyRotationPerRecipient:
+	default:
+		return nil, fmt.Errorf("unsupported rotation `%s`", cfg.Rotation)
+	}
+
+	entries := make([]*proxyEntry, 0, len(cfg.URLs))
+
+	for _, proxyURL := range cfg.URLs {
+		if _, ok := supportedProxySchemes[proxyURL.Scheme]; !ok {
+			return nil, fmt.Errorf("unsupported scheme `%s`", proxyURL.Scheme)
+		}
+
+		if proxyURL.Host == "" {
+			return nil, fmt.Errorf("no host in proxy url `%s`", proxyURL.Redacted())
+		}
+
+		if cfg.NoSOCKS5Auth && proxyURL.Scheme == "socks5" && proxyURL.User != nil {
+			return nil, fmt.Errorf("socks5 proxy `%s` with credentials is not supported", proxyURL.Redacted())
+		}
+
+		entry := &proxyEntry{proxy: &proxy.Proxy{URL: proxyURL}}
+		entries = append(entries, entry)
+
+		proxyQuarantinedGauge.WithLabelValues(entry.proxy.Name()).Set(0)
+	}
+
+	return &proxyPool{
+		entries:        entries,
+		rotation:       cfg.Rotation,
+		quarantine:     cfg.Quarantine,
+		healthCheckURL: cfg.HealthCheckURL,
+		logger:         logger,
+		now:            time.Now,
+	}, nil
+}
+
+func MustNewProxyPool(cfg ProxyPoolConfig, logger log.Logger) proxy.Pool {
+	pool, err := NewProxyPool(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return pool
+}
+
+func (p *proxyPool) Pick(key string) (*proxy.Proxy, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if len(p.entries) == 0 {
+		return nil, nil
+	}
+
+	start := p.next
+
+	if p.rotation == ProxyRotationPerRecipient {
+		start = p.hash(key)
+	}
+
+	now := p.now()
+
+	for i := 0; i < len(p.entries); i++ {
+		idx := (start + i) % len(p.entries)
+		entry := p.entries[idx]
+
+		if now.Before(entry.quarantinedTil) {
+			continue
+		}
+
+		if p.rotation == ProxyRotationPerCrawl {
+			p.next = (idx + 1) % len(p.entries)
+		}
+
+		return entry.proxy, nil
+	}
+
+	return nil, proxy.ErrNoHealthyProxy
+}
+
+func (p *proxyPool) hash(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(len(p.entries)))
+}
+
+func (p *proxyPool) Report(reported *proxy.Proxy, err error) {
+	if reported == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	entry := p.find(reported)
+	if entry == nil {
+		return
+	}
+
+	if err == nil {
+		p.markSuccess(entry, proxySourceCrawl)
+
+		return
+	}
+
+	p.markFailure(entry, proxySourceCrawl, err)
+}
+
+func (p *proxyPool) CheckHealth(ctx context.Context) {
+	for _, entry := range p.snapshot() {
+		err := p.probe(ctx, entry.proxy)
+
+		p.m.Lock()
+
+		if err != nil {
+			p.markFailure(entry, proxySourceHealthCheck, err)
+		} else {
+			p.markSuccess(entry, proxySourceHealthCheck)
+		}
+
+		p.m.Unlock()
+	}
+}
+
+func (p *proxyPool) snapshot() []*proxyEntry {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	entries := make([]*proxyEntry, len(p.entries))
+	copy(entries, p.entries)
+
+	return entries
+}
+
+func (p *proxyPool) probe(ctx context.Context, probed *proxy.Proxy) error {
+	const probeTimeout = 30 * time.Second
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyURL(probed.URL),
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.healthCheckURL, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			p.logger.Error("failed close", "error", err.Error())
+		}
+	}()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *proxyPool) find(target *proxy.Proxy) *proxyEntry {
+	for _, entry := range p.entries {
+		if entry.proxy == target {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+func (p *proxyPool) markSuccess(entry *proxyEntry, source string) {
+	proxyResultsCounter.WithLabelValues(entry.proxy.Name(), source, proxyResultSuccess).Inc()
+
+	entry.consecutiveFailures = 0
+
+	if entry.quarantinedTil.IsZero() {
+		return
+	}
+
+	entry.quarantinedTil = time.Time{}
+	proxyQuarantinedGauge.WithLabelValues(entry.proxy.Name()).Set(0)
+
+	p.logger.Info("proxy released from quarantine", "proxy", entry.proxy.Name())
+}
+
+// maxConsecutiveFailures in a row, whether crawls or health checks failed,
+// quarantine the proxy.
+const maxConsecutiveFailures = 3
+
+func (p *proxyPool) markFailure(entry *proxyEntry, source string, err error) {
+	proxyResultsCounter.WithLabelValues(entry.proxy.Name(), source, proxyResultFailure).Inc()
+
+	entry.consecutiveFailures++
+
+	if entry.consecutiveFailures >= maxConsecutiveFailures {
+		p.quarantineEntry(entry, err)
+	}
+}
+
+func (p *proxyPool) quarantineEntry(entry *proxyEntry, err error) {
+	entry.quarantinedTil = p.now().Add(p.quarantine)
+	entry.consecutiveFailures = 0
+	proxyQuarantinedGauge.WithLabelValues(entry.proxy.Name()).Set(1)
+
+	p.logger.Info("proxy quarantined", "proxy", entry.proxy.Name(), "til", entry.quarantinedTil, "err", err)
+}
@@ -0,0 +1,202 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
+)
+
+func newTestProxyPool(t *testing.T, rotation ProxyRotation, rawURLs ...string) *proxyPool {
+	t.Helper()
+
+	urls := make([]*url.URL, 0, len(rawURLs))
+
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("parse url: %v", err)
+		}
+
+		urls = append(urls, u)
+	}
+
+	pool, err := NewProxyPool(ProxyPoolConfig{
+		URLs:       urls,
+		Rotation:   rotation,
+		Quarantine: time.Minute,
+	}, log.NewLogger())
+	if err != nil {
+		t.Fatalf("new proxy pool: %v", err)
+	}
+
+	return pool.(*proxyPool)
+}
+
+func TestProxyPool_PickWithoutProxies(t *testing.T) {
+	t.Parallel()
+
+	pool := newTestProxyPool(t, ProxyRotationPerCrawl)
+
+	picked, err := pool.Pick("id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if picked != nil {
+		t.Errorf("expected direct connection, got %s", picked.Name())
+	}
+}
+
+func TestProxyPool_PickPerCrawl(t *testing.T) {
+	t.Parallel()
+
+	pool := newTestProxyPool(t, ProxyRotationPerCrawl, "http://a:1", "https://b:2", "socks5://c:3")
+
+	want := []string{"http://a:1", "https://b:2", "socks5://c:3", "http://a:1"}
+
+	for i, w := range want {
+		picked, err := pool.Pick("id")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if picked.Name() != w {
+			t.Errorf("pick %d: expected %s, got %s", i, w, picked.Name())
+		}
+	}
+}
+
+func TestProxyPool_PickPerRecipientIsSticky(t *testing.T) {
+	t.Parallel()
+
+	pool := newTestProxyPool(t, ProxyRotationPerRecipient, "http://a:1", "http://b:2", "http://c:3")
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("recipient-%d", i)
+
+		first, err := pool.Pick(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		second, err := pool.Pick(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if first != second {
+			t.Errorf("key %s: expected sticky proxy, got %s and %s", key, first.Name(), second.Name())
+		}
+	}
+}
+
+func TestProxyPool_Quarantine(t *testing.T) {
+	t.Parallel()
+
+	pool := newTestProxyPool(t, ProxyRotationPerRecipient, "http://a:1", "http://b:2")
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	failing, err := pool.Pick("id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		pool.Report(failing, fmt.Errorf("navigation failed"))
+	}
+
+	picked, err := pool.Pick("id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if picked == failing {
+		t.Fatalf("expected quarantined proxy %s to be skipped", failing.Name())
+	}
+
+	for i := 0; i < 3; i++ {
+		pool.Report(picked, fmt.Errorf("navigation failed"))
+	}
+
+	if _, err := pool.Pick("id"); !errors.Is(err, proxy.ErrNoHealthyProxy) {
+		t.Fatalf("expected ErrNoHealthyProxy, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	picked, err = pool.Pick("id")
+	if err != nil {
+		t.Fatalf("unexpected error after quarantine expired: %v", err)
+	}
+
+	if picked != failing {
+		t.Errorf("expected sticky proxy %s after quarantine expired, got %s", failing.Name(), picked.Name())
+	}
+}
+
+func TestNewProxyPool_UnsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("ftp://a:1")
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+
+	if _, err := NewProxyPool(ProxyPoolConfig{
+		URLs:     []*url.URL{u},
+		Rotation: ProxyRotationPerCrawl,
+	}, log.NewLogger()); err == nil {
+		t.Fatal("expected error for unsupported scheme")
+	}
+}
+
+func TestProxyPool_CheckHealthQuarantinesAfterConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	pool := newTestProxyPool(t, ProxyRotationPerCrawl, "http://127.0.0.1:1")
+	pool.healthCheckURL = "http://127.0.0.1:1/health"
+
+	for i := 1; i < maxConsecutiveFailures; i++ {
+		pool.CheckHealth(context.Background())
+
+		if _, err := pool.Pick("id"); err != nil {
+			t.Fatalf("expected the proxy kept after %d failed checks, got %v", i, err)
+		}
+	}
+
+	pool.CheckHealth(context.Background())
+
+	if _, err := pool.Pick("id"); !errors.Is(err, proxy.ErrNoHealthyProxy) {
+		t.Fatalf("expected the proxy quarantined after %d failed checks, got %v", maxConsecutiveFailures, err)
+	}
+}
+
+func TestNewProxyPool_SOCKS5Auth(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("socks5://user:password@a:1")
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+
+	cfg := ProxyPoolConfig{URLs: []*url.URL{u}, Rotation: ProxyRotationPerCrawl}
+
+	if _, err := NewProxyPool(cfg, log.NewLogger()); err != nil {
+		t.Fatalf("expected socks5 credentials accepted without the browser: %v", err)
+	}
+
+	cfg.NoSOCKS5Auth = true
+
+	if _, err := NewProxyPool(cfg, log.NewLogger()); err == nil {
+		t.Fatal("expected socks5 credentials refused for the browser")
+	}
+}
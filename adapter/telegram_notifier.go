@@ -10,6 +10,7 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 	"github.com/truewebber/kdmid-queue-checker/domain/notification"
 )
 
@@ -45,10 +46,15 @@ func (n *telegramNotifier) Notify(
 	if len(notification.Images) > 0 {
 		media := make([]models.InputMedia, 0, len(notification.Images))
 
-		for i, image := range notification.Images {
+		for i, img := range notification.Images {
+			photo, err := telegramPhoto(img)
+			if err != nil {
+				return fmt.Errorf("convert image to photo: %w", err)
+			}
+
 			media = append(media, &models.InputMediaPhoto{
-				Media:           fmt.Sprintf("attach://screenshot%d.png", i+1),
-				MediaAttachment: bytes.NewReader(image),
+				Media:           fmt.Sprintf("attach://screenshot%d.%s", i+1, photo.Extension()),
+				MediaAttachment: bytes.NewReader(photo.Bytes),
 			})
 		}
 
@@ -81,3 +87,13 @@ func (n *telegramNotifier) Notify(
 
 	return nil
 }
+
+// telegramPhoto converts images to a format telegram accepts as a photo.
+func telegramPhoto(img image.Image) (image.Image, error) {
+	switch img.MIMEType {
+	case image.MIMETypePNG, image.MIMETypeJPEG:
+		return img, nil
+	default:
+		return image.Encode(img, image.EncodeOptions{MIMEType: image.MIMETypePNG})
+	}
+}
@@ -0,0 +1,187 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type TelegramSolverConfig struct {
+	// ChatID is the chat of volunteers, zero sends the captcha to the
+	// recipient the check runs for.
+	ChatID  int64
+	Timeout time.Duration
+}
+
+var (
+	errNoTelegramChat   = errors.New("no telegram chat to ask")
+	errTelegramNoAnswer = errors.New("no answer from telegram")
+)
+
+type telegramSolver struct {
+	telegramBot *bot.Bot
+	chatID      int64
+	timeout     time.Duration
+
+	m       sync.Mutex
+	pending map[telegramQuestion]chan string
+}
+
+type telegramQuestion struct {
+	chatID    int64
+	messageID int
+}
+
+func NewTelegramSolver(telegramBotToken string, cfg TelegramSolverConfig) (captcha.HumanSolver, error) {
+	telegramBot, err := bot.New(telegramBotToken)
+	if err != nil {
+		return nil, fmt.Errorf("new bot: %w", err)
+	}
+
+	return newTelegramSolver(telegramBot, cfg), nil
+}
+
+func MustNewTelegramSolver(telegramBotToken string, cfg TelegramSolverConfig) captcha.HumanSolver {
+	solver, err := NewTelegramSolver(telegramBotToken, cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return solver
+}
+
+func newTelegramSolver(telegramBot *bot.Bot, cfg TelegramSolverConfig) *telegramSolver {
+	const defaultTimeout = 2 * time.Minute
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &telegramSolver{
+		telegramBot: telegramBot,
+		chatID:      cfg.ChatID,
+		timeout:     cfg.Timeout,
+		pending:     make(map[telegramQuestion]chan string),
+	}
+}
+
+func (t *telegramSolver) Solve(ctx context.Context, img image.Image) (captcha.Solution, error) {
+	chatID := t.chatID
+	if chatID == 0 {
+		recipientID, ok := captcha.RecipientFrom(ctx)
+		if !ok {
+			return captcha.Solution{}, errNoTelegramChat
+		}
+
+		chatID = recipientID
+	}
+
+	photo, err := telegramPhoto(img)
+	if err != nil {
+		return captcha.Solution{}, fmt.Errorf("convert image to photo: %w", err)
+	}
+
+	message, err := t.telegramBot.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID: chatID,
+		Photo: &models.InputFileUpload{
+			Filename: "captcha." + photo.Extension(),
+			Data:     bytes.NewReader(photo.Bytes),
+		},
+		Caption: fmt.Sprintf("Reply with the %d digits from the picture within %s.",
+			captcha.CodeLength, t.timeout),
+		ReplyMarkup: &models.ForceReply{
+			ForceReply:            true,
+			InputFieldPlaceholder: strings.Repeat("0", captcha.CodeLength),
+		},
+	})
+	if err != nil {
+		return captcha.Solution{}, fmt.Errorf("send captcha: %w", err)
+	}
+
+	question := telegramQuestion{chatID: chatID, messageID: message.ID}
+	answer := t.ask(question)
+
+	defer t.forget(question)
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case code := <-answer:
+		return captcha.Solution{Code: code, Confidence: 1}, nil
+	case <-timer.C:
+		return captcha.Solution{}, fmt.Errorf("%w in %s", errTelegramNoAnswer, t.timeout)
+	case <-ctx.Done():
+		return captcha.Solution{}, ctx.Err()
+	}
+}
+
+// Answer hands a typed code to the question it replies to, a message which is
+// not a reply answers the question when it is the only one open in the chat.
+func (t *telegramSolver) Answer(chatID int64, questionID int, code string) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	question := telegramQuestion{chatID: chatID, messageID: questionID}
+
+	answer, ok := t.pending[question]
+	if !ok && questionID == 0 {
+		answer, ok = t.onlyQuestionIn(chatID)
+	}
+
+	if !ok {
+		return false
+	}
+
+	select {
+	case answer <- strings.TrimSpace(code):
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *telegramSolver) onlyQuestionIn(chatID int64) (chan string, bool) {
+	var found chan string
+
+	for question, answer := range t.pending {
+		if question.chatID != chatID {
+			continue
+		}
+
+		if found != nil {
+			return nil, false
+		}
+
+		found = answer
+	}
+
+	return found, found != nil
+}
+
+func (t *telegramSolver) ask(question telegramQuestion) chan string {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	answer := make(chan string, 1)
+	t.pending[question] = answer
+
+	return answer
+}
+
+func (t *telegramSolver) forget(question telegramQuestion) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	delete(t.pending, question)
+}
@@ -0,0 +1,134 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type fakeTelegram struct {
+	server *httptest.Server
+	photos atomic.Int64
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+
+	fake := &fakeTelegram{}
+
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/sendPhoto") {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+
+			return
+		}
+
+		fake.photos.Add(1)
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":77,"date":0,"chat":{"id":1,"type":"private"}}}`))
+	}))
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeTelegram) solver(t *testing.T, cfg TelegramSolverConfig) *telegramSolver {
+	t.Helper()
+
+	telegramBot, err := bot.New("token", bot.WithServerURL(f.server.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+
+	return newTelegramSolver(telegramBot, cfg)
+}
+
+func answerWhenAsked(t *testing.T, solver *telegramSolver, chatID int64, questionID int, code string) {
+	t.Helper()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			if solver.Answer(chatID, questionID, code) {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+}
+
+func TestTelegramSolver_Reply(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTelegram(t)
+	solver := fake.solver(t, TelegramSolverConfig{ChatID: 1, Timeout: 5 * time.Second})
+
+	answerWhenAsked(t, solver, 1, 77, " 123456 ")
+
+	solution, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG()))
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Code != "123456" {
+		t.Errorf("expected code 123456, got %q", solution.Code)
+	}
+
+	if fake.photos.Load() != 1 {
+		t.Errorf("expected one photo sent, got %d", fake.photos.Load())
+	}
+}
+
+func TestTelegramSolver_AsksRecipient(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTelegram(t)
+	solver := fake.solver(t, TelegramSolverConfig{Timeout: 5 * time.Second})
+
+	answerWhenAsked(t, solver, 42, 0, "654321")
+
+	solution, err := solver.Solve(captcha.WithRecipient(context.Background(), 42), image.NewPNG(fakeCaptchaPNG()))
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Code != "654321" {
+		t.Errorf("expected code 654321, got %q", solution.Code)
+	}
+}
+
+func TestTelegramSolver_NoChat(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTelegram(t)
+	solver := fake.solver(t, TelegramSolverConfig{})
+
+	if _, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG())); !errors.Is(err, errNoTelegramChat) {
+		t.Fatalf("expected no chat error, got %v", err)
+	}
+}
+
+func TestTelegramSolver_Timeout(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTelegram(t)
+	solver := fake.solver(t, TelegramSolverConfig{ChatID: 1, Timeout: 50 * time.Millisecond})
+
+	if _, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG())); !errors.Is(err, errTelegramNoAnswer) {
+		t.Fatalf("expected no answer error, got %v", err)
+	}
+
+	if solver.Answer(1, 77, "123456") {
+		t.Error("expected late answer to be rejected")
+	}
+}
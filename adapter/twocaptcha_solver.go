@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 
@@ -15,9 +16,12 @@ type twoCaptchaSolver struct {
 	numberic int
 	maxLen   int
 	minLen   int
+	price    float64
 }
 
-func NewTwoCaptchaSolver(apiKey string) captcha.Solver {
+// NewTwoCaptchaSolver creates a solver which charges price for every captcha
+// 2captcha answers.
+func NewTwoCaptchaSolver(apiKey string, price float64) captcha.Solver {
 	const (
 		pollingInterval = 5
 		numberic        = 1
@@ -33,21 +37,52 @@ func NewTwoCaptchaSolver(apiKey string) captcha.Solver {
 		numberic: numberic,
 		maxLen:   maxLen,
 		minLen:   minLen,
+		price:    price,
 	}
 }
 
-func (t *twoCaptchaSolver) Solve(imageBytes image.PNG) (string, error) {
+func (t *twoCaptchaSolver) Solve(_ context.Context, img image.Image) (captcha.Solution, error) {
 	normal := api2captcha.Normal{
-		Base64:   base64.RawStdEncoding.EncodeToString(imageBytes),
+		Base64:   base64.RawStdEncoding.EncodeToString(img.Bytes),
 		Numberic: t.numberic,
 		MaxLen:   t.maxLen,
 		MinLen:   t.minLen,
 	}
 
-	code, _, err := t.client.Solve(normal.ToRequest())
+	code, captchaID, err := t.client.Solve(normal.ToRequest())
 	if err != nil {
-		return "", fmt.Errorf("could not solve captcha: %w", err)
+		return captcha.Solution{}, fmt.Errorf("could not solve captcha: %w", err)
 	}
 
-	return code, nil
+	return captcha.Solution{
+		Code:       code,
+		Confidence: 1,
+		Cost:       t.price,
+		Reporter: func(correct bool) error {
+			if err := t.client.Report(captchaID, correct); err != nil {
+				return fmt.Errorf("could not report captcha %s: %w", captchaID, err)
+			}
+
+			return nil
+		},
+	}, nil
+}
+
+type twoCaptchaBalanceChecker struct {
+	client *api2captcha.Client
+}
+
+func NewTwoCaptchaBalanceChecker(apiKey string) captcha.BalanceChecker {
+	return &twoCaptchaBalanceChecker{
+		client: api2captcha.NewClient(apiKey),
+	}
+}
+
+func (t *twoCaptchaBalanceChecker) Balance(_ context.Context) (float64, error) {
+	balance, err := t.client.GetBalance()
+	if err != nil {
+		return 0, fmt.Errorf("could not get balance: %w", err)
+	}
+
+	return balance, nil
 }
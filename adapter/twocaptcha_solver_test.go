@@ -0,0 +1,124 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+const testTwoCaptchaPrice = 0.001
+
+type fakeTwoCaptcha struct {
+	server *httptest.Server
+
+	m       sync.Mutex
+	reports []string
+}
+
+func newFakeTwoCaptcha(t *testing.T) *fakeTwoCaptcha {
+	t.Helper()
+
+	fake := &fakeTwoCaptcha{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/in.php", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("OK|4242"))
+	})
+	mux.HandleFunc("/res.php", func(w http.ResponseWriter, r *http.Request) {
+		switch action := r.URL.Query().Get("action"); action {
+		case "get":
+			_, _ = w.Write([]byte("OK|123456"))
+		case "getbalance":
+			_, _ = w.Write([]byte("3.7125"))
+		case "reportbad", "reportgood":
+			fake.m.Lock()
+			fake.reports = append(fake.reports, action+":"+r.URL.Query().Get("id"))
+			fake.m.Unlock()
+
+			_, _ = w.Write([]byte("OK_REPORT_RECORDED"))
+		default:
+			_, _ = w.Write([]byte("ERROR_WRONG_ACTION"))
+		}
+	})
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeTwoCaptcha) solver(t *testing.T) *twoCaptchaSolver {
+	t.Helper()
+
+	baseURL, err := url.Parse(f.server.URL)
+	if err != nil {
+		t.Fatalf("parse fake url: %v", err)
+	}
+
+	solver := NewTwoCaptchaSolver("key", testTwoCaptchaPrice).(*twoCaptchaSolver)
+	solver.client.BaseURL = baseURL
+	solver.client.PollingInterval = 0
+
+	return solver
+}
+
+func TestTwoCaptchaSolver_Report(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTwoCaptcha(t)
+	solver := fake.solver(t)
+
+	for _, correct := range []bool{false, true} {
+		solution, err := solver.Solve(context.Background(), image.NewPNG(fakeCaptchaPNG()))
+		if err != nil {
+			t.Fatalf("solve: %v", err)
+		}
+
+		if solution.Code != "123456" {
+			t.Fatalf("expected code 123456, got %s", solution.Code)
+		}
+
+		if solution.Cost != testTwoCaptchaPrice {
+			t.Fatalf("expected cost %v, got %v", testTwoCaptchaPrice, solution.Cost)
+		}
+
+		if err := solution.Report(correct); err != nil {
+			t.Fatalf("report: %v", err)
+		}
+	}
+
+	fake.m.Lock()
+	defer fake.m.Unlock()
+
+	if len(fake.reports) != 2 || fake.reports[0] != "reportbad:4242" || fake.reports[1] != "reportgood:4242" {
+		t.Fatalf("unexpected reports %v", fake.reports)
+	}
+}
+
+func TestTwoCaptchaBalanceChecker(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeTwoCaptcha(t)
+
+	baseURL, err := url.Parse(fake.server.URL)
+	if err != nil {
+		t.Fatalf("parse fake url: %v", err)
+	}
+
+	checker := NewTwoCaptchaBalanceChecker("key").(*twoCaptchaBalanceChecker)
+	checker.client.BaseURL = baseURL
+
+	balance, err := checker.Balance(context.Background())
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+
+	if balance != 3.7125 {
+		t.Errorf("expected balance 3.7125, got %v", balance)
+	}
+}
@@ -1,21 +1,64 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
 	"github.com/truewebber/kdmid-queue-checker/app/daemon"
 	"github.com/truewebber/kdmid-queue-checker/app/query"
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
 )
 
 type Application struct {
-	Daemon Daemon
-	Query  Query
+	Daemon    Daemon
+	Query     Query
+	Migrators []crawl.Migrator
+	Closers   []io.Closer
+}
+
+// Migrate brings the storages to their current layout before the daemons
+// start, it returns how many crawls were moved.
+func (a *Application) Migrate(ctx context.Context) (int, error) {
+	migrated := 0
+
+	for _, migrator := range a.Migrators {
+		moved, err := migrator.Migrate(ctx)
+		migrated += moved
+
+		if err != nil {
+			return migrated, fmt.Errorf("migrate %T: %w", migrator, err)
+		}
+	}
+
+	return migrated, nil
+}
+
+func (a *Application) Close() error {
+	var errs []error
+
+	for _, closer := range a.Closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %T: %w", closer, err))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 type Daemon struct {
-	CheckSlot *daemon.CheckSlot
-	Bot       *daemon.NotifierBot
+	CheckSlot      *daemon.CheckSlot
+	Bot            *daemon.NotifierBot
+	ProxyHealth    *daemon.ProxyHealth
+	CaptchaBalance *daemon.CaptchaBalance
+	Janitor        *daemon.Janitor
 }
 
 type Query struct {
-	ListUsers  *query.ListUsersHandler
-	ListCrawls *query.ListCrawlsHandler
+	ListUsers        *query.ListUsersHandler
+	ListCrawls       *query.ListCrawlsHandler
+	GetArtifact      *query.GetArtifactHandler
+	DispatcherHealth *query.DispatcherHealthHandler
+	CaptchaSpend     *query.CaptchaSpendHandler
 }
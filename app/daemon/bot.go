@@ -6,24 +6,40 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/truewebber/gopkg/log"
 
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
 	"github.com/truewebber/kdmid-queue-checker/domain/notification"
 )
 
 type NotifierBot struct {
-	storage     notification.Storage
-	telegramBot *bot.Bot
-	logger      log.Logger
+	storage        notification.Storage
+	crawlStorage   crawl.Storage
+	captchaAnswers captcha.HumanSolver
+	telegramBot    *bot.Bot
+	logger         log.Logger
 }
 
-func NewNotifierBot(botToken string, storage notification.Storage, logger log.Logger) (*NotifierBot, error) {
+// NewNotifierBot creates the bot, captchaAnswers receives codes people reply
+// with and may be nil when nobody is asked to solve captchas. crawlStorage
+// answers /history and may be nil too.
+func NewNotifierBot(
+	botToken string,
+	storage notification.Storage,
+	crawlStorage crawl.Storage,
+	captchaAnswers captcha.HumanSolver,
+	logger log.Logger,
+) (*NotifierBot, error) {
 	notifierBot := &NotifierBot{
-		storage: storage,
-		logger:  logger,
+		storage:        storage,
+		crawlStorage:   crawlStorage,
+		captchaAnswers: captchaAnswers,
+		logger:         logger,
 	}
 
 	if err := notifierBot.registerBot(botToken); err != nil {
@@ -33,8 +49,14 @@ func NewNotifierBot(botToken string, storage notification.Storage, logger log.Lo
 	return notifierBot, nil
 }
 
-func MustNewNotifierBot(botToken string, storage notification.Storage, logger log.Logger) *NotifierBot {
-	notifierBot, err := NewNotifierBot(botToken, storage, logger)
+func MustNewNotifierBot(
+	botToken string,
+	storage notification.Storage,
+	crawlStorage crawl.Storage,
+	captchaAnswers captcha.HumanSolver,
+	logger log.Logger,
+) *NotifierBot {
+	notifierBot, err := NewNotifierBot(botToken, storage, crawlStorage, captchaAnswers, logger)
 	if err != nil {
 		panic(err)
 	}
@@ -80,6 +102,13 @@ func (b *NotifierBot) registerBot(botToken string) error {
 		b.unregisterHandler,
 	)
 
+	telegramBot.RegisterHandler(
+		bot.HandlerTypeMessageText,
+		"/history",
+		bot.MatchTypePrefix,
+		b.historyHandler,
+	)
+
 	telegramBot.RegisterHandlerRegexp(
 		bot.HandlerTypeMessageText,
 		regexp.MustCompile(".*"),
@@ -96,12 +125,43 @@ func (b *NotifierBot) defaultHandler(ctx context.Context, telegramBot *bot.Bot,
 		return
 	}
 
+	if b.answerCaptcha(ctx, telegramBot, update) {
+		return
+	}
+
+	if _, err := telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text: "To use bot please use one of commands below:\n" +
+			" - /register {id} {cd}\n" +
+			" - /stop or /unregister\n" +
+			" - /history [error|interesting|ok]",
+	}); err != nil {
+		b.logger.Error("send message error", "message", update.Message, "error", err)
+	}
+}
+
+func (b *NotifierBot) answerCaptcha(ctx context.Context, telegramBot *bot.Bot, update *models.Update) bool {
+	if b.captchaAnswers == nil {
+		return false
+	}
+
+	questionID := 0
+	if update.Message.ReplyToMessage != nil {
+		questionID = update.Message.ReplyToMessage.ID
+	}
+
+	if !b.captchaAnswers.Answer(update.Message.Chat.ID, questionID, update.Message.Text) {
+		return false
+	}
+
 	if _, err := telegramBot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "To use bot please use one of commands below:\n - /register {id} {cd}\n - /stop or /unregister",
+		Text:   "Thanks, submitting the code.",
 	}); err != nil {
 		b.logger.Error("send message error", "message", update.Message, "error", err)
 	}
+
+	return true
 }
 
 func (b *NotifierBot) registerHandler(ctx context.Context, telegramBot *bot.Bot, update *models.Update) {
@@ -220,3 +280,77 @@ func (b *NotifierBot) unregisterHandler(ctx context.Context, telegramBot *bot.Bo
 		b.logger.Error("send message error", "message", update.Message, "error", err)
 	}
 }
+
+const (
+	historyLength = 10
+	historyDays   = 7
+)
+
+// historyHandler lists the last crawls of the user, optionally only the ones
+// with the given status.
+func (b *NotifierBot) historyHandler(ctx context.Context, telegramBot *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Chat.Type != "private" || b.crawlStorage == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	crawlQuery := crawl.Query{
+		UserIDs: []int64{chatID},
+		From:    time.Now().AddDate(0, 0, -historyDays),
+		Limit:   historyLength,
+	}
+
+	if statusVal := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/history")); statusVal != "" {
+		status, err := crawl.ParseStatus(statusVal)
+		if err != nil {
+			b.sendHistoryUsage(ctx, telegramBot, update)
+
+			return
+		}
+
+		crawlQuery.Statuses = []crawl.Status{status}
+	}
+
+	page, err := b.crawlStorage.Query(ctx, crawlQuery)
+	if err != nil {
+		b.logger.Error("query crawls error", "message", update.Message, "error", err)
+
+		return
+	}
+
+	if _, err := telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   historyText(page),
+	}); err != nil {
+		b.logger.Error("send message error", "message", update.Message, "error", err)
+	}
+}
+
+func (b *NotifierBot) sendHistoryUsage(ctx context.Context, telegramBot *bot.Bot, update *models.Update) {
+	if _, err := telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "Usage: /history [error|interesting|ok]",
+	}); err != nil {
+		b.logger.Error("send message error", "message", update.Message, "error", err)
+	}
+}
+
+func historyText(page crawl.Page) string {
+	if len(page.Summaries) == 0 {
+		return "No checks yet."
+	}
+
+	lines := make([]string, 0, len(page.Summaries)+1)
+	lines = append(lines, fmt.Sprintf("Last %d of %d checks:", len(page.Summaries), page.Total))
+
+	for _, summary := range page.Summaries {
+		line := summary.RanAt.Local().Format(time.DateTime) + " " + string(summary.Status())
+		if summary.Err != nil {
+			line += ": " + summary.Err.Error()
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+)
+
+var captchaBalance = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "kdmid",
+	Name:      "captcha_balance",
+	Help:      "Last known balance of the paid captcha solver.",
+})
+
+type CaptchaBalance struct {
+	checker  captcha.BalanceChecker
+	ledger   captcha.Ledger
+	interval time.Duration
+	logger   log.Logger
+}
+
+// NewCaptchaBalance polls the balance every interval, a zero interval turns
+// polling off.
+func NewCaptchaBalance(
+	checker captcha.BalanceChecker,
+	ledger captcha.Ledger,
+	interval time.Duration,
+	logger log.Logger,
+) *CaptchaBalance {
+	return &CaptchaBalance{
+		checker:  checker,
+		ledger:   ledger,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (b *CaptchaBalance) Handle(ctx context.Context) error {
+	if b.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	b.check(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			b.check(ctx)
+		}
+	}
+}
+
+func (b *CaptchaBalance) check(ctx context.Context) {
+	amount, err := b.checker.Balance(ctx)
+	if err != nil {
+		b.logger.Error("check captcha balance", "err", err)
+
+		return
+	}
+
+	captchaBalance.Set(amount)
+
+	if err := b.ledger.SaveBalance(ctx, captcha.Balance{Amount: amount, CheckedAt: time.Now()}); err != nil {
+		b.logger.Error("save captcha balance", "err", err)
+
+		return
+	}
+
+	b.logger.Info("captcha balance checked", "balance", amount)
+}
@@ -13,33 +13,51 @@ import (
 
 	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
 	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 	"github.com/truewebber/kdmid-queue-checker/domain/notification"
 	"github.com/truewebber/kdmid-queue-checker/domain/page"
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
 )
 
 type CheckSlot struct {
 	dispatcher       page.Dispatcher
+	proxyPool        proxy.Pool
 	solver           captcha.Solver
+	humanSolver      captcha.Solver
 	crawlStorage     crawl.Storage
 	recipientStorage notification.Storage
 	notifier         notification.Notifier
+	ledger           captcha.Ledger
+	budget           *captchaBudget
+	metrics          checkSlotMetrics
+	appVersion       string
 	logger           log.Logger
 }
 
 func NewCheckSlot(
+	consulate, appVersion string,
 	dispatcher page.Dispatcher,
-	solver captcha.Solver,
+	proxyPool proxy.Pool,
+	solver, humanSolver captcha.Solver,
 	crawlStorage crawl.Storage,
 	recipientStorage notification.Storage,
 	notifier notification.Notifier,
+	ledger captcha.Ledger,
+	dailyBudget float64,
 	logger log.Logger,
 ) *CheckSlot {
 	return &CheckSlot{
 		dispatcher:       dispatcher,
+		proxyPool:        proxyPool,
 		solver:           solver,
+		humanSolver:      humanSolver,
 		crawlStorage:     crawlStorage,
 		recipientStorage: recipientStorage,
 		notifier:         notifier,
+		ledger:           ledger,
+		budget:           &captchaBudget{daily: dailyBudget},
+		metrics:          checkSlotMetrics{consulate: consulate},
+		appVersion:       appVersion,
 		logger:           logger,
 	}
 }
@@ -153,13 +171,18 @@ func (c *CheckSlot) getCronRules(startCheckFrom, startCheckTil string, amountOfT
 }
 
 func (c *CheckSlot) runAllRecipients(ctx context.Context) {
+	solver, ok := c.pickSolver(ctx)
+	if !ok {
+		return
+	}
+
 	recipients, err := c.recipientStorage.List(ctx)
 	if err != nil {
 		c.logger.Error("list recipients failed", "err", err)
 	}
 
 	for _, recipient := range recipients {
-		if err := c.runSingleCheck(ctx, &recipient); err != nil {
+		if err := c.runSingleCheck(ctx, solver, &recipient); err != nil {
 			c.logger.Error("check slot failed", "recipient", recipient, "err", err)
 		}
 	}
@@ -167,16 +190,27 @@ func (c *CheckSlot) runAllRecipients(ctx context.Context) {
 
 func (c *CheckSlot) runSingleCheck(
 	ctx context.Context,
+	solver captcha.Solver,
 	recipient *notification.Recipient,
 ) error {
 	c.logger.Info("start run single check")
 
-	crawlResult, crawlErr := c.crawl(recipient.ID, recipient.CD)
+	crawlResult, crawlErr := c.crawl(
+		captcha.WithRecipient(ctx, recipient.TelegramID), solver, recipient.ID, recipient.CD,
+	)
 	if crawlErr != nil {
 		return fmt.Errorf("crawl failed: %w", crawlErr)
 	}
 
-	if saveErr := c.crawlStorage.Save(ctx, recipient.TelegramID, crawlResult); saveErr != nil {
+	if crawlResult.SomethingInteresting {
+		c.metrics.observeInteresting()
+	}
+
+	savedAt := time.Now()
+	saveErr := c.crawlStorage.Save(ctx, recipient.TelegramID, crawlResult)
+	c.metrics.observeStorageWrite(savedAt, saveErr)
+
+	if saveErr != nil {
 		crawlResult.Err = fmt.Errorf("%w, save crawl result: %w", crawlResult.Err, saveErr)
 
 		c.logger.Error("save crawl result", "recipient", recipient, "err", saveErr)
@@ -193,6 +227,33 @@ func (c *CheckSlot) runSingleCheck(
 	return nil
 }
 
+// recordSpend books the cost of every solve as soon as it is paid, solves
+// which failed or were retried cost money too.
+func (c *CheckSlot) recordSpend(ctx context.Context, at time.Time, solution captcha.Solution, solveErr error) {
+	solved := solveErr == nil
+	if !solved && solution.Cost == 0 {
+		return
+	}
+
+	recipientID, _ := captcha.RecipientFrom(ctx)
+
+	solver := solution.Solver
+	if !solved {
+		solver = unsolvedSolver
+	}
+
+	c.metrics.observeSolve(recipientID, solver, solved, solution.Cost)
+
+	spend := captcha.Spend{Cost: solution.Cost}
+	if solved {
+		spend.Solves = 1
+	}
+
+	if err := c.ledger.Record(ctx, recipientID, at, spend); err != nil {
+		c.logger.Error("record captcha spend", "recipient", recipientID, "err", err)
+	}
+}
+
 func (c *CheckSlot) notify(
 	ctx context.Context,
 	result *crawl.Result,
@@ -200,7 +261,10 @@ func (c *CheckSlot) notify(
 ) error {
 	n := c.buildNotification(result)
 
-	if err := c.notifier.Notify(ctx, n, recipient); err != nil {
+	err := c.notifier.Notify(ctx, n, recipient)
+	c.metrics.observeNotification(err)
+
+	if err != nil {
 		c.logger.Error("notify failed", "err", err)
 	}
 
@@ -216,29 +280,46 @@ func (c *CheckSlot) buildNotification(result *crawl.Result) *notification.Notifi
 	}
 }
 
-func (c *CheckSlot) buildNotificationImages(result *crawl.Result) []notification.PNG {
-	images := make([]notification.PNG, 0)
+func (c *CheckSlot) buildNotificationImages(result *crawl.Result) []image.Image {
+	images := make([]image.Image, 0)
 
 	for _, stat := range []page.Stat{result.One, result.Two, result.Three} {
-		if len(stat.Screenshot) != 0 {
-			images = append(images, notification.PNG(stat.Screenshot))
+		if !stat.Screenshot.Empty() {
+			images = append(images, stat.Screenshot)
 		}
 
 		if stat.Captcha.Presented {
-			images = append(images, notification.PNG(stat.Captcha.Image))
+			images = append(images, stat.Captcha.Image)
 		}
 	}
 
 	return images
 }
 
-func (c *CheckSlot) crawl(applicationID, applicationCD string) (*crawl.Result, error) {
-	i := 0
+func (c *CheckSlot) crawl(
+	ctx context.Context, solver captcha.Solver, applicationID, applicationCD string,
+) (*crawl.Result, error) {
+	var (
+		i         = 0
+		attempts  []crawl.CaptchaAttempt
+		crawlID   = crawl.NewID()
+		startedAt = time.Now()
+	)
 
 	for {
-		crawlResult, err := c.crawlWithRetry(applicationID, applicationCD, i)
+		crawlResult, err := c.crawlWithRetry(ctx, solver, applicationID, applicationCD, i, attempts)
+		if crawlResult != nil {
+			crawlResult.ID = crawlID
+			crawlResult.Retries = i
+			crawlResult.Durations.Total = time.Since(startedAt)
+			crawlResult.AppVersion = c.appVersion
+		}
+
 		if errors.Is(err, errRetryCrawl) {
 			i++
+			attempts = crawlResult.Attempts
+
+			c.metrics.observeRetry()
 
 			c.logger.Info("retry crawl", "idx", i, "err", err)
 
@@ -255,10 +336,26 @@ func (c *CheckSlot) crawl(applicationID, applicationCD string) (*crawl.Result, e
 
 var errRetryCrawl = fmt.Errorf("retry crawl")
 
+// unsolvedSolver labels the cost of captchas no solver answered.
+const unsolvedSolver = "unsolved"
+
 const maxRetryOnCaptchaNotSolved = 3
 
-func (c *CheckSlot) crawlWithRetry(applicationID, applicationCD string, retryIdx int) (*crawl.Result, error) {
-	navigator, err := c.dispatcher.NewNavigator(applicationID, applicationCD)
+func (c *CheckSlot) crawlWithRetry(
+	ctx context.Context,
+	solver captcha.Solver,
+	applicationID, applicationCD string,
+	retryIdx int,
+	previousAttempts []crawl.CaptchaAttempt,
+) (*crawl.Result, error) {
+	navigatorProxy, err := c.proxyPool.Pick(applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("pick proxy: %w", err)
+	}
+
+	navigator, err := c.dispatcher.NewNavigator(applicationID, applicationCD, page.NavigatorOptions{
+		Proxy: navigatorProxy,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("new navigator: %w", err)
 	}
@@ -270,26 +367,64 @@ func (c *CheckSlot) crawlWithRetry(applicationID, applicationCD string, retryIdx
 	}()
 
 	crawlResult := &crawl.Result{
-		RanAt: time.Now(),
+		RanAt:    time.Now().UTC(),
+		Proxy:    navigatorProxy.Name(),
+		Attempts: previousAttempts,
 	}
 
+	defer func() {
+		c.reportProxy(navigatorProxy, crawlResult.Err)
+	}()
+
+	startedAt := time.Now()
 	crawlResult.One, err = navigator.OpenPageToAuthorize()
+	crawlResult.Durations.Authorize = time.Since(startedAt)
+	c.metrics.observeStep(stepAuthorize, startedAt, err)
+
 	if err != nil {
 		crawlResult.Err = fmt.Errorf("open page to authorize: %w", err)
 
 		return crawlResult, nil
 	}
 
-	code, err := c.solver.Solve(crawlResult.One.Captcha.Image)
+	startedAt = time.Now()
+	solution, err := solver.Solve(ctx, crawlResult.One.Captcha.Image)
+	crawlResult.Durations.CaptchaSolve = time.Since(startedAt)
+	c.metrics.observeStep(stepCaptchaSolve, startedAt, err)
+	c.recordSpend(ctx, crawlResult.RanAt, solution, err)
+
 	if err != nil {
+		c.metrics.observeCaptcha(captchaResultSolveError)
+
 		crawlResult.Err = fmt.Errorf("solve captcha: %w", err)
 
 		return crawlResult, nil
 	}
 
-	crawlResult.Two, err = navigator.SubmitAuthorization(code)
-	if errors.Is(err, page.ErrCaptchaNotSolved) && retryIdx < maxRetryOnCaptchaNotSolved {
-		return nil, fmt.Errorf("%w: %w", err, errRetryCrawl)
+	crawlResult.CaptchaSolver = solution.Solver
+
+	startedAt = time.Now()
+	crawlResult.Two, err = navigator.SubmitAuthorization(solution.Code)
+	crawlResult.Durations.Submit = time.Since(startedAt)
+	c.metrics.observeStep(stepSubmit, startedAt, err)
+
+	verdict := captchaVerdict(err)
+
+	crawlResult.Attempts = append(crawlResult.Attempts, crawl.CaptchaAttempt{
+		Image:         crawlResult.One.Captcha.Image,
+		Code:          solution.Code,
+		Solver:        solution.Solver,
+		Preprocessing: solution.Preprocessing,
+		Confidence:    solution.Confidence,
+		Cost:          solution.Cost,
+		Verdict:       verdict,
+	})
+
+	c.metrics.observeVerdict(solution.Solver, solution.Preprocessing, verdict)
+	c.reportSolution(solution, verdict)
+
+	if verdict == crawl.VerdictRejected && retryIdx < maxRetryOnCaptchaNotSolved {
+		return crawlResult, fmt.Errorf("%w: %w", err, errRetryCrawl)
 	}
 
 	if err != nil {
@@ -298,7 +433,11 @@ func (c *CheckSlot) crawlWithRetry(applicationID, applicationCD string, retryIdx
 		return crawlResult, nil
 	}
 
+	startedAt = time.Now()
 	crawlResult.Three, err = navigator.OpenSlotBookingPage()
+	crawlResult.Durations.Calendar = time.Since(startedAt)
+	c.metrics.observeStep(stepCalendar, startedAt, err)
+
 	if err != nil {
 		crawlResult.Err = fmt.Errorf("open slot booking page: %w", err)
 
@@ -311,3 +450,43 @@ func (c *CheckSlot) crawlWithRetry(applicationID, applicationCD string, retryIdx
 
 	return crawlResult, nil
 }
+
+func captchaVerdict(submitErr error) crawl.Verdict {
+	switch {
+	case errors.Is(submitErr, page.ErrCaptchaNotSolved):
+		return crawl.VerdictRejected
+	case submitErr == nil:
+		return crawl.VerdictAccepted
+	default:
+		return crawl.VerdictUnknown
+	}
+}
+
+func (c *CheckSlot) reportSolution(solution captcha.Solution, verdict crawl.Verdict) {
+	var correct bool
+
+	switch verdict {
+	case crawl.VerdictRejected:
+		c.metrics.observeCaptcha(captchaResultRejected)
+	case crawl.VerdictAccepted:
+		c.metrics.observeCaptcha(captchaResultAccepted)
+
+		correct = true
+	default:
+		return
+	}
+
+	if err := solution.Report(correct); err != nil {
+		c.logger.Error("report captcha solution", "solver", solution.Solver, "correct", correct, "err", err)
+	}
+}
+
+func (c *CheckSlot) reportProxy(navigatorProxy *proxy.Proxy, crawlErr error) {
+	if errors.Is(crawlErr, page.ErrNavigationFailed) {
+		c.proxyPool.Report(navigatorProxy, crawlErr)
+
+		return
+	}
+
+	c.proxyPool.Report(navigatorProxy, nil)
+}
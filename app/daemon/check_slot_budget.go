@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+)
+
+type captchaBudget struct {
+	daily    float64
+	triggers atomic.Int64
+}
+
+const (
+	budgetHalfUsed   = 0.5
+	budgetMostlyUsed = 0.8
+)
+
+// stride tells how many triggers share a single check, the closer the spend
+// is to the budget the fewer checks are run. Zero means the budget is spent.
+func (b *captchaBudget) stride(spent float64) int64 {
+	if b.daily <= 0 {
+		return 1
+	}
+
+	switch used := spent / b.daily; {
+	case used >= 1:
+		return 0
+	case used >= budgetMostlyUsed:
+		return 4
+	case used >= budgetHalfUsed:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// allow counts the trigger and tells whether it should run a check.
+func (b *captchaBudget) allow(spent float64) bool {
+	trigger := b.triggers.Add(1) - 1
+
+	stride := b.stride(spent)
+	if stride == 0 {
+		return false
+	}
+
+	return trigger%stride == 0
+}
+
+// pickSolver runs paid solvers while the budget allows, the checks the budget
+// would skip ask a human instead when one is configured.
+func (c *CheckSlot) pickSolver(ctx context.Context) (captcha.Solver, bool) {
+	daily, err := c.ledger.Daily(ctx, time.Now())
+	if err != nil {
+		c.logger.Error("read captcha spend", "err", err)
+
+		return c.solver, true
+	}
+
+	var spend captcha.Spend
+	for _, recipientSpend := range daily {
+		spend = spend.Add(recipientSpend)
+	}
+
+	c.metrics.observeDailySpend(spend.Cost)
+
+	if c.budget.allow(spend.Cost) {
+		return c.solver, true
+	}
+
+	if c.humanSolver != nil {
+		c.metrics.observeBudgetHuman()
+
+		c.logger.Info("captcha asked to a human to stay within captcha budget",
+			"spent", spend.Cost, "budget", c.budget.daily)
+
+		return c.humanSolver, true
+	}
+
+	c.metrics.observeBudgetSkip()
+
+	c.logger.Info("check skipped to stay within captcha budget",
+		"spent", spend.Cost, "budget", c.budget.daily, "stride", c.budget.stride(spend.Cost))
+
+	return nil, false
+}
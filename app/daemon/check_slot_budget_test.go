@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+func TestCaptchaBudget_Allow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		daily   float64
+		spent   float64
+		allowed int
+	}{
+		{"Unlimited", 0, 100, 8},
+		{"Fresh", 1, 0.1, 8},
+		{"Half Used", 1, 0.5, 4},
+		{"Mostly Used", 1, 0.9, 2},
+		{"Spent", 1, 1, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			budget := &captchaBudget{daily: tt.daily}
+
+			allowed := 0
+
+			for i := 0; i < 8; i++ {
+				if budget.allow(tt.spent) {
+					allowed++
+				}
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("expected %d of 8 triggers allowed, got %d", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+type fakeLedger struct {
+	spend captcha.Spend
+}
+
+func (f *fakeLedger) Record(context.Context, int64, time.Time, captcha.Spend) error {
+	return nil
+}
+
+func (f *fakeLedger) Daily(context.Context, time.Time) (map[int64]captcha.Spend, error) {
+	return map[int64]captcha.Spend{42: f.spend}, nil
+}
+
+func (f *fakeLedger) Balance(context.Context) (captcha.Balance, error) {
+	return captcha.Balance{}, nil
+}
+
+func (f *fakeLedger) SaveBalance(context.Context, captcha.Balance) error {
+	return nil
+}
+
+type namedSolver string
+
+func (n namedSolver) Solve(context.Context, image.Image) (captcha.Solution, error) {
+	return captcha.Solution{Solver: string(n)}, nil
+}
+
+func TestCheckSlot_PickSolver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		spent    float64
+		human    captcha.Solver
+		expected string
+	}{
+		{"Within Budget", 0.1, namedSolver("human"), "paid"},
+		{"Spent With Human", 1, namedSolver("human"), "human"},
+		{"Spent Without Human", 1, nil, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewCheckSlot("barcelona", "dev", nil, nil, namedSolver("paid"), tt.human,
+				nil, nil, nil, &fakeLedger{spend: captcha.Spend{Cost: tt.spent}}, 1, log.NewLogger())
+
+			solver, ok := c.pickSolver(context.Background())
+			if ok != (tt.expected != "") {
+				t.Fatalf("expected a solver %t, got %t", tt.expected != "", ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			solution, _ := solver.Solve(context.Background(), image.Image{})
+			if solution.Solver != tt.expected {
+				t.Errorf("expected %s solver, got %s", tt.expected, solution.Solver)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+const (
+	stepAuthorize    = "authorize"
+	stepCaptchaSolve = "captcha_solve"
+	stepSubmit       = "submit"
+	stepCalendar     = "calendar"
+
+	resultOK                = "ok"
+	resultError             = "error"
+	resultCaptchaNotSolved  = "captcha_not_solved"
+	resultNavigationFailed  = "navigation_failed"
+	resultUnavailable       = "unavailable"
+	resultTimeout           = "timeout"
+	captchaResultAccepted   = "accepted"
+	captchaResultRejected   = "rejected"
+	captchaResultSolveError = "solve_error"
+	notificationResultSent  = "sent"
+	notificationResultError = "failed"
+)
+
+var (
+	crawlStepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_step_duration_seconds",
+		Help:      "Duration of a single crawl step.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"consulate", "step"})
+	crawlStepResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_step_results_total",
+		Help:      "Outcomes of crawl steps by error class.",
+	}, []string{"consulate", "step", "result"})
+	crawlRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_retries_total",
+		Help:      "Crawls restarted because the captcha was not accepted.",
+	}, []string{"consulate"})
+	captchaAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "captcha_attempts_total",
+		Help:      "Captcha solving attempts by outcome.",
+	}, []string{"consulate", "result"})
+	notificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "notifications_total",
+		Help:      "Notifications sent to recipients by outcome.",
+	}, []string{"consulate", "result"})
+	interestingResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_interesting_results_total",
+		Help:      "Crawls which found something interesting.",
+	}, []string{"consulate"})
+	captchaVerdicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "captcha_verdicts_total",
+		Help:      "Submitted captcha codes by solver, preprocessing pipeline and verdict.",
+	}, []string{"consulate", "solver", "preprocessing", "verdict"})
+	captchaSolves = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "captcha_solves_total",
+		Help:      "Captchas solved and submitted per recipient.",
+	}, []string{"consulate", "recipient", "solver"})
+	captchaCost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "captcha_cost_total",
+		Help:      "Money spent on solving captchas per recipient.",
+	}, []string{"consulate", "recipient", "solver"})
+	captchaDailySpend = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kdmid",
+		Name:      "captcha_daily_spend",
+		Help:      "Money spent on solving captchas today.",
+	}, []string{"consulate"})
+	budgetSkippedChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_budget_skipped_total",
+		Help:      "Scheduled checks skipped to stay within the captcha budget.",
+	}, []string{"consulate"})
+	budgetHumanChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_budget_human_total",
+		Help:      "Scheduled checks whose captcha was asked to a human to stay within the captcha budget.",
+	}, []string{"consulate"})
+	storageWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kdmid",
+		Name:      "crawl_storage_write_duration_seconds",
+		Help:      "Latency of saving crawl results.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"consulate", "result"})
+)
+
+type checkSlotMetrics struct {
+	consulate string
+}
+
+func (m checkSlotMetrics) observeStep(step string, startedAt time.Time, err error) {
+	crawlStepDuration.WithLabelValues(m.consulate, step).Observe(time.Since(startedAt).Seconds())
+	crawlStepResults.WithLabelValues(m.consulate, step, errorClass(err)).Inc()
+}
+
+func (m checkSlotMetrics) observeRetry() {
+	crawlRetries.WithLabelValues(m.consulate).Inc()
+}
+
+func (m checkSlotMetrics) observeCaptcha(result string) {
+	captchaAttempts.WithLabelValues(m.consulate, result).Inc()
+}
+
+func (m checkSlotMetrics) observeVerdict(solver, preprocessing string, verdict crawl.Verdict) {
+	captchaVerdicts.WithLabelValues(m.consulate, solver, preprocessing, string(verdict)).Inc()
+}
+
+func (m checkSlotMetrics) observeSolve(recipientID int64, solver string, solved bool, cost float64) {
+	recipient := strconv.FormatInt(recipientID, 10)
+
+	if solved {
+		captchaSolves.WithLabelValues(m.consulate, recipient, solver).Inc()
+	}
+
+	captchaCost.WithLabelValues(m.consulate, recipient, solver).Add(cost)
+}
+
+func (m checkSlotMetrics) observeDailySpend(cost float64) {
+	captchaDailySpend.WithLabelValues(m.consulate).Set(cost)
+}
+
+func (m checkSlotMetrics) observeBudgetSkip() {
+	budgetSkippedChecks.WithLabelValues(m.consulate).Inc()
+}
+
+func (m checkSlotMetrics) observeBudgetHuman() {
+	budgetHumanChecks.WithLabelValues(m.consulate).Inc()
+}
+
+func (m checkSlotMetrics) observeNotification(err error) {
+	result := notificationResultSent
+	if err != nil {
+		result = notificationResultError
+	}
+
+	notificationsSent.WithLabelValues(m.consulate, result).Inc()
+}
+
+func (m checkSlotMetrics) observeInteresting() {
+	interestingResults.WithLabelValues(m.consulate).Inc()
+}
+
+func (m checkSlotMetrics) observeStorageWrite(startedAt time.Time, err error) {
+	result := resultOK
+	if err != nil {
+		result = resultError
+	}
+
+	storageWriteDuration.WithLabelValues(m.consulate, result).Observe(time.Since(startedAt).Seconds())
+}
+
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return resultOK
+	case errors.Is(err, page.ErrCaptchaNotSolved):
+		return resultCaptchaNotSolved
+	case errors.Is(err, page.ErrNavigationFailed):
+		return resultNavigationFailed
+	case errors.Is(err, page.ErrUnavailable):
+		return resultUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return resultTimeout
+	default:
+		return resultError
+	}
+}
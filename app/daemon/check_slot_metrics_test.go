@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: resultOK},
+		{err: fmt.Errorf("submit: %w", page.ErrCaptchaNotSolved), want: resultCaptchaNotSolved},
+		{err: fmt.Errorf("%w: connection reset", page.ErrNavigationFailed), want: resultNavigationFailed},
+		{err: fmt.Errorf("new navigator: %w", page.ErrUnavailable), want: resultUnavailable},
+		{err: fmt.Errorf("solve: %w", context.DeadlineExceeded), want: resultTimeout},
+		{err: errors.New("boom"), want: resultError},
+	}
+
+	for _, tt := range tests {
+		if got := errorClass(tt.err); got != tt.want {
+			t.Errorf("errorClass(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
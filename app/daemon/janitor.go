@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+var (
+	artifactsPrunedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "artifacts_pruned_total",
+		Help:      "Crawls removed by the janitor.",
+	}, []string{"reason"})
+
+	artifactsPrunedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "artifacts_pruned_bytes_total",
+		Help:      "Bytes freed by the janitor.",
+	})
+
+	artifactsArchivedCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "kdmid",
+		Name:      "artifacts_archived_days_total",
+		Help:      "User days packed into archives by the janitor.",
+	})
+
+	artifactsBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kdmid",
+		Name:      "artifacts_bytes",
+		Help:      "Size of the stored crawls left after the last janitor run.",
+	})
+)
+
+// Janitor enforces the artifacts retention policy.
+type Janitor struct {
+	pruner    crawl.Pruner
+	archiver  crawl.Archiver
+	retention crawl.Retention
+	interval  time.Duration
+	logger    log.Logger
+}
+
+type PruneReport struct {
+	Expired    []crawl.Expired
+	FreedBytes int64
+	KeptBytes  int64
+}
+
+// NewJanitor prunes and archives crawls every interval, a zero interval or an
+// empty retention turns it off. A nil archiver leaves crawls unarchived.
+func NewJanitor(
+	pruner crawl.Pruner,
+	archiver crawl.Archiver,
+	retention crawl.Retention,
+	interval time.Duration,
+	logger log.Logger,
+) *Janitor {
+	return &Janitor{
+		pruner:    pruner,
+		archiver:  archiver,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+func (j *Janitor) Handle(ctx context.Context) error {
+	if j.interval <= 0 || j.pruner == nil || !j.retention.Enabled() {
+		return nil
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.prune(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			j.prune(ctx)
+		}
+	}
+}
+
+func (j *Janitor) prune(ctx context.Context) {
+	report, err := j.Prune(ctx, false)
+	if err != nil {
+		j.logger.Error("prune artifacts", "err", err)
+
+		return
+	}
+
+	artifactsBytes.Set(float64(report.KeptBytes))
+
+	if len(report.Expired) > 0 {
+		j.logger.Info("artifacts pruned", "crawls", len(report.Expired), "freed_bytes", report.FreedBytes)
+	}
+
+	archived, err := j.Archive(ctx)
+	if err != nil {
+		j.logger.Error("archive artifacts", "err", err)
+
+		return
+	}
+
+	if archived > 0 {
+		j.logger.Info("artifacts archived", "days", archived)
+	}
+}
+
+// Archive packs the days older than the retention ArchiveDays.
+func (j *Janitor) Archive(ctx context.Context) (int, error) {
+	if j.archiver == nil || j.retention.ArchiveDays <= 0 {
+		return 0, nil
+	}
+
+	archived, err := j.archiver.Archive(ctx, time.Now().AddDate(0, 0, -j.retention.ArchiveDays))
+	artifactsArchivedCount.Add(float64(archived))
+
+	if err != nil {
+		return archived, fmt.Errorf("archive: %w", err)
+	}
+
+	return archived, nil
+}
+
+// Prune removes expired crawls, with dryRun it only reports what would go.
+func (j *Janitor) Prune(ctx context.Context, dryRun bool) (PruneReport, error) {
+	footprints, err := j.pruner.Footprints(ctx)
+	if err != nil {
+		return PruneReport{}, fmt.Errorf("footprints: %w", err)
+	}
+
+	report := PruneReport{}
+
+	for _, footprint := range footprints {
+		report.KeptBytes += footprint.Bytes
+	}
+
+	expired := j.retention.Expired(footprints, time.Now())
+
+	if !dryRun && len(expired) > 0 {
+		removed := make([]crawl.Footprint, 0, len(expired))
+		for _, crawlExpired := range expired {
+			removed = append(removed, crawlExpired.Footprint)
+		}
+
+		if err := j.pruner.Remove(ctx, removed); err != nil {
+			return report, fmt.Errorf("remove crawls: %w", err)
+		}
+	}
+
+	for _, crawlExpired := range expired {
+		if !dryRun {
+			artifactsPrunedCount.WithLabelValues(string(crawlExpired.Reason)).Inc()
+			artifactsPrunedBytes.Add(float64(crawlExpired.Footprint.Bytes))
+		}
+
+		report.Expired = append(report.Expired, crawlExpired)
+		report.FreedBytes += crawlExpired.Footprint.Bytes
+		report.KeptBytes -= crawlExpired.Footprint.Bytes
+	}
+
+	return report, nil
+}
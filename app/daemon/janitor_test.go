@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+type fakePruner struct {
+	footprints []crawl.Footprint
+	removed    []string
+}
+
+func (f *fakePruner) Footprints(context.Context) ([]crawl.Footprint, error) {
+	return f.footprints, nil
+}
+
+func (f *fakePruner) Remove(_ context.Context, footprints []crawl.Footprint) error {
+	for _, footprint := range footprints {
+		f.removed = append(f.removed, footprint.Ref)
+	}
+
+	return nil
+}
+
+func TestJanitor_Prune(t *testing.T) {
+	t.Parallel()
+
+	pruner := &fakePruner{footprints: []crawl.Footprint{
+		{Ref: "old", RanAt: time.Now().AddDate(0, 0, -30), Bytes: 100},
+		{Ref: "new", RanAt: time.Now(), Bytes: 10},
+	}}
+	janitor := NewJanitor(pruner, nil, crawl.Retention{KeepDays: 7}, 0, log.NewLogger())
+
+	report, err := janitor.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+
+	if len(pruner.removed) != 0 {
+		t.Errorf("expected dry run to keep everything, removed %v", pruner.removed)
+	}
+
+	if len(report.Expired) != 1 || report.FreedBytes != 100 || report.KeptBytes != 10 {
+		t.Errorf("unexpected dry run report %+v", report)
+	}
+
+	if _, err := janitor.Prune(context.Background(), false); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+
+	if len(pruner.removed) != 1 || pruner.removed[0] != "old" {
+		t.Errorf("expected old crawl removed, got %v", pruner.removed)
+	}
+}
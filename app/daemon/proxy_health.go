@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
+)
+
+type ProxyHealth struct {
+	pool     proxy.Pool
+	interval time.Duration
+	logger   log.Logger
+}
+
+// NewProxyHealth checks the pool every interval, a zero interval turns the
+// checks off.
+func NewProxyHealth(pool proxy.Pool, interval time.Duration, logger log.Logger) *ProxyHealth {
+	return &ProxyHealth{
+		pool:     pool,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (p *ProxyHealth) Handle(ctx context.Context) error {
+	if p.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.pool.CheckHealth(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.pool.CheckHealth(ctx)
+
+			p.logger.Info("proxy health checked")
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+)
+
+type CaptchaSpendHandler struct {
+	ledger      captcha.Ledger
+	dailyBudget float64
+}
+
+func NewCaptchaSpendHandler(ledger captcha.Ledger, dailyBudget float64) *CaptchaSpendHandler {
+	return &CaptchaSpendHandler{
+		ledger:      ledger,
+		dailyBudget: dailyBudget,
+	}
+}
+
+type Spend struct {
+	Solves int
+	Cost   float64
+}
+
+type CaptchaSpend struct {
+	Total            Spend
+	Recipients       map[int64]Spend
+	DailyBudget      float64
+	Balance          float64
+	BalanceCheckedAt time.Time
+}
+
+func (h *CaptchaSpendHandler) Handle(ctx context.Context, date time.Time) (CaptchaSpend, error) {
+	daily, err := h.ledger.Daily(ctx, date)
+	if err != nil {
+		return CaptchaSpend{}, fmt.Errorf("daily spend: %w", err)
+	}
+
+	balance, err := h.ledger.Balance(ctx)
+	if err != nil {
+		return CaptchaSpend{}, fmt.Errorf("balance: %w", err)
+	}
+
+	spend := CaptchaSpend{
+		Recipients:       make(map[int64]Spend, len(daily)),
+		DailyBudget:      h.dailyBudget,
+		Balance:          balance.Amount,
+		BalanceCheckedAt: balance.CheckedAt,
+	}
+
+	var total captcha.Spend
+
+	for recipientID, recipientSpend := range daily {
+		total = total.Add(recipientSpend)
+
+		spend.Recipients[recipientID] = Spend{Solves: recipientSpend.Solves, Cost: recipientSpend.Cost}
+	}
+
+	spend.Total = Spend{Solves: total.Solves, Cost: total.Cost}
+
+	return spend, nil
+}
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
+)
+
+type DispatcherHealthHandler struct {
+	dispatcher page.Dispatcher
+}
+
+func NewDispatcherHealthHandler(dispatcher page.Dispatcher) *DispatcherHealthHandler {
+	return &DispatcherHealthHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+type DispatcherHealth struct {
+	Healthy        bool      `json:"healthy"`
+	Since          time.Time `json:"since"`
+	OpenNavigators int       `json:"open_navigators"`
+	Relaunches     int       `json:"relaunches"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+func (h *DispatcherHealthHandler) Handle(_ context.Context) DispatcherHealth {
+	health := h.dispatcher.Health()
+
+	return DispatcherHealth{
+		Healthy:        health.Healthy,
+		Since:          health.Since,
+		OpenNavigators: health.OpenNavigators,
+		Relaunches:     health.Relaunches,
+		LastError:      health.LastError,
+	}
+}
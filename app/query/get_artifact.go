@@ -0,0 +1,34 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	crawldomain "github.com/truewebber/kdmid-queue-checker/domain/crawl"
+)
+
+type GetArtifactHandler struct {
+	crawlStorage crawldomain.Storage
+}
+
+func NewGetArtifactHandler(crawlStorage crawldomain.Storage) *GetArtifactHandler {
+	return &GetArtifactHandler{
+		crawlStorage: crawlStorage,
+	}
+}
+
+// Handle loads the artifact, crawl.ErrArtifactNotFound tells it is gone and
+// crawl.ErrInvalidArtifactRef that no crawl could have it.
+func (h *GetArtifactHandler) Handle(ctx context.Context, artifact Artifact) ([]byte, error) {
+	artifactBytes, err := h.crawlStorage.Artifact(ctx, crawldomain.ArtifactRef{
+		Crawl:    artifact.Crawl,
+		Kind:     crawldomain.ArtifactKind(artifact.Kind),
+		Index:    artifact.Index,
+		MIMEType: artifact.MIMEType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get artifact: %w", err)
+	}
+
+	return artifactBytes, nil
+}
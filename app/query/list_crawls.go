@@ -6,7 +6,6 @@ import (
 	"time"
 
 	crawldomain "github.com/truewebber/kdmid-queue-checker/domain/crawl"
-	"github.com/truewebber/kdmid-queue-checker/domain/image"
 )
 
 type ListCrawlsHandler struct {
@@ -20,40 +19,124 @@ func NewListCrawlsHandler(crawlStorage crawldomain.Storage) *ListCrawlsHandler {
 }
 
 type Crawl struct {
-	Screenshots          []image.PNG
-	Captch               image.PNG
+	ID                   string
+	UserID               int64
+	Status               string
+	Screenshots          []Artifact
+	Captcha              Artifact
 	CrawledAt            time.Time
+	Proxy                string
+	CaptchaSolver        string
+	Attempts             []CaptchaAttempt
+	SubmittedCode        string
+	Retries              int
+	Durations            Durations
+	AppVersion           string
 	Err                  error
 	SomethingInteresting bool
 }
 
-func (h *ListCrawlsHandler) Handle(ctx context.Context, userID int64, date time.Time) ([]Crawl, error) {
-	results, err := h.crawlStorage.ListResults(ctx, userID, date)
+// Artifact references a crawl artifact, GetArtifactHandler loads it.
+type Artifact struct {
+	Crawl    string
+	Kind     string
+	Index    int
+	MIMEType string
+}
+
+func (a Artifact) Empty() bool {
+	return a.Kind == ""
+}
+
+type Durations struct {
+	Authorize, CaptchaSolve, Submit, Calendar, Total time.Duration
+}
+
+type CaptchaAttempt struct {
+	Code, Solver, Preprocessing, Verdict string
+	Confidence                           float64
+}
+
+// CrawlPage is a page of crawls, Total counts every crawl the query matched.
+type CrawlPage struct {
+	Crawls []Crawl
+	Total  int
+}
+
+func (h *ListCrawlsHandler) Handle(ctx context.Context, crawlQuery crawldomain.Query) (CrawlPage, error) {
+	page, err := h.crawlStorage.Query(ctx, crawlQuery)
 	if err != nil {
-		return nil, fmt.Errorf("list crawls: %w", err)
+		return CrawlPage{}, fmt.Errorf("query crawls: %w", err)
 	}
 
-	return h.castCrawls(results), nil
+	return CrawlPage{
+		Crawls: h.castCrawls(page.Summaries),
+		Total:  page.Total,
+	}, nil
 }
 
-func (h *ListCrawlsHandler) castCrawls(domainCrawls []crawldomain.Result) []Crawl {
+func (h *ListCrawlsHandler) castCrawls(domainCrawls []crawldomain.Summary) []Crawl {
 	crawls := make([]Crawl, 0, len(domainCrawls))
 
 	for _, domainCrawl := range domainCrawls {
 		crawl := Crawl{
-			Screenshots: []image.PNG{
-				domainCrawl.One.Screenshot,
-				domainCrawl.Two.Screenshot,
-				domainCrawl.Three.Screenshot,
-			},
-			Captch:               domainCrawl.One.Captcha.Image,
+			UserID:               domainCrawl.UserID,
+			Status:               string(domainCrawl.Status()),
+			ID:                   domainCrawl.ID,
+			Screenshots:          h.castArtifacts(domainCrawl.Artifacts, crawldomain.ArtifactScreenshot),
 			CrawledAt:            domainCrawl.RanAt,
+			Proxy:                domainCrawl.Proxy,
+			CaptchaSolver:        domainCrawl.CaptchaSolver,
+			Attempts:             h.castAttempts(domainCrawl.Attempts),
+			SubmittedCode:        domainCrawl.SubmittedCode(),
+			Retries:              domainCrawl.Retries,
+			Durations:            Durations(domainCrawl.Durations),
+			AppVersion:           domainCrawl.AppVersion,
 			Err:                  domainCrawl.Err,
 			SomethingInteresting: domainCrawl.SomethingInteresting,
 		}
 
+		if captchas := h.castArtifacts(domainCrawl.Artifacts, crawldomain.ArtifactCaptcha); len(captchas) > 0 {
+			crawl.Captcha = captchas[0]
+		}
+
 		crawls = append(crawls, crawl)
 	}
 
 	return crawls
 }
+
+func (h *ListCrawlsHandler) castArtifacts(refs []crawldomain.ArtifactRef, kind crawldomain.ArtifactKind) []Artifact {
+	artifacts := make([]Artifact, 0)
+
+	for _, ref := range refs {
+		if ref.Kind != kind {
+			continue
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Crawl:    ref.Crawl,
+			Kind:     string(ref.Kind),
+			Index:    ref.Index,
+			MIMEType: ref.MIMEType,
+		})
+	}
+
+	return artifacts
+}
+
+func (h *ListCrawlsHandler) castAttempts(domainAttempts []crawldomain.CaptchaAttempt) []CaptchaAttempt {
+	attempts := make([]CaptchaAttempt, 0, len(domainAttempts))
+
+	for _, domainAttempt := range domainAttempts {
+		attempts = append(attempts, CaptchaAttempt{
+			Code:          domainAttempt.Code,
+			Solver:        domainAttempt.Solver,
+			Preprocessing: domainAttempt.Preprocessing,
+			Verdict:       string(domainAttempt.Verdict),
+			Confidence:    domainAttempt.Confidence,
+		})
+	}
+
+	return attempts
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/service"
+)
+
+// captcha-export walks the crawl artifacts and writes every captcha sent to
+// kdmid into a labelled dataset:
+//
+//	<out>/labels.csv                       file,code,verdict,solver,preprocessing,confidence,user_id,crawled_at
+//	<out>/<verdict>/<code>_<n>.<extension> the captcha, named after the submitted code
+//
+// The accepted directory can be fed straight to ocr-train, rejected images are
+// named after the wrong code and only useful for benchmarking.
+func main() {
+	artifacts := flag.String("artifacts", os.Getenv("ARTIFACTS_DIRECTORY"), "crawl artifacts directory")
+	storageFlags := service.NewCrawlStorageFlags(flag.CommandLine)
+	out := flag.String("out", "dataset", "directory to write the dataset to")
+	from := flag.String("from", time.Now().AddDate(0, 0, -30).Format(time.DateOnly), "first crawl date to export")
+	to := flag.String("to", time.Now().Format(time.DateOnly), "last crawl date to export")
+	verdict := flag.String("verdict", "", "export only attempts with this verdict: accepted, rejected or unknown")
+	flag.Parse()
+
+	logger := log.NewLogger()
+
+	if err := run(
+		context.Background(), *artifacts, storageFlags.Config(), *out, *from, *to, crawl.Verdict(*verdict), logger,
+	); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := logger.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func run(
+	ctx context.Context,
+	artifacts string,
+	storageConfig service.CrawlStorage,
+	out, fromVal, toVal string,
+	verdict crawl.Verdict,
+	logger log.Logger,
+) error {
+	if artifacts == "" && storageConfig.Kind != service.CrawlStorageS3 {
+		return errors.New("artifacts directory is required")
+	}
+
+	from, err := time.Parse(time.DateOnly, fromVal)
+	if err != nil {
+		return fmt.Errorf("parse from date: %w", err)
+	}
+
+	to, err := time.Parse(time.DateOnly, toVal)
+	if err != nil {
+		return fmt.Errorf("parse to date: %w", err)
+	}
+
+	storage, err := service.NewCrawlStorage(storageConfig, artifacts, logger)
+	if err != nil {
+		return fmt.Errorf("new crawl storage: %w", err)
+	}
+
+	if closer, ok := storage.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.Error("failed close crawl storage", "error", err.Error())
+			}
+		}()
+	}
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return fmt.Errorf("create out directory: %w", err)
+	}
+
+	labelsFile, err := os.Create(filepath.Join(out, "labels.csv"))
+	if err != nil {
+		return fmt.Errorf("create labels file: %w", err)
+	}
+
+	defer labelsFile.Close()
+
+	e := &exporter{
+		out:     out,
+		verdict: verdict,
+		labels:  csv.NewWriter(labelsFile),
+		counts:  make(map[crawl.Verdict]int),
+	}
+
+	if err := e.labels.Write([]string{
+		"file", "code", "verdict", "solver", "preprocessing", "confidence", "user_id", "crawled_at",
+	}); err != nil {
+		return fmt.Errorf("write labels header: %w", err)
+	}
+
+	userIDs, err := storage.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+			results, err := storage.ListResults(ctx, userID, date)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("list results of %d at %s: %w", userID, date.Format(time.DateOnly), err)
+			}
+
+			for i := range results {
+				if err := e.export(userID, &results[i]); err != nil {
+					return fmt.Errorf("export crawl: %w", err)
+				}
+			}
+		}
+	}
+
+	e.labels.Flush()
+
+	if err := e.labels.Error(); err != nil {
+		return fmt.Errorf("write labels: %w", err)
+	}
+
+	fmt.Printf("exported %d accepted, %d rejected and %d unknown captchas to %s\n",
+		e.counts[crawl.VerdictAccepted], e.counts[crawl.VerdictRejected], e.counts[crawl.VerdictUnknown], out)
+
+	return nil
+}
+
+type exporter struct {
+	out     string
+	verdict crawl.Verdict
+	labels  *csv.Writer
+	counts  map[crawl.Verdict]int
+	seq     int
+}
+
+func (e *exporter) export(userID int64, result *crawl.Result) error {
+	for _, attempt := range result.Attempts {
+		if attempt.Image.Empty() || attempt.Code == "" {
+			continue
+		}
+
+		if e.verdict != "" && attempt.Verdict != e.verdict {
+			continue
+		}
+
+		verdictDir := filepath.Join(e.out, string(attempt.Verdict))
+		if err := os.MkdirAll(verdictDir, 0o755); err != nil {
+			return fmt.Errorf("create verdict directory: %w", err)
+		}
+
+		e.seq++
+
+		fileName := fmt.Sprintf("%s_%05d.%s", attempt.Code, e.seq, attempt.Image.Extension())
+		if err := os.WriteFile(filepath.Join(verdictDir, fileName), attempt.Image.Bytes, 0o644); err != nil {
+			return fmt.Errorf("write captcha: %w", err)
+		}
+
+		if err := e.labels.Write([]string{
+			filepath.Join(string(attempt.Verdict), fileName),
+			attempt.Code,
+			string(attempt.Verdict),
+			attempt.Solver,
+			attempt.Preprocessing,
+			strconv.FormatFloat(attempt.Confidence, 'f', 4, 64),
+			strconv.FormatInt(userID, 10),
+			result.RanAt.Format(time.RFC3339),
+		}); err != nil {
+			return fmt.Errorf("write label: %w", err)
+		}
+
+		e.counts[attempt.Verdict]++
+	}
+
+	return nil
+}
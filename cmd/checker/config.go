@@ -1,24 +1,123 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Netflix/go-env"
+
+	"github.com/truewebber/kdmid-queue-checker/service"
 )
 
 type config struct {
 	TwoCaptcha struct {
-		APIKey string `env:"TWO_CAPTCHA_API_KEY,required=true"`
+		APIKey string  `env:"TWO_CAPTCHA_API_KEY"`
+		Price  float64 `env:"TWO_CAPTCHA_PRICE,default=0.001"`
+	}
+	AntiCaptcha struct {
+		Endpoint        string        `env:"ANTI_CAPTCHA_ENDPOINT,default=https://api.anti-captcha.com"`
+		APIKey          string        `env:"ANTI_CAPTCHA_API_KEY"`
+		PollingInterval time.Duration `env:"ANTI_CAPTCHA_POLLING_INTERVAL,default=3s"`
+		Timeout         time.Duration `env:"ANTI_CAPTCHA_TIMEOUT,default=2m"`
+	}
+	Captcha struct {
+		// CAPTCHA_SOLVER is the single solver name used before chains.
+		Solvers              []string      `env:"CAPTCHA_SOLVERS,CAPTCHA_SOLVER,default=2captcha,separator=;"`
+		OCRMinConfidence     float64       `env:"OCR_MIN_CONFIDENCE,default=0.2"`
+		Preprocessing        []string      `env:"CAPTCHA_PREPROCESSING,separator=;"`
+		DailyBudget          float64       `env:"CAPTCHA_DAILY_BUDGET,default=0"`
+		BalanceCheckInterval time.Duration `env:"CAPTCHA_BALANCE_CHECK_INTERVAL,default=15m"`
+		LedgerKeepDays       int           `env:"CAPTCHA_LEDGER_KEEP_DAYS,default=90"`
+		HumanOverBudget      bool          `env:"CAPTCHA_HUMAN_OVER_BUDGET,default=false"`
+		TelegramChatID       int64         `env:"CAPTCHA_TELEGRAM_CHAT_ID,default=0"`
+		TelegramTimeout      time.Duration `env:"CAPTCHA_TELEGRAM_TIMEOUT,default=2m"`
 	}
 	ArtifactsDirectory string `env:"ARTIFACTS_DIRECTORY,required=true"`
-	RecipientStorage   struct {
+	CrawlStorage       struct {
+		Kind          string `env:"CRAWL_STORAGE,default=fs"`
+		SQLitePath    string `env:"CRAWL_STORAGE_SQLITE_PATH"`
+		SQLiteMaxBlob int    `env:"CRAWL_STORAGE_SQLITE_MAX_BLOB,default=262144"`
+		ArchiveFormat string `env:"ARTIFACTS_ARCHIVE_FORMAT,default=zstd"`
+		S3            struct {
+			Endpoint  string `env:"S3_ENDPOINT"`
+			Region    string `env:"S3_REGION,default=us-east-1"`
+			Bucket    string `env:"S3_BUCKET,default=kdmid-artifacts"`
+			Prefix    string `env:"S3_PREFIX"`
+			AccessKey string `env:"S3_ACCESS_KEY"`
+			SecretKey string `env:"S3_SECRET_KEY"`
+			UseSSL    bool   `env:"S3_USE_SSL,default=true"`
+		}
+	}
+	Retention struct {
+		KeepDays        int           `env:"ARTIFACTS_KEEP_DAYS,default=0"`
+		KeepNotableDays int           `env:"ARTIFACTS_KEEP_NOTABLE_DAYS,default=0"`
+		MaxBytes        int64         `env:"ARTIFACTS_MAX_SIZE,default=0"`
+		ArchiveDays     int           `env:"ARTIFACTS_ARCHIVE_DAYS,default=0"`
+		PruneInterval   time.Duration `env:"ARTIFACTS_PRUNE_INTERVAL,default=1h"`
+	}
+	RecipientStorage struct {
 		Directory string `env:"RECIPIENT_STORAGE_DIRECTORY,required=true"`
 		Limit     uint8  `env:"RECIPIENT_STORAGE_LIMIT,required=true"`
 	}
 	TelegramBotToken string `env:"TELEGRAM_BOT_TOKEN,required=true"`
 	AppHostPort      string `env:"APP_HOST_PORT,required=true"`
 	MetricsHostPort  string `env:"METRICS_HOST_PORT,required=true"`
-	ProxyURL         string `env:"PROXY_URL,required=true"`
+	Kdmid            struct {
+		BaseURL   string        `env:"KDMID_BASE_URL,default=https://barcelona.kdmid.ru"`
+		Timeout   time.Duration `env:"KDMID_TIMEOUT,default=2m"`
+		Navigator string        `env:"KDMID_NAVIGATOR,default=browser"`
+	}
+	Browser struct {
+		MaxContexts         int           `env:"BROWSER_MAX_CONTEXTS,default=2"`
+		HealthCheckInterval time.Duration `env:"BROWSER_HEALTH_CHECK_INTERVAL,default=30s"`
+		RelaunchBackoff     time.Duration `env:"BROWSER_RELAUNCH_BACKOFF,default=1s"`
+		MaxRelaunchBackoff  time.Duration `env:"BROWSER_RELAUNCH_MAX_BACKOFF,default=1m"`
+	}
+	Screenshots struct {
+		Format    string `env:"SCREENSHOT_FORMAT,default=png"`
+		Quality   int    `env:"SCREENSHOT_QUALITY,default=0"`
+		MaxWidth  int    `env:"SCREENSHOT_MAX_WIDTH,default=0"`
+		MaxHeight int    `env:"SCREENSHOT_MAX_HEIGHT,default=0"`
+		Authorize struct {
+			Scope     string `env:"SCREENSHOT_AUTHORIZE_SCOPE,default=full_page"`
+			Selector  string `env:"SCREENSHOT_AUTHORIZE_SELECTOR"`
+			Format    string `env:"SCREENSHOT_AUTHORIZE_FORMAT"`
+			Quality   int    `env:"SCREENSHOT_AUTHORIZE_QUALITY,default=0"`
+			MaxWidth  int    `env:"SCREENSHOT_AUTHORIZE_MAX_WIDTH,default=0"`
+			MaxHeight int    `env:"SCREENSHOT_AUTHORIZE_MAX_HEIGHT,default=0"`
+		}
+		Submit struct {
+			Scope     string `env:"SCREENSHOT_SUBMIT_SCOPE,default=full_page"`
+			Selector  string `env:"SCREENSHOT_SUBMIT_SELECTOR"`
+			Format    string `env:"SCREENSHOT_SUBMIT_FORMAT"`
+			Quality   int    `env:"SCREENSHOT_SUBMIT_QUALITY,default=0"`
+			MaxWidth  int    `env:"SCREENSHOT_SUBMIT_MAX_WIDTH,default=0"`
+			MaxHeight int    `env:"SCREENSHOT_SUBMIT_MAX_HEIGHT,default=0"`
+		}
+		Calendar struct {
+			Scope     string `env:"SCREENSHOT_CALENDAR_SCOPE,default=full_page"`
+			Selector  string `env:"SCREENSHOT_CALENDAR_SELECTOR"`
+			Format    string `env:"SCREENSHOT_CALENDAR_FORMAT"`
+			Quality   int    `env:"SCREENSHOT_CALENDAR_QUALITY,default=0"`
+			MaxWidth  int    `env:"SCREENSHOT_CALENDAR_MAX_WIDTH,default=0"`
+			MaxHeight int    `env:"SCREENSHOT_CALENDAR_MAX_HEIGHT,default=0"`
+		}
+	}
+	Blocking struct {
+		ResourceTypes    []string `env:"BROWSER_BLOCK_RESOURCE_TYPES,default=font;media,separator=;"`
+		BlockURLPatterns []string `env:"BROWSER_BLOCK_URL_PATTERNS,default=google-analytics.com;googletagmanager.com;mc.yandex.ru,separator=;"`
+		AllowURLPatterns []string `env:"BROWSER_ALLOW_URL_PATTERNS,default=CodeImage.aspx,separator=;"`
+	}
+	Proxy struct {
+		URLs                string        `env:"PROXY_URLS,PROXY_URL"`
+		File                string        `env:"PROXY_FILE"`
+		Rotation            string        `env:"PROXY_ROTATION,default=crawl"`
+		Quarantine          time.Duration `env:"PROXY_QUARANTINE,default=30m"`
+		HealthCheckURL      string        `env:"PROXY_HEALTH_CHECK_URL,default=https://barcelona.kdmid.ru/queue/"`
+		HealthCheckInterval time.Duration `env:"PROXY_HEALTH_CHECK_INTERVAL,default=5m"`
+	}
 }
 
 func mustLoadConfig() *config {
@@ -37,5 +136,27 @@ func loadConfig() (*config, error) {
 		return nil, fmt.Errorf("config unmarshal: %w", err)
 	}
 
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("config validate: %w", err)
+	}
+
 	return &c, nil
 }
+
+// validate asks for the API keys of the paid solvers in the chain only.
+func (c *config) validate() error {
+	for _, solver := range c.Captcha.Solvers {
+		switch strings.TrimSpace(solver) {
+		case service.SolverTwoCaptcha:
+			if c.TwoCaptcha.APIKey == "" {
+				return errors.New("TWO_CAPTCHA_API_KEY is required by the 2captcha solver")
+			}
+		case service.SolverAntiCaptcha:
+			if c.AntiCaptcha.APIKey == "" {
+				return errors.New("ANTI_CAPTCHA_API_KEY is required by the anticaptcha solver")
+			}
+		}
+	}
+
+	return nil
+}
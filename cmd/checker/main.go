@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"regexp"
+	"strings"
 	"syscall"
 
 	"github.com/truewebber/gopkg/log"
@@ -15,6 +18,9 @@ import (
 	"github.com/truewebber/kdmid-queue-checker/service"
 )
 
+// version is stamped at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	cfg := mustLoadConfig()
 	logger := log.NewLogger()
@@ -38,8 +44,23 @@ func run(ctx context.Context, cfg *config, logger log.Logger) error {
 
 	app := service.NewApplication(appConfig, logger)
 
+	defer func() {
+		if err := app.Close(); err != nil {
+			logger.Error("failed close application", "error", err.Error())
+		}
+	}()
+
 	logger.Info("Application configured")
 
+	migrated, err := app.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate crawl storage: %w", err)
+	}
+
+	if migrated > 0 {
+		logger.Info("crawl storage layout migrated", "crawls", migrated)
+	}
+
 	httpServer := port.NewHTTP(cfg.AppHostPort, app, logger)
 	metricsServer := metrics.NewMetricsServer(cfg.MetricsHostPort)
 
@@ -69,6 +90,30 @@ func run(ctx context.Context, cfg *config, logger log.Logger) error {
 		return nil
 	})
 
+	group.Go(func() error {
+		if err := app.Daemon.ProxyHealth.Handle(groupCtx); err != nil {
+			return fmt.Errorf("handle daemon proxy health: %w", err)
+		}
+
+		return nil
+	})
+
+	group.Go(func() error {
+		if err := app.Daemon.CaptchaBalance.Handle(groupCtx); err != nil {
+			return fmt.Errorf("handle daemon captcha balance: %w", err)
+		}
+
+		return nil
+	})
+
+	group.Go(func() error {
+		if err := app.Daemon.Janitor.Handle(groupCtx); err != nil {
+			return fmt.Errorf("handle daemon janitor: %w", err)
+		}
+
+		return nil
+	})
+
 	group.Go(func() error {
 		if err := httpServer.Start(groupCtx); err != nil {
 			return fmt.Errorf("run http server: %w", err)
@@ -85,19 +130,207 @@ func run(ctx context.Context, cfg *config, logger log.Logger) error {
 }
 
 func buildAppConfig(cfg *config) (*service.Config, error) {
-	proxyURL, err := url.Parse(cfg.ProxyURL)
+	kdmidBaseURL, err := url.Parse(cfg.Kdmid.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse kdmid base url: %w", err)
+	}
+
+	blockURLPatterns, err := compilePatterns(cfg.Blocking.BlockURLPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("compile block url patterns: %w", err)
+	}
+
+	allowURLPatterns, err := compilePatterns(cfg.Blocking.AllowURLPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("compile allow url patterns: %w", err)
+	}
+
+	proxyURLs, err := parseProxyURLs(cfg.Proxy.URLs, cfg.Proxy.File)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy urls: %w", err)
+	}
+
+	preprocessing, err := parsePreprocessing(cfg.Captcha.Preprocessing)
 	if err != nil {
-		return nil, fmt.Errorf("parse proxy url: %w", err)
+		return nil, fmt.Errorf("parse captcha preprocessing: %w", err)
 	}
 
 	return &service.Config{
-		TwoCaptchaAPIKey:   cfg.TwoCaptcha.APIKey,
+		AppVersion:       version,
+		TwoCaptchaAPIKey: cfg.TwoCaptcha.APIKey,
+		Captcha: service.Captcha{
+			Solvers:              nonEmpty(cfg.Captcha.Solvers),
+			OCRMinConfidence:     cfg.Captcha.OCRMinConfidence,
+			TwoCaptchaPrice:      cfg.TwoCaptcha.Price,
+			DailyBudget:          cfg.Captcha.DailyBudget,
+			BalanceCheckInterval: cfg.Captcha.BalanceCheckInterval,
+			LedgerKeepDays:       cfg.Captcha.LedgerKeepDays,
+			Preprocessing:        preprocessing,
+			HumanOverBudget:      cfg.Captcha.HumanOverBudget,
+			TelegramChatID:       cfg.Captcha.TelegramChatID,
+			TelegramTimeout:      cfg.Captcha.TelegramTimeout,
+			AntiCaptcha: service.AntiCaptcha{
+				Endpoint:        cfg.AntiCaptcha.Endpoint,
+				APIKey:          cfg.AntiCaptcha.APIKey,
+				PollingInterval: cfg.AntiCaptcha.PollingInterval,
+				Timeout:         cfg.AntiCaptcha.Timeout,
+			},
+		},
 		ArtifactsDirectory: cfg.ArtifactsDirectory,
-		TelegramBotToken:   cfg.TelegramBotToken,
+		CrawlStorage: service.CrawlStorage{
+			Kind:          cfg.CrawlStorage.Kind,
+			SQLitePath:    cfg.CrawlStorage.SQLitePath,
+			SQLiteMaxBlob: cfg.CrawlStorage.SQLiteMaxBlob,
+			ArchiveFormat: cfg.CrawlStorage.ArchiveFormat,
+			S3: service.S3{
+				Endpoint:  cfg.CrawlStorage.S3.Endpoint,
+				Region:    cfg.CrawlStorage.S3.Region,
+				Bucket:    cfg.CrawlStorage.S3.Bucket,
+				Prefix:    cfg.CrawlStorage.S3.Prefix,
+				AccessKey: cfg.CrawlStorage.S3.AccessKey,
+				SecretKey: cfg.CrawlStorage.S3.SecretKey,
+				UseSSL:    cfg.CrawlStorage.S3.UseSSL,
+			},
+			Retention: service.Retention{
+				KeepDays:        cfg.Retention.KeepDays,
+				KeepNotableDays: cfg.Retention.KeepNotableDays,
+				MaxBytes:        cfg.Retention.MaxBytes,
+				ArchiveDays:     cfg.Retention.ArchiveDays,
+				PruneInterval:   cfg.Retention.PruneInterval,
+			},
+		},
+		TelegramBotToken: cfg.TelegramBotToken,
 		RecipientStorage: service.RecipientStorage{
 			Directory: cfg.RecipientStorage.Directory,
 			Limit:     cfg.RecipientStorage.Limit,
 		},
-		ProxyURL: proxyURL,
+		Kdmid: service.Kdmid{
+			BaseURL:   kdmidBaseURL,
+			Timeout:   cfg.Kdmid.Timeout,
+			Navigator: cfg.Kdmid.Navigator,
+			Browser: service.Browser{
+				MaxContexts:         cfg.Browser.MaxContexts,
+				HealthCheckInterval: cfg.Browser.HealthCheckInterval,
+				RelaunchBackoff:     cfg.Browser.RelaunchBackoff,
+				MaxRelaunchBackoff:  cfg.Browser.MaxRelaunchBackoff,
+			},
+			Screenshots: service.Screenshots{
+				Format:    cfg.Screenshots.Format,
+				Quality:   cfg.Screenshots.Quality,
+				MaxWidth:  cfg.Screenshots.MaxWidth,
+				MaxHeight: cfg.Screenshots.MaxHeight,
+				Authorize: service.ScreenshotStep{
+					Scope:     cfg.Screenshots.Authorize.Scope,
+					Selector:  cfg.Screenshots.Authorize.Selector,
+					Format:    cfg.Screenshots.Authorize.Format,
+					Quality:   cfg.Screenshots.Authorize.Quality,
+					MaxWidth:  cfg.Screenshots.Authorize.MaxWidth,
+					MaxHeight: cfg.Screenshots.Authorize.MaxHeight,
+				},
+				Submit: service.ScreenshotStep{
+					Scope:     cfg.Screenshots.Submit.Scope,
+					Selector:  cfg.Screenshots.Submit.Selector,
+					Format:    cfg.Screenshots.Submit.Format,
+					Quality:   cfg.Screenshots.Submit.Quality,
+					MaxWidth:  cfg.Screenshots.Submit.MaxWidth,
+					MaxHeight: cfg.Screenshots.Submit.MaxHeight,
+				},
+				Calendar: service.ScreenshotStep{
+					Scope:     cfg.Screenshots.Calendar.Scope,
+					Selector:  cfg.Screenshots.Calendar.Selector,
+					Format:    cfg.Screenshots.Calendar.Format,
+					Quality:   cfg.Screenshots.Calendar.Quality,
+					MaxWidth:  cfg.Screenshots.Calendar.MaxWidth,
+					MaxHeight: cfg.Screenshots.Calendar.MaxHeight,
+				},
+			},
+			Blocking: service.Blocking{
+				ResourceTypes:    nonEmpty(cfg.Blocking.ResourceTypes),
+				BlockURLPatterns: blockURLPatterns,
+				AllowURLPatterns: allowURLPatterns,
+			},
+		},
+		Proxy: service.Proxy{
+			URLs:                proxyURLs,
+			Rotation:            cfg.Proxy.Rotation,
+			Quarantine:          cfg.Proxy.Quarantine,
+			HealthCheckURL:      cfg.Proxy.HealthCheckURL,
+			HealthCheckInterval: cfg.Proxy.HealthCheckInterval,
+		},
 	}, nil
 }
+
+func parseProxyURLs(list, file string) ([]*url.URL, error) {
+	rawURLs := strings.Split(list, ",")
+
+	if file != "" {
+		fileBytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read proxy file: %w", err)
+		}
+
+		rawURLs = append(rawURLs, strings.Split(string(fileBytes), "\n")...)
+	}
+
+	proxyURLs := make([]*url.URL, 0, len(rawURLs))
+
+	for _, rawURL := range rawURLs {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" || strings.HasPrefix(rawURL, "#") {
+			continue
+		}
+
+		proxyURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+
+		proxyURLs = append(proxyURLs, proxyURL)
+	}
+
+	return proxyURLs, nil
+}
+
+// parsePreprocessing reads entries like `2captcha=contrast,denoise`.
+func parsePreprocessing(entries []string) (map[string][]string, error) {
+	preprocessing := make(map[string][]string, len(entries))
+
+	for _, entry := range nonEmpty(entries) {
+		solver, steps, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry `%s`, expected solver=step,step", entry)
+		}
+
+		preprocessing[strings.TrimSpace(solver)] = nonEmpty(strings.Split(steps, ","))
+	}
+
+	return preprocessing, nil
+}
+
+func compilePatterns(rawPatterns []string) ([]*regexp.Regexp, error) {
+	rawPatterns = nonEmpty(rawPatterns)
+	patterns := make([]*regexp.Regexp, 0, len(rawPatterns))
+
+	for _, rawPattern := range rawPatterns {
+		pattern, err := regexp.Compile(rawPattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile `%s`: %w", rawPattern, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/truewebber/kdmid-queue-checker/adapter"
+)
+
+// ocr-train builds OCR digit templates from a directory of labelled captchas,
+// every file is named after the code it shows, e.g. 123456_0001.png. The
+// accuracy is reported on the eval directory, which must not share captchas
+// with the corpus, e.g. the accepted captchas of captcha-export.
+func main() {
+	corpus := flag.String("corpus", "adapter/testdata/captcha/train", "directory of labelled captcha images")
+	eval := flag.String("eval", "adapter/testdata/captcha/eval", "directory of held-out labelled captcha images")
+	out := flag.String("out", "adapter/ocr_templates.json", "file to write templates to")
+	flag.Parse()
+
+	if err := run(*corpus, *eval, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(corpus, eval, out string) error {
+	samples, err := adapter.LoadOCRSamples(corpus)
+	if err != nil {
+		return fmt.Errorf("load samples: %w", err)
+	}
+
+	templates, err := adapter.TrainOCRTemplates(samples)
+	if err != nil {
+		return fmt.Errorf("train templates: %w", err)
+	}
+
+	data, err := json.Marshal(templates)
+	if err != nil {
+		return fmt.Errorf("marshal templates: %w", err)
+	}
+
+	if err := os.WriteFile(out, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write templates: %w", err)
+	}
+
+	fmt.Printf("trained on %d samples, templates written to %s\n", len(samples), out)
+
+	if eval == "" {
+		return nil
+	}
+
+	evalSamples, err := adapter.LoadOCRSamples(eval)
+	if err != nil {
+		return fmt.Errorf("load eval samples: %w", err)
+	}
+
+	accuracy, err := adapter.EvaluateOCRTemplates(templates, evalSamples)
+	if err != nil {
+		return fmt.Errorf("evaluate templates: %w", err)
+	}
+
+	fmt.Printf("accuracy on %d held-out captchas: codes %.1f%%, digits %.1f%%\n",
+		accuracy.Samples, accuracy.Codes*100, accuracy.Digits*100)
+
+	return nil
+}
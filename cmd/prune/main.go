@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/truewebber/gopkg/log"
+
+	"github.com/truewebber/kdmid-queue-checker/app/daemon"
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/service"
+)
+
+// prune applies the artifacts retention policy once, the same way the
+// checker janitor does:
+//
+//	prune -artifacts /data/artifacts -keep-days 14 -keep-notable-days 90 -dry-run
+//
+// With -migrate crawls kept in an older layout are moved first, the checker
+// does the same when it starts.
+func main() {
+	artifacts := flag.String("artifacts", os.Getenv("ARTIFACTS_DIRECTORY"), "crawl artifacts directory")
+	storageFlags := service.NewCrawlStorageFlags(flag.CommandLine)
+	keepDays := flag.Int("keep-days", 0, "days to keep ordinary crawls, 0 keeps them forever")
+	keepNotableDays := flag.Int("keep-notable-days", 0, "days to keep interesting and failed crawls, 0 keeps them forever")
+	maxSize := flag.Int64("max-size", 0, "max total size of the crawls in bytes, 0 for no limit")
+	archiveDays := flag.Int("archive-days", 0, "days after which file system crawls are archived, 0 never archives")
+	dryRun := flag.Bool("dry-run", false, "only print what would be removed")
+	migrate := flag.Bool("migrate", false, "move crawls kept in an older layout first, skipped in a dry run")
+	flag.Parse()
+
+	logger := log.NewLogger()
+
+	retention := crawl.Retention{
+		KeepDays:        *keepDays,
+		KeepNotableDays: *keepNotableDays,
+		MaxBytes:        *maxSize,
+		ArchiveDays:     *archiveDays,
+	}
+
+	if err := run(
+		context.Background(), *artifacts, storageFlags.Config(), retention, *migrate, *dryRun, logger,
+	); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := logger.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func run(
+	ctx context.Context,
+	artifacts string,
+	storageConfig service.CrawlStorage,
+	retention crawl.Retention,
+	migrate bool,
+	dryRun bool,
+	logger log.Logger,
+) error {
+	if artifacts == "" && storageConfig.Kind != service.CrawlStorageS3 {
+		return errors.New("artifacts directory is required")
+	}
+
+	storage, err := service.NewCrawlStorage(storageConfig, artifacts, logger)
+	if err != nil {
+		return fmt.Errorf("new crawl storage: %w", err)
+	}
+
+	if closer, ok := storage.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.Error("failed close crawl storage", "error", err.Error())
+			}
+		}()
+	}
+
+	// Moving crawls is not previewed, a dry run leaves the layout as is.
+	if migrate && !dryRun {
+		migrator, ok := storage.(crawl.Migrator)
+		if !ok {
+			return fmt.Errorf("crawl storage %T has no layout to migrate", storage)
+		}
+
+		migrated, err := migrator.Migrate(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+
+		fmt.Printf("migrated %d crawls\n", migrated)
+	}
+
+	pruner, ok := storage.(crawl.Pruner)
+	if !ok {
+		return fmt.Errorf("crawl storage %T can not be pruned", storage)
+	}
+
+	archiver, _ := storage.(crawl.Archiver)
+	janitor := daemon.NewJanitor(pruner, archiver, retention, 0, logger)
+
+	report, err := janitor.Prune(ctx, dryRun)
+	if err != nil {
+		return fmt.Errorf("prune: %w", err)
+	}
+
+	if err := printReport(os.Stdout, report, dryRun); err != nil {
+		return fmt.Errorf("print report: %w", err)
+	}
+
+	// Archiving only repacks crawls, there is nothing to preview.
+	if dryRun {
+		return nil
+	}
+
+	archived, err := janitor.Archive(ctx)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+
+	if archived > 0 {
+		fmt.Printf("archived %d days\n", archived)
+	}
+
+	return nil
+}
+
+func printReport(out io.Writer, report daemon.PruneReport, dryRun bool) error {
+	action := "removed"
+	if dryRun {
+		action = "would remove"
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	for _, expired := range report.Expired {
+		footprint := expired.Footprint
+
+		notable := ""
+		if footprint.Notable {
+			notable = "notable"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\t%d B\t%s\n",
+			action, expired.Reason, footprint.UserID, footprint.RanAt.Format(time.DateTime),
+			notable, footprint.Bytes, footprint.Ref,
+		)
+	}
+
+	fmt.Fprintf(writer, "%s %d crawls, %d B freed, %d B kept\n",
+		action, len(report.Expired), report.FreedBytes, report.KeptBytes,
+	)
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("flush report: %w", err)
+	}
+
+	return nil
+}
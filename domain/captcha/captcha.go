@@ -1,7 +1,74 @@
 package captcha
 
-import "github.com/truewebber/kdmid-queue-checker/domain/image"
+import (
+	"context"
+	"fmt"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+const CodeLength = 6
+
+var (
+	ErrLowConfidence = fmt.Errorf("low confidence")
+	ErrInvalidCode   = fmt.Errorf("invalid code")
+	ErrNotSolved     = fmt.Errorf("no solver solved captcha")
+)
+
+type Solution struct {
+	Code       string
+	Confidence float64
+	Solver     string
+	// Preprocessing names the pipeline the captcha went through.
+	Preprocessing string
+	Cost          float64
+	Reporter      func(correct bool) error
+}
+
+// Report tells the solver whether the code was accepted, solvers which do
+// not take feedback ignore it.
+func (s Solution) Report(correct bool) error {
+	if s.Reporter == nil {
+		return nil
+	}
+
+	return s.Reporter(correct)
+}
 
 type Solver interface {
-	Solve(png image.PNG) (string, error)
+	Solve(ctx context.Context, img image.Image) (Solution, error)
+}
+
+func ValidCode(code string) bool {
+	if len(code) != CodeLength {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+type recipientKey struct{}
+
+// WithRecipient tells solvers which recipient the captcha is solved for.
+func WithRecipient(ctx context.Context, telegramID int64) context.Context {
+	return context.WithValue(ctx, recipientKey{}, telegramID)
+}
+
+func RecipientFrom(ctx context.Context) (int64, bool) {
+	telegramID, ok := ctx.Value(recipientKey{}).(int64)
+
+	return telegramID, ok
+}
+
+// HumanSolver sends captchas to people and waits for them to type the code,
+// the answers come back through the chat the question was asked in.
+type HumanSolver interface {
+	Solver
+	Answer(chatID int64, questionID int, code string) bool
 }
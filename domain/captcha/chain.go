@@ -0,0 +1,69 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type Link struct {
+	Name          string
+	Solver        Solver
+	MinConfidence float64
+	// Preprocess runs on the captcha before it is given to the solver.
+	Preprocess image.Pipeline
+}
+
+type chain struct {
+	links []Link
+}
+
+// NewChain tries the solvers in order and falls back to the next one when
+// a solver fails, is not confident enough or answers something that is not
+// a code. The cost of discarded answers is added to the returned solution,
+// which carries it even when no solver succeeded.
+func NewChain(links ...Link) Solver {
+	return &chain{links: links}
+}
+
+func (c *chain) Solve(ctx context.Context, img image.Image) (Solution, error) {
+	var (
+		errs = make([]error, 0, len(c.links))
+		cost float64
+	)
+
+	for _, link := range c.links {
+		solution, err := c.solve(ctx, link, img)
+
+		cost += solution.Cost
+
+		switch {
+		case err != nil:
+		case !ValidCode(solution.Code):
+			err = fmt.Errorf("%w `%s`", ErrInvalidCode, solution.Code)
+		case solution.Confidence < link.MinConfidence:
+			err = fmt.Errorf("%w: %.2f < %.2f", ErrLowConfidence, solution.Confidence, link.MinConfidence)
+		default:
+			solution.Solver = link.Name
+			solution.Preprocessing = link.Preprocess.String()
+			solution.Cost = cost
+
+			return solution, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", link.Name, err))
+	}
+
+	return Solution{Cost: cost}, fmt.Errorf("%w: %w", ErrNotSolved, errors.Join(errs...))
+}
+
+func (c *chain) solve(ctx context.Context, link Link, img image.Image) (Solution, error) {
+	preprocessed, err := link.Preprocess.Apply(img)
+	if err != nil {
+		return Solution{}, fmt.Errorf("preprocess: %w", err)
+	}
+
+	return link.Solver.Solve(ctx, preprocessed)
+}
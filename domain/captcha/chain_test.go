@@ -0,0 +1,208 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	goimage "image"
+	"image/png"
+	"testing"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+)
+
+type fakeSolver struct {
+	solution Solution
+	err      error
+	calls    int
+	seen     image.Image
+}
+
+func (f *fakeSolver) Solve(_ context.Context, img image.Image) (Solution, error) {
+	f.calls++
+	f.seen = img
+
+	return f.solution, f.err
+}
+
+func TestChain_Fallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		first      *fakeSolver
+		wantSolver string
+	}{
+		{
+			name:       "Confident First",
+			first:      &fakeSolver{solution: Solution{Code: "123456", Confidence: 0.9}},
+			wantSolver: "ocr",
+		},
+		{
+			name:       "Error",
+			first:      &fakeSolver{err: errors.New("boom")},
+			wantSolver: "2captcha",
+		},
+		{
+			name:       "Low Confidence",
+			first:      &fakeSolver{solution: Solution{Code: "123456", Confidence: 0.1}},
+			wantSolver: "2captcha",
+		},
+		{
+			name:       "Not Six Digits",
+			first:      &fakeSolver{solution: Solution{Code: "12345", Confidence: 0.9}},
+			wantSolver: "2captcha",
+		},
+		{
+			name:       "Not Digits",
+			first:      &fakeSolver{solution: Solution{Code: "12a456", Confidence: 0.9}},
+			wantSolver: "2captcha",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			second := &fakeSolver{solution: Solution{Code: "654321", Confidence: 1}}
+
+			solver := NewChain(
+				Link{Name: "ocr", Solver: tt.first, MinConfidence: 0.5},
+				Link{Name: "2captcha", Solver: second},
+			)
+
+			solution, err := solver.Solve(context.Background(), image.Image{})
+			if err != nil {
+				t.Fatalf("solve: %v", err)
+			}
+
+			if solution.Solver != tt.wantSolver {
+				t.Errorf("expected solver %s, got %s", tt.wantSolver, solution.Solver)
+			}
+
+			wantCalls := 1
+			if tt.wantSolver == "ocr" {
+				wantCalls = 0
+			}
+
+			if second.calls != wantCalls {
+				t.Errorf("expected fallback solver to be called %d times, got %d", wantCalls, second.calls)
+			}
+		})
+	}
+}
+
+func TestChain_AllFailed(t *testing.T) {
+	t.Parallel()
+
+	solver := NewChain(
+		Link{
+			Name:          "anticaptcha",
+			Solver:        &fakeSolver{solution: Solution{Code: "123456", Confidence: 0.1, Cost: 0.002}},
+			MinConfidence: 0.5,
+		},
+		Link{Name: "2captcha", Solver: &fakeSolver{err: errors.New("boom")}},
+	)
+
+	solution, err := solver.Solve(context.Background(), image.Image{})
+	if !errors.Is(err, ErrNotSolved) || !errors.Is(err, ErrLowConfidence) {
+		t.Fatalf("expected ErrNotSolved wrapping ErrLowConfidence, got %v", err)
+	}
+
+	if solution.Cost != 0.002 || solution.Code != "" {
+		t.Errorf("expected only the cost 0.002 of the discarded answer, got %+v", solution)
+	}
+}
+
+func TestChain_KeepsReporter(t *testing.T) {
+	t.Parallel()
+
+	var reported []bool
+
+	solver := NewChain(Link{Name: "2captcha", Solver: &fakeSolver{solution: Solution{
+		Code:       "123456",
+		Confidence: 1,
+		Reporter: func(correct bool) error {
+			reported = append(reported, correct)
+
+			return nil
+		},
+	}}})
+
+	solution, err := solver.Solve(context.Background(), image.Image{})
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if err := solution.Report(false); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	if len(reported) != 1 || reported[0] {
+		t.Fatalf("expected one bad report, got %v", reported)
+	}
+
+	if err := (Solution{}).Report(true); err != nil {
+		t.Fatalf("report without reporter: %v", err)
+	}
+}
+
+func TestChain_SumsCost(t *testing.T) {
+	t.Parallel()
+
+	solver := NewChain(
+		Link{Name: "paid", Solver: &fakeSolver{solution: Solution{Code: "12a456", Cost: 0.002}}},
+		Link{Name: "2captcha", Solver: &fakeSolver{solution: Solution{Code: "123456", Confidence: 1, Cost: 0.001}}},
+	)
+
+	solution, err := solver.Solve(context.Background(), image.Image{})
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Cost != 0.003 {
+		t.Errorf("expected cost of both answers 0.003, got %v", solution.Cost)
+	}
+}
+
+func TestChain_Preprocess(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, goimage.NewRGBA(goimage.Rect(0, 0, 10, 5))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	src := image.NewPNG(buffer.Bytes())
+	raw := &fakeSolver{err: errors.New("boom")}
+	upscaled := &fakeSolver{solution: Solution{Code: "123456", Confidence: 1}}
+
+	solver := NewChain(
+		Link{Name: "ocr", Solver: raw},
+		Link{Name: "2captcha", Solver: upscaled, Preprocess: image.MustNewPipeline(image.StepUpscale)},
+	)
+
+	solution, err := solver.Solve(context.Background(), src)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+
+	if solution.Preprocessing != "upscale" {
+		t.Errorf("expected upscale preprocessing, got %s", solution.Preprocessing)
+	}
+
+	if !bytes.Equal(raw.seen.Bytes, src.Bytes) {
+		t.Error("expected raw captcha for link without preprocessing")
+	}
+
+	decoded, err := png.Decode(bytes.NewReader(upscaled.seen.Bytes))
+	if err != nil {
+		t.Fatalf("decode preprocessed: %v", err)
+	}
+
+	if bounds := decoded.Bounds(); bounds.Dx() != 20 || bounds.Dy() != 10 {
+		t.Errorf("expected upscaled 20x10 captcha, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+}
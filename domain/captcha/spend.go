@@ -0,0 +1,36 @@
+package captcha
+
+import (
+	"context"
+	"time"
+)
+
+type Spend struct {
+	Solves int
+	Cost   float64
+}
+
+func (s Spend) Add(other Spend) Spend {
+	return Spend{
+		Solves: s.Solves + other.Solves,
+		Cost:   s.Cost + other.Cost,
+	}
+}
+
+type Balance struct {
+	Amount    float64
+	CheckedAt time.Time
+}
+
+type BalanceChecker interface {
+	Balance(ctx context.Context) (float64, error)
+}
+
+// Ledger keeps how much solving cost every recipient day by day and the last
+// known balance of the paid solver.
+type Ledger interface {
+	Record(ctx context.Context, recipientID int64, at time.Time, spend Spend) error
+	Daily(ctx context.Context, day time.Time) (map[int64]Spend, error)
+	SaveBalance(ctx context.Context, balance Balance) error
+	Balance(ctx context.Context) (Balance, error)
+}
@@ -4,18 +4,87 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 	"github.com/truewebber/kdmid-queue-checker/domain/page"
 )
 
 type Result struct {
+	// ID stays the same across the retries of a crawl and the storages.
+	ID                   string
 	One, Two, Three      page.Stat
 	RanAt                time.Time
+	Proxy                string
+	CaptchaSolver        string
+	Attempts             []CaptchaAttempt
+	Retries              int
+	Durations            Durations
+	AppVersion           string
 	Err                  error
 	SomethingInteresting bool
 }
 
+// Durations of the steps of the last try, Total spans all the retries.
+type Durations struct {
+	Authorize    time.Duration
+	CaptchaSolve time.Duration
+	Submit       time.Duration
+	Calendar     time.Duration
+	Total        time.Duration
+}
+
+func NewID() string {
+	return uuid.NewString()
+}
+
+// SubmittedCode is the captcha code the crawl ended with, empty when no code
+// was sent.
+func (r *Result) SubmittedCode() string {
+	return submittedCode(r.Attempts)
+}
+
+func submittedCode(attempts []CaptchaAttempt) string {
+	if len(attempts) == 0 {
+		return ""
+	}
+
+	return attempts[len(attempts)-1].Code
+}
+
+type Verdict string
+
+const (
+	VerdictAccepted Verdict = "accepted"
+	VerdictRejected Verdict = "rejected"
+	// VerdictUnknown is used when the submission failed for a reason unrelated
+	// to the code, so it is not known whether the code was right.
+	VerdictUnknown Verdict = "unknown"
+)
+
+// CaptchaAttempt is a single captcha sent to kdmid, a crawl has one attempt
+// per retry caused by a rejected code.
+type CaptchaAttempt struct {
+	Image         image.Image
+	Code          string
+	Solver        string
+	Preprocessing string
+	Confidence    float64
+	Cost          float64
+	Verdict       Verdict
+}
+
 type Storage interface {
 	Save(ctx context.Context, userID int64, result *Result) error
 	ListUsers(context.Context) ([]int64, error)
 	ListResults(ctx context.Context, userID int64, date time.Time) ([]Result, error)
+	// Query lists crawl summaries, Artifact loads what a summary references.
+	Query(ctx context.Context, query Query) (Page, error)
+	Artifact(ctx context.Context, ref ArtifactRef) ([]byte, error)
+}
+
+// Migrator moves the crawls kept in an older layout to the current one and
+// tells how many it moved.
+type Migrator interface {
+	Migrate(ctx context.Context) (int, error)
 }
@@ -0,0 +1,110 @@
+package crawl
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusError       Status = "error"
+	StatusInteresting Status = "interesting"
+)
+
+// ParseStatus accepts only the statuses crawls can have.
+func ParseStatus(value string) (Status, error) {
+	switch status := Status(value); status {
+	case StatusOK, StatusError, StatusInteresting:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown status `%s`", value)
+	}
+}
+
+// Status of the crawl, a failed crawl is an error even if it found something.
+func (r *Result) Status() Status {
+	return statusOf(r.Err, r.SomethingInteresting)
+}
+
+func statusOf(err error, interesting bool) Status {
+	switch {
+	case err != nil:
+		return StatusError
+	case interesting:
+		return StatusInteresting
+	default:
+		return StatusOK
+	}
+}
+
+type Order int
+
+const (
+	NewestFirst Order = iota
+	OldestFirst
+)
+
+// Query selects crawls across users and days. From is inclusive and To is
+// exclusive, a zero bound leaves that side open. Empty UserIDs and Statuses
+// match everything, a zero Limit returns every crawl past Offset.
+type Query struct {
+	From, To time.Time
+	UserIDs  []int64
+	Statuses []Status
+	Order    Order
+	Offset   int
+	Limit    int
+}
+
+// Page is the slice of the crawls a query asked for, Total counts every
+// matching crawl regardless of the pagination.
+type Page struct {
+	Summaries []Summary
+	Total     int
+}
+
+func (q Query) HasUser(userID int64) bool {
+	return len(q.UserIDs) == 0 || slices.Contains(q.UserIDs, userID)
+}
+
+func (q Query) InRange(ranAt time.Time) bool {
+	if !q.From.IsZero() && ranAt.Before(q.From) {
+		return false
+	}
+
+	return q.To.IsZero() || ranAt.Before(q.To)
+}
+
+func (q Query) Match(summary *Summary) bool {
+	return q.HasUser(summary.UserID) && q.InRange(summary.RanAt) &&
+		(len(q.Statuses) == 0 || slices.Contains(q.Statuses, summary.Status()))
+}
+
+// Paginate orders the matched crawls and cuts the page out of them, for
+// storages that cannot do it themselves.
+func (q Query) Paginate(matched []Summary) Page {
+	sort.SliceStable(matched, func(i, j int) bool {
+		if q.Order == OldestFirst {
+			return matched[i].RanAt.Before(matched[j].RanAt)
+		}
+
+		return matched[i].RanAt.After(matched[j].RanAt)
+	})
+
+	page := Page{Total: len(matched)}
+
+	from := min(max(q.Offset, 0), len(matched))
+	to := len(matched)
+
+	if q.Limit > 0 {
+		to = min(from+q.Limit, len(matched))
+	}
+
+	page.Summaries = matched[from:to]
+
+	return page
+}
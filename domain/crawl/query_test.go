@@ -0,0 +1,105 @@
+package crawl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuery_Paginate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	at := func(hours int) Summary {
+		return Summary{UserID: 42, RanAt: start.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []int
+	}{
+		{name: "NewestFirst", query: Query{}, expected: []int{3, 2, 1, 0}},
+		{name: "OldestFirst", query: Query{Order: OldestFirst}, expected: []int{0, 1, 2, 3}},
+		{name: "Limit", query: Query{Limit: 2}, expected: []int{3, 2}},
+		{name: "Offset", query: Query{Offset: 1, Limit: 2}, expected: []int{2, 1}},
+		{name: "PastTheEnd", query: Query{Offset: 10, Limit: 2}, expected: []int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			page := test.query.Paginate([]Summary{at(1), at(3), at(0), at(2)})
+
+			if page.Total != 4 {
+				t.Errorf("expected total 4, got %d", page.Total)
+			}
+
+			got := make([]int, 0, len(page.Summaries))
+			for _, result := range page.Summaries {
+				got = append(got, int(result.RanAt.Sub(start)/time.Hour))
+			}
+
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	query := Query{
+		From:     day,
+		To:       day.AddDate(0, 0, 1),
+		UserIDs:  []int64{42},
+		Statuses: []Status{StatusError, StatusInteresting},
+	}
+
+	tests := []struct {
+		name     string
+		summary  Summary
+		expected bool
+	}{
+		{name: "Error", summary: Summary{UserID: 42, RanAt: day, Err: errors.New("timeout")}, expected: true},
+		{name: "Interesting", summary: Summary{UserID: 42, RanAt: day, SomethingInteresting: true}, expected: true},
+		{name: "OK", summary: Summary{UserID: 42, RanAt: day}, expected: false},
+		{name: "OtherUser", summary: Summary{UserID: 7, RanAt: day, SomethingInteresting: true}, expected: false},
+		{
+			name:     "NextDay",
+			summary:  Summary{UserID: 42, RanAt: day.AddDate(0, 0, 1), SomethingInteresting: true},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := query.Match(&test.summary); got != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []Status{StatusOK, StatusError, StatusInteresting} {
+		got, err := ParseStatus(string(status))
+		if err != nil || got != status {
+			t.Errorf("expected %s, got %s, %v", status, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "failed", "OK"} {
+		if _, err := ParseStatus(value); err == nil {
+			t.Errorf("expected an error for `%s`", value)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package crawl
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Footprint is what a stored crawl costs on disk, Ref is whatever the storage
+// needs to find the crawl again when it is removed.
+type Footprint struct {
+	Ref    string
+	UserID int64
+	RanAt  time.Time
+	// Notable crawls found something interesting or failed.
+	Notable bool
+	Bytes   int64
+}
+
+// Pruner removes the crawls it is given together, so storages packing several
+// crawls in one file rewrite it once.
+type Pruner interface {
+	Footprints(ctx context.Context) ([]Footprint, error)
+	Remove(ctx context.Context, footprints []Footprint) error
+}
+
+// Archiver packs the crawls that ran before the given time into compressed
+// archives which the storage still reads from.
+type Archiver interface {
+	Archive(ctx context.Context, before time.Time) (int, error)
+}
+
+// Retention keeps ordinary crawls for KeepDays and notable ones for
+// KeepNotableDays but never less than KeepDays, a zero keeps them forever.
+// When MaxBytes is set the oldest ordinary crawls are removed until the rest
+// fits, notable crawls are never removed to save space. Crawls older than
+// ArchiveDays are archived.
+type Retention struct {
+	KeepDays        int
+	KeepNotableDays int
+	MaxBytes        int64
+	ArchiveDays     int
+}
+
+type ExpiryReason string
+
+const (
+	ExpiryAge  ExpiryReason = "age"
+	ExpirySize ExpiryReason = "size"
+)
+
+type Expired struct {
+	Footprint Footprint
+	Reason    ExpiryReason
+}
+
+func (r Retention) Enabled() bool {
+	return r.KeepDays > 0 || r.KeepNotableDays > 0 || r.MaxBytes > 0 || r.ArchiveDays > 0
+}
+
+// Expired picks the crawls to remove, oldest first within each reason.
+func (r Retention) Expired(footprints []Footprint, now time.Time) []Expired {
+	sorted := append([]Footprint(nil), footprints...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].RanAt.Before(sorted[j].RanAt) })
+
+	expired := make([]Expired, 0)
+	kept := make([]Footprint, 0, len(sorted))
+
+	var keptBytes int64
+
+	for _, footprint := range sorted {
+		if r.tooOld(footprint, now) {
+			expired = append(expired, Expired{Footprint: footprint, Reason: ExpiryAge})
+
+			continue
+		}
+
+		kept = append(kept, footprint)
+		keptBytes += footprint.Bytes
+	}
+
+	if r.MaxBytes <= 0 {
+		return expired
+	}
+
+	for _, footprint := range kept {
+		if keptBytes <= r.MaxBytes {
+			break
+		}
+
+		if footprint.Notable {
+			continue
+		}
+
+		expired = append(expired, Expired{Footprint: footprint, Reason: ExpirySize})
+		keptBytes -= footprint.Bytes
+	}
+
+	return expired
+}
+
+func (r Retention) tooOld(footprint Footprint, now time.Time) bool {
+	keepDays := r.KeepDays
+	if footprint.Notable {
+		keepDays = r.KeepNotableDays
+
+		// Notable crawls never go before the ordinary ones.
+		if r.KeepDays > 0 && keepDays > 0 {
+			keepDays = max(r.KeepDays, keepDays)
+		}
+	}
+
+	if keepDays <= 0 {
+		return false
+	}
+
+	return footprint.RanAt.Before(now.AddDate(0, 0, -keepDays))
+}
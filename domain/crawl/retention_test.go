@@ -0,0 +1,81 @@
+package crawl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetention_Expired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	footprints := []Footprint{
+		{Ref: "fresh", RanAt: daysAgo(1), Bytes: 10},
+		{Ref: "old", RanAt: daysAgo(20), Bytes: 10},
+		{Ref: "old-notable", RanAt: daysAgo(20), Notable: true, Bytes: 10},
+		{Ref: "ancient-notable", RanAt: daysAgo(100), Notable: true, Bytes: 10},
+		{Ref: "recent", RanAt: daysAgo(5), Bytes: 10},
+		{Ref: "recent-notable", RanAt: daysAgo(6), Notable: true, Bytes: 100},
+	}
+
+	tests := []struct {
+		name      string
+		retention Retention
+		expected  map[string]ExpiryReason
+	}{
+		{
+			name:      "Disabled",
+			retention: Retention{},
+			expected:  map[string]ExpiryReason{},
+		},
+		{
+			name:      "Age",
+			retention: Retention{KeepDays: 14, KeepNotableDays: 90},
+			expected:  map[string]ExpiryReason{"old": ExpiryAge, "ancient-notable": ExpiryAge},
+		},
+		{
+			name:      "Notable Kept Forever",
+			retention: Retention{KeepDays: 14},
+			expected:  map[string]ExpiryReason{"old": ExpiryAge},
+		},
+		{
+			name:      "Notable Kept At Least As Long As Ordinary",
+			retention: Retention{KeepDays: 30, KeepNotableDays: 7},
+			expected:  map[string]ExpiryReason{"ancient-notable": ExpiryAge},
+		},
+		{
+			name:      "Size Removes Oldest Ordinary",
+			retention: Retention{KeepDays: 14, KeepNotableDays: 90, MaxBytes: 125},
+			expected: map[string]ExpiryReason{
+				"old": ExpiryAge, "ancient-notable": ExpiryAge, "recent": ExpirySize,
+			},
+		},
+		{
+			name:      "Size Never Removes Notable",
+			retention: Retention{MaxBytes: 1},
+			expected: map[string]ExpiryReason{
+				"old": ExpirySize, "recent": ExpirySize, "fresh": ExpirySize,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := make(map[string]ExpiryReason)
+			for _, expired := range tt.retention.Expired(footprints, now) {
+				got[expired.Footprint.Ref] = expired.Reason
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package crawl
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrArtifactNotFound = errors.New("artifact not found")
+	// ErrInvalidArtifactRef is returned for references no crawl could have.
+	ErrInvalidArtifactRef = errors.New("invalid artifact reference")
+)
+
+type ArtifactKind string
+
+const (
+	ArtifactHTML           ArtifactKind = "html"
+	ArtifactNetwork        ArtifactKind = "network"
+	ArtifactScreenshot     ArtifactKind = "screenshot"
+	ArtifactCaptcha        ArtifactKind = "captcha"
+	ArtifactAttemptCaptcha ArtifactKind = "attempt_captcha"
+)
+
+// ArtifactRef points at an artifact without loading it. Index is the step,
+// 1 to 3, or the attempt number for attempt captchas.
+type ArtifactRef struct {
+	Crawl    string
+	Kind     ArtifactKind
+	Index    int
+	MIMEType string
+}
+
+// Summary is a crawl without its artifacts, Ref is how the storage finds the
+// crawl again. Attempts come without images, they are among the Artifacts.
+type Summary struct {
+	Ref                  string
+	UserID               int64
+	ID                   string
+	RanAt                time.Time
+	Proxy                string
+	CaptchaSolver        string
+	Attempts             []CaptchaAttempt
+	Retries              int
+	Durations            Durations
+	AppVersion           string
+	Err                  error
+	SomethingInteresting bool
+	Artifacts            []ArtifactRef
+}
+
+// NewSummary keeps the metadata of the result, artifacts are left to the
+// storage to reference.
+func NewSummary(ref string, userID int64, result *Result) Summary {
+	var attempts []CaptchaAttempt
+
+	for _, attempt := range result.Attempts {
+		attempt.Image.Bytes = nil
+		attempts = append(attempts, attempt)
+	}
+
+	return Summary{
+		Ref:                  ref,
+		UserID:               userID,
+		ID:                   result.ID,
+		RanAt:                result.RanAt,
+		Proxy:                result.Proxy,
+		CaptchaSolver:        result.CaptchaSolver,
+		Attempts:             attempts,
+		Retries:              result.Retries,
+		Durations:            result.Durations,
+		AppVersion:           result.AppVersion,
+		Err:                  result.Err,
+		SomethingInteresting: result.SomethingInteresting,
+	}
+}
+
+func (s *Summary) Status() Status {
+	return statusOf(s.Err, s.SomethingInteresting)
+}
+
+func (s *Summary) SubmittedCode() string {
+	return submittedCode(s.Attempts)
+}
+
+// Artifact finds the reference of an artifact, false when the crawl has none.
+func (s *Summary) Artifact(kind ArtifactKind, index int) (ArtifactRef, bool) {
+	for _, ref := range s.Artifacts {
+		if ref.Kind == kind && ref.Index == index {
+			return ref, true
+		}
+	}
+
+	return ArtifactRef{}, false
+}
@@ -0,0 +1,182 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"slices"
+)
+
+const (
+	minGroupGapRatio  = 0.6
+	minGroupGap       = 4
+	minRegionSize     = 8
+	minGroupMassRatio = 4
+)
+
+// CropCaptcha crops the detected digits, or the fallback when none are found.
+func CropCaptcha(srcImage Image, fallback CroppingFunc) (Image, error) {
+	decodedImage, _, err := image.Decode(bytes.NewReader(srcImage.Bytes))
+	if err != nil {
+		return Image{}, fmt.Errorf("could not decode source image: %w", err)
+	}
+
+	rect, ok := DetectCaptchaRegion(decodedImage)
+	if !ok {
+		rect = fallback(decodedImage.Bounds().Dy(), decodedImage.Bounds().Dx())
+	}
+
+	return crop(decodedImage, rect)
+}
+
+// DetectCaptchaRegion finds the digit group nearest to the centre, between the decoys.
+func DetectCaptchaRegion(img image.Image) (CroppingRect, bool) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width < minRegionSize || height < minRegionSize {
+		return CroppingRect{}, false
+	}
+
+	mask := InkMask(img)
+
+	band, ok := mask.textBand()
+	if !ok {
+		return CroppingRect{}, false
+	}
+
+	columns := mask.Columns(band.from, band.to)
+	splitGap := max(minGroupGap, int(float64(band.to-band.from)*minGroupGapRatio))
+
+	group, ok := centralGroup(columns, max(2, slices.Max(columns)/8), splitGap)
+	if !ok {
+		return CroppingRect{}, false
+	}
+
+	textRows, ok := heaviestRun(mask.Rows(group.from, group.to), max(2, (group.to-group.from)/50))
+	if !ok {
+		return CroppingRect{}, false
+	}
+
+	y0, y1 := textRows.from, textRows.to
+
+	if group.to-group.from < minRegionSize || y1-y0 < minRegionSize {
+		return CroppingRect{}, false
+	}
+
+	padding := max(minRegionSize/2, (y1-y0)/5)
+
+	return CroppingRect{
+		X0: bounds.Min.X + max(0, group.from-padding),
+		Y0: bounds.Min.Y + max(0, y0-padding),
+		X1: bounds.Min.X + min(width, group.to+padding),
+		Y1: bounds.Min.Y + min(height, y1+padding),
+	}, true
+}
+
+type run struct {
+	from, to int
+}
+
+func activeRuns(projection []int, threshold int) []run {
+	var (
+		runs    []run
+		current *run
+	)
+
+	for i, count := range projection {
+		if count < threshold {
+			current = nil
+
+			continue
+		}
+
+		if current == nil {
+			runs = append(runs, run{from: i})
+			current = &runs[len(runs)-1]
+		}
+
+		current.to = i + 1
+	}
+
+	return runs
+}
+
+func heaviestRun(projection []int, threshold int) (run, bool) {
+	var (
+		best     run
+		bestMass int
+	)
+
+	for _, r := range activeRuns(projection, threshold) {
+		if runMass := mass(projection, r); runMass > bestMass {
+			best, bestMass = r, runMass
+		}
+	}
+
+	return best, bestMass > 0
+}
+
+func centralGroup(projection []int, threshold, splitGap int) (run, bool) {
+	runs := activeRuns(projection, threshold)
+	if len(runs) == 0 {
+		return run{}, false
+	}
+
+	groups := []run{runs[0]}
+	for _, next := range runs[1:] {
+		if next.from-groups[len(groups)-1].to > splitGap {
+			groups = append(groups, next)
+
+			continue
+		}
+
+		groups[len(groups)-1].to = next.to
+	}
+
+	masses := make([]int, len(groups))
+	for i, group := range groups {
+		masses[i] = mass(projection, group)
+	}
+
+	center := len(projection) / 2
+	minMass := slices.Max(masses) / minGroupMassRatio
+
+	var (
+		best  run
+		found bool
+	)
+
+	for i, group := range groups {
+		if masses[i] < minMass {
+			continue
+		}
+
+		if !found || distance(group, center) < distance(best, center) {
+			best, found = group, true
+		}
+	}
+
+	return best, found
+}
+
+func mass(projection []int, r run) int {
+	total := 0
+	for _, count := range projection[r.from:r.to] {
+		total += count
+	}
+
+	return total
+}
+
+func distance(r run, point int) int {
+	return abs((r.from+r.to)/2 - point)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,163 @@
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type captchaSample struct {
+	Code   string `json:"code"`
+	Digits [4]int `json:"digits"`
+}
+
+func loadCaptchaCorpus(t *testing.T) map[string]captchaSample {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "captcha", "expected.json"))
+	if err != nil {
+		t.Fatalf("read expected: %v", err)
+	}
+
+	corpus := make(map[string]captchaSample)
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatalf("unmarshal expected: %v", err)
+	}
+
+	return corpus
+}
+
+func loadCaptchaImage(t *testing.T, name string) Image {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "captcha", name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+
+	return Detect(data)
+}
+
+func decodeImage(t *testing.T, img Image) image.Image {
+	t.Helper()
+
+	decoded, _, err := image.Decode(bytes.NewReader(img.Bytes))
+	if err != nil {
+		t.Fatalf("decode image: %v", err)
+	}
+
+	return decoded
+}
+
+func TestDetectCaptchaRegion_Corpus(t *testing.T) {
+	t.Parallel()
+
+	const maxMargin = 16
+
+	for name, sample := range loadCaptchaCorpus(t) {
+		name, sample := name, sample
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rect, ok := DetectCaptchaRegion(decodeImage(t, loadCaptchaImage(t, name)))
+			if !ok {
+				t.Fatal("expected captcha region to be detected")
+			}
+
+			x0, y0, x1, y1 := sample.Digits[0], sample.Digits[1], sample.Digits[2], sample.Digits[3]
+
+			if rect.X0 > x0 || rect.Y0 > y0 || rect.X1 < x1 || rect.Y1 < y1 {
+				t.Fatalf("region %+v does not contain digits %v", rect, sample.Digits)
+			}
+
+			if x0-rect.X0 > maxMargin || y0-rect.Y0 > maxMargin || rect.X1-x1 > maxMargin || rect.Y1-y1 > maxMargin {
+				t.Errorf("region %+v is too loose around digits %v", rect, sample.Digits)
+			}
+		})
+	}
+}
+
+func TestCropCaptcha_Fallback(t *testing.T) {
+	t.Parallel()
+
+	blank := image.NewRGBA(image.Rect(0, 0, 300, 60))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 60; y++ {
+			blank.Set(x, y, color.White)
+		}
+	}
+
+	var fallbackCalled bool
+
+	cropped, err := CropCaptcha(encodePNG(t, blank), func(height, width int) CroppingRect {
+		fallbackCalled = true
+
+		return CroppingRect{X0: width / 3, Y0: 0, X1: width / 3 * 2, Y1: height}
+	})
+	if err != nil {
+		t.Fatalf("crop captcha: %v", err)
+	}
+
+	if !fallbackCalled {
+		t.Fatal("expected fallback to be used for blank image")
+	}
+
+	bounds := decodeImage(t, cropped).Bounds()
+	if bounds.Dx() != 100 || bounds.Dy() != 60 {
+		t.Errorf("expected 100x60 crop, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestCropCaptcha_Detected(t *testing.T) {
+	t.Parallel()
+
+	cropped, err := CropCaptcha(loadCaptchaImage(t, "element_06.png"), func(int, int) CroppingRect {
+		t.Fatal("fallback must not be used when digits are found")
+
+		return CroppingRect{}
+	})
+	if err != nil {
+		t.Fatalf("crop captcha: %v", err)
+	}
+
+	if cropped.MIMEType != MIMETypePNG || cropped.Empty() {
+		t.Fatalf("expected png crop, got %q", cropped.MIMEType)
+	}
+}
+
+func TestSegmentDigits_Corpus(t *testing.T) {
+	t.Parallel()
+
+	for name, sample := range loadCaptchaCorpus(t) {
+		name, sample := name, sample
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			img := decodeImage(t, loadCaptchaImage(t, name))
+
+			rect, ok := DetectCaptchaRegion(img)
+			if !ok {
+				t.Fatal("expected captcha region to be detected")
+			}
+
+			mask := InkMask(img).Crop(image.Rect(rect.X0, rect.Y0, rect.X1, rect.Y1))
+
+			digits, err := SegmentDigits(mask, len(sample.Code))
+			if err != nil {
+				t.Fatalf("segment digits: %v", err)
+			}
+
+			for i, digit := range digits {
+				if digit.Width == 0 || digit.Height == 0 {
+					t.Errorf("digit %d is empty", i)
+				}
+			}
+		})
+	}
+}
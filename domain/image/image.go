@@ -3,27 +3,74 @@ package image
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 
 	"image"
 	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
 	"image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MIMETypePNG  = "image/png"
+	MIMETypeJPEG = "image/jpeg"
+	MIMETypeWebP = "image/webp"
 )
 
 type (
-	PNG          []byte
+	Image struct {
+		Bytes    []byte
+		MIMEType string
+	}
 	CroppingRect struct {
 		X0, Y0, X1, Y1 int
 	}
-	CroppingFunc func(height, width int) CroppingRect
+	CroppingFunc  func(height, width int) CroppingRect
+	EncodeOptions struct {
+		MIMEType            string
+		Quality             int
+		MaxWidth, MaxHeight int
+	}
 )
 
-func Crop(pngImage PNG, cropFunc CroppingFunc) (PNG, error) {
-	srcImage, _, err := image.Decode(bytes.NewReader(pngImage))
+func NewPNG(pngBytes []byte) Image {
+	return Image{Bytes: pngBytes, MIMEType: MIMETypePNG}
+}
+
+func Detect(imageBytes []byte) Image {
+	return Image{Bytes: imageBytes, MIMEType: http.DetectContentType(imageBytes)}
+}
+
+func (i Image) Empty() bool {
+	return len(i.Bytes) == 0
+}
+
+func (i Image) Extension() string {
+	switch i.MIMEType {
+	case MIMETypeJPEG:
+		return "jpg"
+	case MIMETypeWebP:
+		return "webp"
+	default:
+		return "png"
+	}
+}
+
+func Crop(srcImage Image, cropFunc CroppingFunc) (Image, error) {
+	decodedImage, _, err := image.Decode(bytes.NewReader(srcImage.Bytes))
 	if err != nil {
-		return nil, fmt.Errorf("could not decode source image: %w", err)
+		return Image{}, fmt.Errorf("could not decode source image: %w", err)
 	}
 
-	rectToCrop := cropFunc(srcImage.Bounds().Dy(), srcImage.Bounds().Dx())
+	return crop(decodedImage, cropFunc(decodedImage.Bounds().Dy(), decodedImage.Bounds().Dx()))
+}
+
+func crop(srcImage image.Image, rectToCrop CroppingRect) (Image, error) {
 	rect := image.Rect(rectToCrop.X0, rectToCrop.Y0, rectToCrop.X1, rectToCrop.Y1)
 
 	croppedImage := image.NewRGBA(rect)
@@ -31,10 +78,94 @@ func Crop(pngImage PNG, cropFunc CroppingFunc) (PNG, error) {
 
 	var buffer bytes.Buffer
 
-	err = png.Encode(&buffer, croppedImage)
+	err := png.Encode(&buffer, croppedImage)
 	if err != nil {
-		return nil, fmt.Errorf("could not encode output image: %w", err)
+		return Image{}, fmt.Errorf("could not encode output image: %w", err)
+	}
+
+	return NewPNG(buffer.Bytes()), nil
+}
+
+// Validate rejects a quality for lossless formats, WebP included.
+func (o EncodeOptions) Validate() error {
+	switch o.MIMEType {
+	case "", MIMETypePNG, MIMETypeWebP:
+		if o.Quality != 0 {
+			return fmt.Errorf("quality is not supported for `%s`, it is encoded lossless", o.MIMEType)
+		}
+	case MIMETypeJPEG:
+		if o.Quality < 0 || o.Quality > 100 {
+			return fmt.Errorf("quality %d is out of 1..100", o.Quality)
+		}
+	default:
+		return fmt.Errorf("unsupported mime type `%s`", o.MIMEType)
+	}
+
+	return nil
+}
+
+// Encode converts the image, a zero JPEG quality is the encoder default.
+func Encode(srcImage Image, options EncodeOptions) (Image, error) {
+	if options.MIMEType == "" {
+		options.MIMEType = MIMETypePNG
 	}
 
-	return buffer.Bytes(), nil
+	if options.MIMEType == srcImage.MIMEType && options.MaxWidth <= 0 && options.MaxHeight <= 0 {
+		return srcImage, nil
+	}
+
+	decodedImage, _, err := image.Decode(bytes.NewReader(srcImage.Bytes))
+	if err != nil {
+		return Image{}, fmt.Errorf("could not decode source image: %w", err)
+	}
+
+	decodedImage = fit(decodedImage, options.MaxWidth, options.MaxHeight)
+
+	var buffer bytes.Buffer
+
+	switch options.MIMEType {
+	case MIMETypePNG:
+		err = png.Encode(&buffer, decodedImage)
+	case MIMETypeJPEG:
+		quality := options.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+
+		err = jpeg.Encode(&buffer, decodedImage, &jpeg.Options{Quality: quality})
+	case MIMETypeWebP:
+		err = nativewebp.Encode(&buffer, decodedImage, nil)
+	default:
+		return Image{}, fmt.Errorf("unsupported mime type `%s`", options.MIMEType)
+	}
+
+	if err != nil {
+		return Image{}, fmt.Errorf("could not encode output image: %w", err)
+	}
+
+	return Image{Bytes: buffer.Bytes(), MIMEType: options.MIMEType}, nil
+}
+
+func fit(srcImage image.Image, maxWidth, maxHeight int) image.Image {
+	width, height := srcImage.Bounds().Dx(), srcImage.Bounds().Dy()
+	scale := 1.0
+
+	if maxWidth > 0 && width > maxWidth {
+		scale = min(scale, float64(maxWidth)/float64(width))
+	}
+
+	if maxHeight > 0 && height > maxHeight {
+		scale = min(scale, float64(maxHeight)/float64(height))
+	}
+
+	if scale == 1.0 {
+		return srcImage
+	}
+
+	dstRect := image.Rect(0, 0, max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale)))
+	dstImage := image.NewRGBA(dstRect)
+
+	xdraw.ApproxBiLinear.Scale(dstImage, dstRect, srcImage, srcImage.Bounds(), xdraw.Src, nil)
+
+	return dstImage
 }
@@ -0,0 +1,122 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func newTestPNG(t *testing.T, width, height int) Image {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	return encodePNG(t, img)
+}
+
+func encodePNG(t *testing.T, img image.Image) Image {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	return NewPNG(buffer.Bytes())
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	src := newTestPNG(t, 200, 100)
+
+	tests := []struct {
+		name          string
+		options       EncodeOptions
+		wantMIMEType  string
+		wantExtension string
+		wantWidth     int
+		wantHeight    int
+	}{
+		{name: "Same Format", options: EncodeOptions{MIMEType: MIMETypePNG}, wantMIMEType: MIMETypePNG, wantExtension: "png", wantWidth: 200, wantHeight: 100},
+		{name: "JPEG", options: EncodeOptions{MIMEType: MIMETypeJPEG, Quality: 50}, wantMIMEType: MIMETypeJPEG, wantExtension: "jpg", wantWidth: 200, wantHeight: 100},
+		{name: "WebP", options: EncodeOptions{MIMEType: MIMETypeWebP}, wantMIMEType: MIMETypeWebP, wantExtension: "webp", wantWidth: 200, wantHeight: 100},
+		{name: "Max Width", options: EncodeOptions{MaxWidth: 100}, wantMIMEType: MIMETypePNG, wantExtension: "png", wantWidth: 100, wantHeight: 50},
+		{name: "Max Height", options: EncodeOptions{MIMEType: MIMETypeJPEG, MaxHeight: 20}, wantMIMEType: MIMETypeJPEG, wantExtension: "jpg", wantWidth: 40, wantHeight: 20},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			encoded, err := Encode(src, tt.options)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			if encoded.MIMEType != tt.wantMIMEType || Detect(encoded.Bytes).MIMEType != tt.wantMIMEType {
+				t.Errorf("expected mime type %s, got %s", tt.wantMIMEType, encoded.MIMEType)
+			}
+
+			if encoded.Extension() != tt.wantExtension {
+				t.Errorf("expected extension %s, got %s", tt.wantExtension, encoded.Extension())
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(encoded.Bytes))
+			if err != nil {
+				t.Fatalf("decode config: %v", err)
+			}
+
+			if config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("expected %dx%d, got %dx%d", tt.wantWidth, tt.wantHeight, config.Width, config.Height)
+			}
+		})
+	}
+}
+
+func TestEncode_UnsupportedMIMEType(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Encode(newTestPNG(t, 10, 10), EncodeOptions{MIMEType: "image/bmp"}); err == nil {
+		t.Fatal("expected error for unsupported mime type")
+	}
+}
+
+func TestEncodeOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options EncodeOptions
+		wantErr bool
+	}{
+		{name: "Default", options: EncodeOptions{}, wantErr: false},
+		{name: "JPEG Quality", options: EncodeOptions{MIMEType: MIMETypeJPEG, Quality: 60}, wantErr: false},
+		{name: "JPEG Quality Out Of Range", options: EncodeOptions{MIMEType: MIMETypeJPEG, Quality: 120}, wantErr: true},
+		{name: "WebP", options: EncodeOptions{MIMEType: MIMETypeWebP}, wantErr: false},
+		{name: "WebP Quality", options: EncodeOptions{MIMEType: MIMETypeWebP, Quality: 80}, wantErr: true},
+		{name: "PNG Quality", options: EncodeOptions{MIMEType: MIMETypePNG, Quality: 80}, wantErr: true},
+		{name: "Unsupported", options: EncodeOptions{MIMEType: "image/bmp"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package image
+
+import (
+	"image"
+	"slices"
+)
+
+const (
+	inkThreshold     = 64
+	minInkNeighbours = 4
+)
+
+// Mask marks ink pixels of an image, row by row.
+type Mask struct {
+	Width, Height int
+	Ink           []bool
+}
+
+// InkMask separates ink from the background, taken as the median luma of
+// the image border, and drops dots and one pixel wide lines, which have fewer
+// ink neighbours than pixels of digit strokes.
+func InkMask(img image.Image) Mask {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width == 0 || height == 0 {
+		return Mask{}
+	}
+
+	luma := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			luma[y*width+x] = int((299*r + 587*g + 114*b) / 1000 >> 8)
+		}
+	}
+
+	border := make([]int, 0, 2*(width+height))
+	for x := 0; x < width; x++ {
+		border = append(border, luma[x], luma[(height-1)*width+x])
+	}
+
+	for y := 0; y < height; y++ {
+		border = append(border, luma[y*width], luma[y*width+width-1])
+	}
+
+	slices.Sort(border)
+	background := border[len(border)/2]
+
+	ink := make([]bool, len(luma))
+	for i, l := range luma {
+		ink[i] = abs(l-background) > inkThreshold
+	}
+
+	return Mask{Width: width, Height: height, Ink: removeThinNoise(ink, width, height)}
+}
+
+func removeThinNoise(ink []bool, width, height int) []bool {
+	filtered := make([]bool, len(ink))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !ink[y*width+x] {
+				continue
+			}
+
+			neighbours := 0
+
+			for ny := max(0, y-1); ny <= min(height-1, y+1); ny++ {
+				for nx := max(0, x-1); nx <= min(width-1, x+1); nx++ {
+					if (nx != x || ny != y) && ink[ny*width+nx] {
+						neighbours++
+					}
+				}
+			}
+
+			filtered[y*width+x] = neighbours >= minInkNeighbours
+		}
+	}
+
+	return filtered
+}
+
+func (m Mask) At(x, y int) bool {
+	return x >= 0 && y >= 0 && x < m.Width && y < m.Height && m.Ink[y*m.Width+x]
+}
+
+// Columns counts ink pixels of every column between rows y0 and y1.
+func (m Mask) Columns(y0, y1 int) []int {
+	columns := make([]int, m.Width)
+
+	for y := y0; y < y1; y++ {
+		for x := 0; x < m.Width; x++ {
+			if m.Ink[y*m.Width+x] {
+				columns[x]++
+			}
+		}
+	}
+
+	return columns
+}
+
+// Rows counts ink pixels of every row between columns x0 and x1.
+func (m Mask) Rows(x0, x1 int) []int {
+	rows := make([]int, m.Height)
+
+	for y := range rows {
+		for x := x0; x < x1; x++ {
+			if m.Ink[y*m.Width+x] {
+				rows[y]++
+			}
+		}
+	}
+
+	return rows
+}
+
+func (m Mask) Crop(r image.Rectangle) Mask {
+	r = r.Intersect(image.Rect(0, 0, m.Width, m.Height))
+
+	cropped := Mask{Width: r.Dx(), Height: r.Dy(), Ink: make([]bool, r.Dx()*r.Dy())}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		copy(cropped.Ink[(y-r.Min.Y)*cropped.Width:], m.Ink[y*m.Width+r.Min.X:y*m.Width+r.Max.X])
+	}
+
+	return cropped
+}
+
+// Bounds is the smallest rectangle holding all ink of the mask.
+func (m Mask) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			if m.Ink[y*m.Width+x] {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return bounds
+}
+
+// Density splits the mask into a cols×rows grid and returns the share of ink
+// in every cell, row by row.
+func (m Mask) Density(cols, rows int) []float64 {
+	density := make([]float64, cols*rows)
+
+	if m.Width == 0 || m.Height == 0 {
+		return density
+	}
+
+	for cy := 0; cy < rows; cy++ {
+		y0, y1 := cy*m.Height/rows, max(cy*m.Height/rows+1, (cy+1)*m.Height/rows)
+
+		for cx := 0; cx < cols; cx++ {
+			x0, x1 := cx*m.Width/cols, max(cx*m.Width/cols+1, (cx+1)*m.Width/cols)
+
+			ink, total := 0, 0
+
+			for y := y0; y < min(y1, m.Height); y++ {
+				for x := x0; x < min(x1, m.Width); x++ {
+					total++
+
+					if m.Ink[y*m.Width+x] {
+						ink++
+					}
+				}
+			}
+
+			if total != 0 {
+				density[cy*cols+cx] = float64(ink) / float64(total)
+			}
+		}
+	}
+
+	return density
+}
+
+func (m Mask) textBand() (run, bool) {
+	return heaviestRun(m.Rows(0, m.Width), max(2, m.Width/100))
+}
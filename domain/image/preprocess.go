@@ -0,0 +1,185 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sort"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	StepGrayscale = "grayscale"
+	StepContrast  = "contrast"
+	StepDenoise   = "denoise"
+	StepUpscale   = "upscale"
+)
+
+type preprocessStep func(img *image.Gray) *image.Gray
+
+// Pipeline is a named chain of preprocessing steps run on a captcha before it
+// is handed to a solver. Every step works on a grayscale image, so grayscale
+// is implied by any non-empty pipeline.
+type Pipeline struct {
+	names []string
+	steps []preprocessStep
+}
+
+var preprocessSteps = map[string]preprocessStep{
+	StepGrayscale: func(img *image.Gray) *image.Gray { return img },
+	StepContrast:  stretchContrast,
+	StepDenoise:   removeNoiseLines,
+	StepUpscale:   upscale,
+}
+
+func NewPipeline(names ...string) (Pipeline, error) {
+	pipeline := Pipeline{}
+
+	for _, name := range names {
+		step, ok := preprocessSteps[name]
+		if !ok {
+			return Pipeline{}, fmt.Errorf("unknown preprocessing step `%s`", name)
+		}
+
+		pipeline.names = append(pipeline.names, name)
+		pipeline.steps = append(pipeline.steps, step)
+	}
+
+	return pipeline, nil
+}
+
+func MustNewPipeline(names ...string) Pipeline {
+	pipeline, err := NewPipeline(names...)
+	if err != nil {
+		panic(err)
+	}
+
+	return pipeline
+}
+
+func (p Pipeline) Empty() bool {
+	return len(p.steps) == 0
+}
+
+// String names the pipeline the way it is configured, e.g. contrast+denoise,
+// an empty pipeline is called raw.
+func (p Pipeline) String() string {
+	if p.Empty() {
+		return "raw"
+	}
+
+	return strings.Join(p.names, "+")
+}
+
+func (p Pipeline) Apply(srcImage Image) (Image, error) {
+	if p.Empty() {
+		return srcImage, nil
+	}
+
+	decodedImage, _, err := image.Decode(bytes.NewReader(srcImage.Bytes))
+	if err != nil {
+		return Image{}, fmt.Errorf("could not decode source image: %w", err)
+	}
+
+	grayImage := grayscale(decodedImage)
+
+	for _, step := range p.steps {
+		grayImage = step(grayImage)
+	}
+
+	var buffer bytes.Buffer
+
+	if err := png.Encode(&buffer, grayImage); err != nil {
+		return Image{}, fmt.Errorf("could not encode output image: %w", err)
+	}
+
+	return NewPNG(buffer.Bytes()), nil
+}
+
+func grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	grayImage := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			grayImage.Set(x, y, color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+
+	return grayImage
+}
+
+// stretchContrast maps the 1st..99th percentile of brightness onto the full
+// range, so pale digits become black and a tinted background becomes white.
+func stretchContrast(img *image.Gray) *image.Gray {
+	const clipPercent = 100
+
+	if len(img.Pix) == 0 {
+		return img
+	}
+
+	sorted := append([]uint8(nil), img.Pix...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	low := int(sorted[len(sorted)/clipPercent])
+	high := int(sorted[len(sorted)-1-len(sorted)/clipPercent])
+
+	if high <= low {
+		return img
+	}
+
+	stretched := image.NewGray(img.Rect)
+
+	for i, value := range img.Pix {
+		level := (int(value) - low) * 255 / (high - low)
+		stretched.Pix[i] = uint8(min(255, max(0, level)))
+	}
+
+	return stretched
+}
+
+// removeNoiseLines runs a 3x3 median filter, it wipes the one pixel wide
+// lines drawn across kdmid captchas while keeping the thick digit strokes.
+func removeNoiseLines(img *image.Gray) *image.Gray {
+	bounds := img.Rect
+	filtered := image.NewGray(bounds)
+	window := make([]uint8, 0, 9)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			window = window[:0]
+
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					point := image.Pt(x+dx, y+dy)
+					if point.In(bounds) {
+						window = append(window, img.GrayAt(point.X, point.Y).Y)
+					}
+				}
+			}
+
+			sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+
+			filtered.SetGray(x, y, color.Gray{Y: window[len(window)/2]})
+		}
+	}
+
+	return filtered
+}
+
+// upscale doubles the image, remote solvers and people read small captchas
+// noticeably worse.
+func upscale(img *image.Gray) *image.Gray {
+	const factor = 2
+
+	dstRect := image.Rect(0, 0, img.Rect.Dx()*factor, img.Rect.Dy()*factor)
+	dstImage := image.NewGray(dstRect)
+
+	xdraw.CatmullRom.Scale(dstImage, dstRect, img, img.Rect, xdraw.Src, nil)
+
+	return dstImage
+}
@@ -0,0 +1,128 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func decodeGray(t *testing.T, img Image) *image.Gray {
+	t.Helper()
+
+	decoded, _, err := image.Decode(bytes.NewReader(img.Bytes))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	gray, ok := decoded.(*image.Gray)
+	if !ok {
+		t.Fatalf("expected grayscale image, got %T", decoded)
+	}
+
+	return gray
+}
+
+func TestNewPipeline_UnknownStep(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewPipeline(StepContrast, "sharpen"); err == nil {
+		t.Fatal("expected error for unknown step")
+	}
+}
+
+func TestPipeline_Empty(t *testing.T) {
+	t.Parallel()
+
+	src := newTestPNG(t, 20, 10)
+
+	out, err := MustNewPipeline().Apply(src)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if &out.Bytes[0] != &src.Bytes[0] {
+		t.Error("expected empty pipeline to return the source image")
+	}
+
+	if name := MustNewPipeline().String(); name != "raw" {
+		t.Errorf("expected raw, got %s", name)
+	}
+
+	if name := MustNewPipeline(StepContrast, StepDenoise).String(); name != "contrast+denoise" {
+		t.Errorf("expected contrast+denoise, got %s", name)
+	}
+}
+
+func TestPipeline_Upscale(t *testing.T) {
+	t.Parallel()
+
+	out, err := MustNewPipeline(StepGrayscale, StepUpscale).Apply(newTestPNG(t, 20, 10))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if bounds := decodeGray(t, out).Bounds(); bounds.Dx() != 40 || bounds.Dy() != 20 {
+		t.Errorf("expected 40x20, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestPipeline_Contrast(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewGray(image.Rect(0, 0, 10, 10))
+	for i := range src.Pix {
+		src.Pix[i] = 150
+	}
+
+	for x := 0; x < 5; x++ {
+		src.SetGray(x, 0, color.Gray{Y: 100})
+	}
+
+	out, err := MustNewPipeline(StepContrast).Apply(encodePNG(t, src))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	gray := decodeGray(t, out)
+
+	if dark, light := gray.GrayAt(0, 0).Y, gray.GrayAt(9, 9).Y; dark != 0 || light != 255 {
+		t.Errorf("expected levels stretched to 0 and 255, got %d and %d", dark, light)
+	}
+}
+
+func TestPipeline_DenoiseRemovesThinLines(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewGray(image.Rect(0, 0, 30, 20))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+
+	for x := 0; x < 30; x++ {
+		src.SetGray(x, 3, color.Gray{Y: 0})
+	}
+
+	for y := 8; y < 16; y++ {
+		for x := 10; x < 18; x++ {
+			src.SetGray(x, y, color.Gray{Y: 0})
+		}
+	}
+
+	out, err := MustNewPipeline(StepDenoise).Apply(encodePNG(t, src))
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	gray := decodeGray(t, out)
+
+	for x := 0; x < 30; x++ {
+		if gray.GrayAt(x, 3).Y != 255 {
+			t.Fatalf("expected noise line removed at x=%d", x)
+		}
+	}
+
+	if gray.GrayAt(14, 12).Y != 0 {
+		t.Error("expected thick stroke kept")
+	}
+}
@@ -0,0 +1,112 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"slices"
+)
+
+const minDigitMassRatio = 8
+
+// SegmentDigits cuts the text band of the mask into count digits by the
+// column projection, merging the closest pieces of broken digits and
+// splitting touching digits at their thinnest column.
+func SegmentDigits(mask Mask, count int) ([]Mask, error) {
+	band, ok := mask.textBand()
+	if !ok {
+		return nil, fmt.Errorf("no text found")
+	}
+
+	columns := mask.Columns(band.from, band.to)
+
+	var (
+		pieces  []run
+		maxMass int
+	)
+
+	runs := activeRuns(columns, 1)
+	for _, r := range runs {
+		maxMass = max(maxMass, mass(columns, r))
+	}
+
+	for _, r := range runs {
+		if mass(columns, r)*minDigitMassRatio >= maxMass {
+			pieces = append(pieces, r)
+		}
+	}
+
+	if len(pieces) == 0 {
+		return nil, fmt.Errorf("no digits found")
+	}
+
+	for len(pieces) > count {
+		pieces = mergeClosest(pieces)
+	}
+
+	for len(pieces) < count {
+		var split bool
+
+		pieces, split = splitWidest(pieces, columns)
+		if !split {
+			return nil, fmt.Errorf("found %d digits, expected %d", len(pieces), count)
+		}
+	}
+
+	digits := make([]Mask, 0, count)
+
+	for _, piece := range pieces {
+		digit := mask.Crop(image.Rect(piece.from, band.from, piece.to, band.to))
+		digits = append(digits, digit.Crop(digit.Bounds()))
+	}
+
+	return digits, nil
+}
+
+func mergeClosest(pieces []run) []run {
+	closest := 1
+
+	for i := 2; i < len(pieces); i++ {
+		if gap(pieces, i) < gap(pieces, closest) ||
+			gap(pieces, i) == gap(pieces, closest) &&
+				pieces[i].to-pieces[i-1].from < pieces[closest].to-pieces[closest-1].from {
+			closest = i
+		}
+	}
+
+	pieces[closest-1].to = pieces[closest].to
+
+	return slices.Delete(pieces, closest, closest+1)
+}
+
+func gap(pieces []run, i int) int {
+	return pieces[i].from - pieces[i-1].to
+}
+
+func splitWidest(pieces []run, columns []int) ([]run, bool) {
+	widest := 0
+
+	for i, piece := range pieces {
+		if piece.to-piece.from > pieces[widest].to-pieces[widest].from {
+			widest = i
+		}
+	}
+
+	piece := pieces[widest]
+	width := piece.to - piece.from
+
+	if width < 2 {
+		return pieces, false
+	}
+
+	at := piece.from + width/2
+
+	for x := piece.from + width/4; x < piece.to-width/4; x++ {
+		if columns[x] < columns[at] {
+			at = x
+		}
+	}
+
+	pieces[widest].to = at
+
+	return slices.Insert(pieces, widest+1, run{from: at, to: piece.to}), true
+}
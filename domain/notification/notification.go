@@ -4,17 +4,17 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 )
 
 type Notification struct {
-	Images               []PNG
+	Images               []image.Image
 	CrawledAt            time.Time
 	Error                error
 	SomethingInteresting bool
 }
 
-type PNG []byte
-
 type Notifier interface {
 	Notify(context.Context, *Notification, *Recipient) error
 }
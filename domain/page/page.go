@@ -3,23 +3,36 @@ package page
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/proxy"
 )
 
-var ErrCaptchaNotSolved = fmt.Errorf("captcha not solved")
+var (
+	ErrCaptchaNotSolved = fmt.Errorf("captcha not solved")
+	ErrNavigationFailed = fmt.Errorf("navigation failed")
+	ErrUnavailable      = fmt.Errorf("dispatcher unavailable")
+)
 
 type Stat struct {
 	HTML                 []byte
 	Network              []byte
-	Screenshot           image.PNG
+	Screenshot           image.Image
 	Captcha              Captcha
+	Traffic              Traffic
 	SomethingInteresting bool
 }
 
+type Traffic struct {
+	Requests        int
+	BlockedRequests int
+	Bytes           int64
+}
+
 type Captcha struct {
 	Presented bool
-	Image     image.PNG
+	Image     image.Image
 }
 
 type Navigator interface {
@@ -30,6 +43,21 @@ type Navigator interface {
 	OpenSlotBookingPage() (Stat, error)
 }
 
+type NavigatorOptions struct {
+	Proxy *proxy.Proxy
+}
+
+type Health struct {
+	Healthy        bool
+	Since          time.Time
+	OpenNavigators int
+	Relaunches     int
+	LastError      string
+}
+
 type Dispatcher interface {
-	NewNavigator(id, cd string) (Navigator, error)
+	io.Closer
+
+	NewNavigator(id, cd string, options NavigatorOptions) (Navigator, error)
+	Health() Health
 }
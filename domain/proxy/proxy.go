@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+var ErrNoHealthyProxy = fmt.Errorf("no healthy proxy")
+
+type Proxy struct {
+	URL *url.URL
+}
+
+func (p *Proxy) Name() string {
+	if p == nil || p.URL == nil {
+		return "direct"
+	}
+
+	return p.URL.Scheme + "://" + p.URL.Host
+}
+
+type Pool interface {
+	Pick(key string) (*Proxy, error)
+	Report(proxy *Proxy, err error)
+	CheckHealth(ctx context.Context)
+}
@@ -2,17 +2,22 @@ package port
 
 import (
 	"context"
-	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
-	"sort"
+	"net/url"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/truewebber/gopkg/log"
 
 	"github.com/truewebber/kdmid-queue-checker/app"
+	"github.com/truewebber/kdmid-queue-checker/app/query"
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
 )
 
 type HTTPServer struct {
@@ -41,6 +46,8 @@ func (s *HTTPServer) configureRouter() *http.ServeMux {
 
 	mux.HandleFunc("/", s.openIndexPage)
 	mux.HandleFunc("/user/{userID}/{date}", s.openCrawlListPage)
+	mux.HandleFunc("/artifact", s.openArtifact)
+	mux.HandleFunc("/health", s.openHealth)
 
 	return mux
 }
@@ -76,9 +83,17 @@ func (s *HTTPServer) openIndexPage(w http.ResponseWriter, r *http.Request) {
 
 	date := time.Now()
 
+	spend, err := s.app.Query.CaptchaSpend.Handle(r.Context(), date)
+	if err != nil {
+		s.responseError(http.StatusInternalServerError, err, w)
+
+		return
+	}
+
 	html := "<!doctype html><html>" + head + "<body>" +
 		"<div class=\"main\">" +
 		"<h2>kdmid bot artifact viewer</h2>" +
+		"<p>" + captchaSpendText(spend) + "</p>" +
 		"<p style=\"text-decoration: underline\">choose which user to browse</p>"
 
 	for _, user := range users {
@@ -92,13 +107,17 @@ func (s *HTTPServer) openIndexPage(w http.ResponseWriter, r *http.Request) {
 			crawls = "has crawls"
 		}
 
+		userSpend := spend.Recipients[user.TelegramID]
+
 		html += fmt.Sprintf(
-			"<p class=\"user\"><a href=\"/user/%d/%s\">%d</a> | %s | %s</p>",
+			"<p class=\"user\"><a href=\"/user/%d/%s\">%d</a> | %s | %s | %d captchas today, %.4f</p>",
 			user.TelegramID,
 			date.Format(time.DateOnly),
 			user.TelegramID,
 			active,
 			crawls,
+			userSpend.Solves,
+			userSpend.Cost,
 		)
 	}
 
@@ -129,15 +148,59 @@ func (s *HTTPServer) openCrawlListPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	crawls, err := s.app.Query.ListCrawls.Handle(r.Context(), userID, date)
+	pageNumber := 1
+	if pageVal := r.URL.Query().Get("page"); pageVal != "" {
+		pageNumber, err = strconv.Atoi(pageVal)
+		if err != nil || pageNumber < 1 {
+			s.responseError(http.StatusBadRequest, fmt.Errorf("invalid page `%s`", pageVal), w)
+
+			return
+		}
+	}
+
+	statusVal := r.URL.Query().Get("status")
+
+	var statuses []crawl.Status
+	if statusVal != "" {
+		status, err := crawl.ParseStatus(statusVal)
+		if err != nil {
+			s.responseError(http.StatusBadRequest, err, w)
+
+			return
+		}
+
+		statuses = []crawl.Status{status}
+	}
+
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	crawlQuery := crawl.Query{
+		From:     from,
+		To:       from.AddDate(0, 0, 1),
+		UserIDs:  []int64{userID},
+		Statuses: statuses,
+		Offset:   (pageNumber - 1) * crawlsPerPage,
+		Limit:    crawlsPerPage,
+	}
+
+	crawlPage, err := s.app.Query.ListCrawls.Handle(r.Context(), crawlQuery)
+	if err != nil {
+		s.responseError(http.StatusInternalServerError, err, w)
+
+		return
+	}
+
+	spend, err := s.app.Query.CaptchaSpend.Handle(r.Context(), from)
 	if err != nil {
 		s.responseError(http.StatusInternalServerError, err, w)
 
 		return
 	}
 
+	userSpend := spend.Recipients[userID]
+
 	pastVal := date.AddDate(0, 0, -1).Format(time.DateOnly)
 	futureVal := date.AddDate(0, 0, 1).Format(time.DateOnly)
+	dayURL := "/user/" + userIDVal + "/" + dateVal
 
 	html := "<!doctype html><html>" + head +
 		"<body>" +
@@ -146,37 +209,51 @@ func (s *HTTPServer) openCrawlListPage(w http.ResponseWriter, r *http.Request) {
 		"<p>User \"" + userIDVal + "\" | Date: " + dateVal +
 		" | <a href=\"/user/" + userIDVal + "/" + pastVal + "\">Past</a>" +
 		" | <a href=\"/user/" + userIDVal + "/" + futureVal + "\">Future</a></p>" +
+		fmt.Sprintf("<p>captchas: %d, spent: %.4f</p>", userSpend.Solves, userSpend.Cost) +
+		"<p>Show: <a href=\"" + dayURL + "\">all</a>" +
+		" | <a href=\"" + dayURL + "?status=" + string(crawl.StatusError) + "\">errors</a>" +
+		" | <a href=\"" + dayURL + "?status=" + string(crawl.StatusInteresting) + "\">interesting</a>" +
+		" | <a href=\"" + dayURL + "?status=" + string(crawl.StatusOK) + "\">ok</a></p>" +
+		pagerHTML(dayURL, statusVal, pageNumber, crawlPage.Total) +
 		"<p><a href=\"/\">Back</a></p>" +
 		"<div class=\"crawls_block\">"
 
-	sort.SliceStable(crawls, func(i, j int) bool {
-		return crawls[i].CrawledAt.After(crawls[j].CrawledAt)
-	})
-
-	for _, c := range crawls {
+	for _, c := range crawlPage.Crawls {
 		class := "crawl_general"
 		text := ""
 
 		switch {
 		case c.Err != nil:
 			class = "crawl_error"
-			text = c.Err.Error()
+			text = escapeHTML(c.Err.Error())
 		case c.SomethingInteresting:
 			class = "crawl_interesting"
 			text = "Success?"
 		}
 
 		html += "<div class=\"crawl " + class + "\">" +
-			"<p>" + c.CrawledAt.Format(time.TimeOnly) + text + "</p>" +
-			"<p class=\"hr\"></p>"
+			"<p>" + c.CrawledAt.Local().Format("15:04:05.000") + text + "</p>" +
+			"<p>proxy: " + escapeHTML(c.Proxy) + "</p>" +
+			"<p>captcha solver: " + escapeHTML(c.CaptchaSolver) + "</p>" +
+			"<p>id: " + escapeHTML(c.ID) + " | version: " + escapeHTML(c.AppVersion) + " | retries: " + strconv.Itoa(c.Retries) +
+			" | submitted code: " + escapeHTML(c.SubmittedCode) + "</p>" +
+			"<p>" + durationsText(c.Durations) + "</p>"
+
+		for _, attempt := range c.Attempts {
+			html += "<p>captcha attempt: " + escapeHTML(attempt.Code) + " by " + escapeHTML(attempt.Solver) +
+				" on " + escapeHTML(attempt.Preprocessing) +
+				" (" + strconv.FormatFloat(attempt.Confidence, 'f', 2, sixtyFour) + "), " + escapeHTML(attempt.Verdict) + "</p>"
+		}
+
+		html += "<p class=\"hr\"></p>"
 
 		for i := range c.Screenshots {
-			html += "<img class=\"screenshot\" src=\"data:image/png;base64," +
-				base64.StdEncoding.EncodeToString(c.Screenshots[i]) + "\">"
+			html += "<img class=\"screenshot\" loading=\"lazy\" src=\"" + escapeHTML(artifactURL(c.Screenshots[i])) + "\">"
 		}
 
-		html += "<img class=\"captcha\" src=\"data:image/png;base64," +
-			base64.StdEncoding.EncodeToString(c.Captch) + "\">"
+		if !c.Captcha.Empty() {
+			html += "<img class=\"captcha\" loading=\"lazy\" src=\"" + escapeHTML(artifactURL(c.Captcha)) + "\">"
+		}
 
 		html += "</div>"
 	}
@@ -208,6 +285,141 @@ func (s *HTTPServer) openCrawlListPage(w http.ResponseWriter, r *http.Request) {
 	s.responseHTML(html, w)
 }
 
+const crawlsPerPage = 50
+
+func pagerHTML(dayURL, statusVal string, pageNumber, total int) string {
+	pages := max((total+crawlsPerPage-1)/crawlsPerPage, 1)
+
+	pageURL := func(n int) string {
+		values := url.Values{}
+		values.Set("page", strconv.Itoa(n))
+
+		if statusVal != "" {
+			values.Set("status", statusVal)
+		}
+
+		return dayURL + "?" + values.Encode()
+	}
+
+	html := fmt.Sprintf("<p>%d crawls | page %d of %d", total, pageNumber, pages)
+
+	if pageNumber > 1 {
+		html += " | <a href=\"" + pageURL(pageNumber-1) + "\">Newer</a>"
+	}
+
+	if pageNumber < pages {
+		html += " | <a href=\"" + pageURL(pageNumber+1) + "\">Older</a>"
+	}
+
+	return html + "</p>"
+}
+
+func durationsText(d query.Durations) string {
+	return fmt.Sprintf("authorize: %s, captcha: %s, submit: %s, calendar: %s, total: %s",
+		d.Authorize.Round(time.Millisecond), d.CaptchaSolve.Round(time.Millisecond),
+		d.Submit.Round(time.Millisecond), d.Calendar.Round(time.Millisecond), d.Total.Round(time.Millisecond))
+}
+
+func captchaSpendText(spend query.CaptchaSpend) string {
+	text := fmt.Sprintf("captchas today: %d, spent: %.4f", spend.Total.Solves, spend.Total.Cost)
+
+	if spend.DailyBudget > 0 {
+		text += fmt.Sprintf(" of %.4f daily budget", spend.DailyBudget)
+	}
+
+	if !spend.BalanceCheckedAt.IsZero() {
+		text += fmt.Sprintf(" | balance: %.4f at %s", spend.Balance, spend.BalanceCheckedAt.Format(time.DateTime))
+	}
+
+	return text
+}
+
+func (s *HTTPServer) openHealth(w http.ResponseWriter, r *http.Request) {
+	health := s.app.Query.DispatcherHealth.Handle(r.Context())
+
+	code := http.StatusOK
+	if !health.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		s.logger.Error("failed to write health", "error", err.Error())
+	}
+}
+
+// escapeHTML is html.EscapeString for the handlers building their page in a
+// variable named html.
+func escapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+func artifactURL(artifact query.Artifact) string {
+	values := url.Values{}
+	values.Set("crawl", artifact.Crawl)
+	values.Set("kind", artifact.Kind)
+	values.Set("index", strconv.Itoa(artifact.Index))
+	values.Set("mime", artifact.MIMEType)
+
+	return "/artifact?" + values.Encode()
+}
+
+// artifactMIMETypes are served as they are, anything else goes out as plain
+// text so a saved page can't run in the viewer.
+var artifactMIMETypes = []string{image.MIMETypePNG, image.MIMETypeJPEG, image.MIMETypeWebP}
+
+func (s *HTTPServer) openArtifact(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	index, err := strconv.Atoi(values.Get("index"))
+	if err != nil {
+		s.responseError(http.StatusBadRequest, err, w)
+
+		return
+	}
+
+	artifact := query.Artifact{
+		Crawl:    values.Get("crawl"),
+		Kind:     values.Get("kind"),
+		Index:    index,
+		MIMEType: values.Get("mime"),
+	}
+
+	artifactBytes, err := s.app.Query.GetArtifact.Handle(r.Context(), artifact)
+	if errors.Is(err, crawl.ErrInvalidArtifactRef) {
+		s.responseError(http.StatusBadRequest, err, w)
+
+		return
+	}
+
+	if errors.Is(err, crawl.ErrArtifactNotFound) {
+		s.responseError(http.StatusNotFound, err, w)
+
+		return
+	}
+
+	if err != nil {
+		s.responseError(http.StatusInternalServerError, err, w)
+
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if slices.Contains(artifactMIMETypes, artifact.MIMEType) {
+		contentType = artifact.MIMEType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+
+	if _, err := w.Write(artifactBytes); err != nil {
+		s.logger.Error("failed to write artifact", "error", err.Error())
+	}
+}
+
 func (s *HTTPServer) responseHTML(html string, w http.ResponseWriter) {
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "text/html")
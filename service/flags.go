@@ -0,0 +1,72 @@
+package service
+
+import (
+	"flag"
+	"os"
+	"strconv"
+)
+
+// CrawlStorageFlags are the flags the command line tools open the crawl
+// storage with, they default to the environment of the checker.
+type CrawlStorageFlags struct {
+	flags *flag.FlagSet
+	cfg   CrawlStorage
+}
+
+func NewCrawlStorageFlags(flags *flag.FlagSet) *CrawlStorageFlags {
+	f := &CrawlStorageFlags{flags: flags}
+
+	flags.StringVar(&f.cfg.Kind, "storage", envOr("CRAWL_STORAGE", CrawlStorageFs),
+		"crawl storage: fs, sqlite or s3")
+	flags.StringVar(&f.cfg.SQLitePath, "sqlite", os.Getenv("CRAWL_STORAGE_SQLITE_PATH"),
+		"sqlite crawl database, defaults to crawls.db in the artifacts directory")
+	flags.IntVar(&f.cfg.SQLiteMaxBlob, "sqlite-max-blob", envIntOr("CRAWL_STORAGE_SQLITE_MAX_BLOB", 262144),
+		"largest artifact in bytes kept inside the sqlite database")
+	flags.StringVar(&f.cfg.ArchiveFormat, "archive-format", envOr("ARTIFACTS_ARCHIVE_FORMAT", "zstd"),
+		"archive format: zstd or gzip")
+	flags.StringVar(&f.cfg.S3.Endpoint, "s3-endpoint", os.Getenv("S3_ENDPOINT"), "s3 endpoint")
+	flags.StringVar(&f.cfg.S3.Region, "s3-region", envOr("S3_REGION", "us-east-1"), "s3 region")
+	flags.StringVar(&f.cfg.S3.Bucket, "s3-bucket", envOr("S3_BUCKET", "kdmid-artifacts"), "s3 bucket")
+	flags.StringVar(&f.cfg.S3.Prefix, "s3-prefix", os.Getenv("S3_PREFIX"), "s3 key prefix")
+	flags.BoolVar(&f.cfg.S3.UseSSL, "s3-use-ssl", os.Getenv("S3_USE_SSL") != "false", "connect to s3 over TLS")
+
+	// Keys are only taken from the environment, flags end up in shell history.
+	f.cfg.S3.AccessKey = os.Getenv("S3_ACCESS_KEY")
+	f.cfg.S3.SecretKey = os.Getenv("S3_SECRET_KEY")
+
+	return f
+}
+
+// Config is read after the flags are parsed, -sqlite given without -storage
+// selects sqlite.
+func (f *CrawlStorageFlags) Config() CrawlStorage {
+	cfg := f.cfg
+	set := make(map[string]bool)
+
+	f.flags.Visit(func(flag *flag.Flag) {
+		set[flag.Name] = true
+	})
+
+	if set["sqlite"] && !set["storage"] {
+		cfg.Kind = CrawlStorageSQLite
+	}
+
+	return cfg
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func envIntOr(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
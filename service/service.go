@@ -1,7 +1,15 @@
 package service
 
 import (
+	"cmp"
+	"fmt"
+	"io"
 	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/truewebber/gopkg/log"
 
@@ -9,41 +17,417 @@ import (
 	"github.com/truewebber/kdmid-queue-checker/app"
 	"github.com/truewebber/kdmid-queue-checker/app/daemon"
 	"github.com/truewebber/kdmid-queue-checker/app/query"
+	"github.com/truewebber/kdmid-queue-checker/domain/captcha"
+	"github.com/truewebber/kdmid-queue-checker/domain/crawl"
+	"github.com/truewebber/kdmid-queue-checker/domain/image"
+	"github.com/truewebber/kdmid-queue-checker/domain/page"
 )
 
 func NewApplication(cfg *Config, logger log.Logger) *app.Application {
-	dispatcher := adapter.MustNewBrowserDispatcher(cfg.ProxyURL)
-	solver := adapter.NewTwoCaptchaSolver(cfg.TwoCaptchaAPIKey)
-	crawlStorage := adapter.MustNewFileSystemCrawlStorage(cfg.ArtifactsDirectory, logger)
+	proxyPool := adapter.MustNewProxyPool(adapter.ProxyPoolConfig{
+		URLs:           cfg.Proxy.URLs,
+		Rotation:       adapter.ProxyRotation(cfg.Proxy.Rotation),
+		Quarantine:     cfg.Proxy.Quarantine,
+		HealthCheckURL: cfg.Proxy.HealthCheckURL,
+		NoSOCKS5Auth:   cfg.Kdmid.Navigator == NavigatorBrowser,
+	}, logger)
+	dispatcher := mustNewDispatcher(cfg.Kdmid, logger)
+	humanSolver := newHumanSolver(cfg.Captcha, cfg.TelegramBotToken)
+	solver := mustNewSolver(cfg.Captcha, cfg.TwoCaptchaAPIKey, humanSolver)
+	overBudgetSolver := newOverBudgetSolver(cfg.Captcha, humanSolver)
+	balanceChecker, balanceCheckInterval := newBalanceChecker(cfg.Captcha, cfg.TwoCaptchaAPIKey)
+	captchaLedger := adapter.MustNewCaptchaLedgerFs(
+		cfg.RecipientStorage.Directory, cfg.Captcha.LedgerKeepDays, logger,
+	)
+	crawlStorage := mustNewCrawlStorage(cfg.CrawlStorage, cfg.ArtifactsDirectory, logger)
 	recipientStorage := adapter.MustNewRecipientStorageFs(
 		cfg.RecipientStorage.Directory, cfg.RecipientStorage.Limit, logger,
 	)
 
 	telegramNotifier := adapter.MustNewTelegramNotifier(cfg.TelegramBotToken)
 
+	closers := []io.Closer{dispatcher}
+	if closer, ok := crawlStorage.(io.Closer); ok {
+		closers = append(closers, closer)
+	}
+
+	var migrators []crawl.Migrator
+	if migrator, ok := crawlStorage.(crawl.Migrator); ok {
+		migrators = append(migrators, migrator)
+	}
+
 	return &app.Application{
 		Daemon: app.Daemon{
 			CheckSlot: daemon.NewCheckSlot(
-				dispatcher, solver, crawlStorage, recipientStorage, telegramNotifier, logger,
+				consulate(cfg.Kdmid.BaseURL), cfg.AppVersion, dispatcher, proxyPool, solver, overBudgetSolver,
+				crawlStorage, recipientStorage, telegramNotifier,
+				captchaLedger, cfg.Captcha.DailyBudget, logger,
+			),
+			Bot: daemon.MustNewNotifierBot(
+				cfg.TelegramBotToken, recipientStorage, crawlStorage, humanSolver, logger,
 			),
-			Bot: daemon.MustNewNotifierBot(cfg.TelegramBotToken, recipientStorage, logger),
+			ProxyHealth: daemon.NewProxyHealth(proxyPool, cfg.Proxy.HealthCheckInterval, logger),
+			CaptchaBalance: daemon.NewCaptchaBalance(
+				balanceChecker, captchaLedger, balanceCheckInterval, logger,
+			),
+			Janitor: newJanitor(crawlStorage, cfg.CrawlStorage.Retention, logger),
 		},
 		Query: app.Query{
-			ListUsers:  query.NewListUsersHandler(recipientStorage, crawlStorage),
-			ListCrawls: query.NewListCrawlsHandler(crawlStorage),
+			ListUsers:        query.NewListUsersHandler(recipientStorage, crawlStorage),
+			ListCrawls:       query.NewListCrawlsHandler(crawlStorage),
+			GetArtifact:      query.NewGetArtifactHandler(crawlStorage),
+			DispatcherHealth: query.NewDispatcherHealthHandler(dispatcher),
+			CaptchaSpend:     query.NewCaptchaSpendHandler(captchaLedger, cfg.Captcha.DailyBudget),
 		},
+		Migrators: migrators,
+		Closers:   closers,
+	}
+}
+
+// newHumanSolver returns nil unless captchas are asked in telegram, in the
+// chain or once the budget is spent.
+func newHumanSolver(cfg Captcha, telegramBotToken string) captcha.HumanSolver {
+	if !cfg.HumanOverBudget && !slices.Contains(cfg.Solvers, SolverTelegram) {
+		return nil
+	}
+
+	return adapter.MustNewTelegramSolver(telegramBotToken, adapter.TelegramSolverConfig{
+		ChatID:  cfg.TelegramChatID,
+		Timeout: cfg.TelegramTimeout,
+	})
+}
+
+// newOverBudgetSolver asks a human for the captchas of the checks the budget
+// would skip, nil skips them.
+func newOverBudgetSolver(cfg Captcha, humanSolver captcha.HumanSolver) captcha.Solver {
+	if humanSolver == nil {
+		return nil
+	}
+
+	return captcha.NewChain(captcha.Link{
+		Name:       SolverTelegram,
+		Solver:     humanSolver,
+		Preprocess: image.MustNewPipeline(cfg.Preprocessing[SolverTelegram]...),
+	})
+}
+
+func mustNewSolver(cfg Captcha, twoCaptchaAPIKey string, humanSolver captcha.HumanSolver) captcha.Solver {
+	if len(cfg.Solvers) == 0 {
+		panic("no captcha solvers configured")
+	}
+
+	links := make([]captcha.Link, 0, len(cfg.Solvers))
+
+	for _, name := range cfg.Solvers {
+		link := captcha.Link{
+			Name:       name,
+			Preprocess: image.MustNewPipeline(cfg.Preprocessing[name]...),
+		}
+
+		switch name {
+		case SolverTwoCaptcha:
+			link.Solver = adapter.NewTwoCaptchaSolver(twoCaptchaAPIKey, cfg.TwoCaptchaPrice)
+		case SolverOCR:
+			link.Solver = adapter.MustNewOCRSolver()
+			link.MinConfidence = cfg.OCRMinConfidence
+		case SolverAntiCaptcha:
+			link.Solver = adapter.NewAntiCaptchaSolver(antiCaptchaConfig(cfg.AntiCaptcha))
+		case SolverTelegram:
+			link.Solver = humanSolver
+		default:
+			panic(fmt.Sprintf("unsupported captcha solver `%s`", name))
+		}
+
+		links = append(links, link)
+	}
+
+	return captcha.NewChain(links...)
+}
+
+// newBalanceChecker checks the balance of the first paid solver, polling is
+// turned off when no paid solver is used.
+func newBalanceChecker(cfg Captcha, twoCaptchaAPIKey string) (captcha.BalanceChecker, time.Duration) {
+	for _, name := range cfg.Solvers {
+		switch name {
+		case SolverTwoCaptcha:
+			return adapter.NewTwoCaptchaBalanceChecker(twoCaptchaAPIKey), cfg.BalanceCheckInterval
+		case SolverAntiCaptcha:
+			return adapter.NewAntiCaptchaBalanceChecker(antiCaptchaConfig(cfg.AntiCaptcha)), cfg.BalanceCheckInterval
+		}
+	}
+
+	return nil, 0
+}
+
+func antiCaptchaConfig(cfg AntiCaptcha) adapter.AntiCaptchaConfig {
+	return adapter.AntiCaptchaConfig{
+		Endpoint:        cfg.Endpoint,
+		APIKey:          cfg.APIKey,
+		PollingInterval: cfg.PollingInterval,
+		Timeout:         cfg.Timeout,
+	}
+}
+
+func mustNewCrawlStorage(cfg CrawlStorage, artifactsDirectory string, logger log.Logger) crawl.Storage {
+	crawlStorage, err := NewCrawlStorage(cfg, artifactsDirectory, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return crawlStorage
+}
+
+// NewCrawlStorage opens the configured crawl storage, the command line tools
+// open it the same way the checker does.
+func NewCrawlStorage(cfg CrawlStorage, artifactsDirectory string, logger log.Logger) (crawl.Storage, error) {
+	switch cfg.Kind {
+	case CrawlStorageFs:
+		return adapter.NewFileSystemCrawlStorage(
+			artifactsDirectory, adapter.ArchiveFormat(cfg.ArchiveFormat), logger,
+		)
+	case CrawlStorageSQLite:
+		path := cfg.SQLitePath
+		if path == "" {
+			path = filepath.Join(artifactsDirectory, "crawls.db")
+		}
+
+		return adapter.NewSQLiteCrawlStorage(adapter.SQLiteCrawlStorageConfig{
+			Path:          path,
+			BlobDirectory: filepath.Join(artifactsDirectory, "blobs"),
+			MaxBlobSize:   cfg.SQLiteMaxBlob,
+		}, logger)
+	case CrawlStorageS3:
+		return adapter.NewS3CrawlStorage(adapter.S3CrawlStorageConfig{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			Prefix:    cfg.S3.Prefix,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			UseSSL:    cfg.S3.UseSSL,
+		}, logger)
+	default:
+		return nil, fmt.Errorf("unsupported crawl storage `%s`", cfg.Kind)
+	}
+}
+
+// newJanitor turns pruning off for storages that can not be pruned.
+func newJanitor(crawlStorage crawl.Storage, cfg Retention, logger log.Logger) *daemon.Janitor {
+	pruner, ok := crawlStorage.(crawl.Pruner)
+	if !ok {
+		return daemon.NewJanitor(nil, nil, crawl.Retention{}, 0, logger)
+	}
+
+	archiver, _ := crawlStorage.(crawl.Archiver)
+
+	return daemon.NewJanitor(pruner, archiver, crawl.Retention{
+		KeepDays:        cfg.KeepDays,
+		KeepNotableDays: cfg.KeepNotableDays,
+		MaxBytes:        cfg.MaxBytes,
+		ArchiveDays:     cfg.ArchiveDays,
+	}, cfg.PruneInterval, logger)
+}
+
+func consulate(baseURL *url.URL) string {
+	consulate, _, _ := strings.Cut(baseURL.Hostname(), ".")
+
+	return consulate
+}
+
+func mustNewDispatcher(cfg Kdmid, logger log.Logger) page.Dispatcher {
+	switch cfg.Navigator {
+	case NavigatorBrowser:
+		return adapter.MustNewBrowserDispatcher(adapter.BrowserDispatcherConfig{
+			BaseURL: cfg.BaseURL,
+			Timeout: cfg.Timeout,
+			Blocking: adapter.ResourceBlocking{
+				ResourceTypes:    cfg.Blocking.ResourceTypes,
+				BlockURLPatterns: cfg.Blocking.BlockURLPatterns,
+				AllowURLPatterns: cfg.Blocking.AllowURLPatterns,
+			},
+			Screenshots:         mustBuildScreenshotPolicies(cfg.Screenshots),
+			MaxContexts:         cfg.Browser.MaxContexts,
+			HealthCheckInterval: cfg.Browser.HealthCheckInterval,
+			RelaunchBackoff:     cfg.Browser.RelaunchBackoff,
+			MaxRelaunchBackoff:  cfg.Browser.MaxRelaunchBackoff,
+		}, logger)
+	case NavigatorHTTP:
+		return adapter.MustNewHTTPDispatcher(adapter.HTTPDispatcherConfig{
+			BaseURL: cfg.BaseURL,
+			Timeout: cfg.Timeout,
+		})
+	default:
+		panic(fmt.Sprintf("unsupported navigator `%s`", cfg.Navigator))
+	}
+}
+
+// mustBuildScreenshotPolicies builds the policy of every step, a step
+// inherits the format, quality and size it leaves empty.
+func mustBuildScreenshotPolicies(cfg Screenshots) adapter.ScreenshotPolicies {
+	mimeTypes := map[string]string{
+		"png":  image.MIMETypePNG,
+		"jpeg": image.MIMETypeJPEG,
+		"jpg":  image.MIMETypeJPEG,
+		"webp": image.MIMETypeWebP,
+	}
+
+	policy := func(step ScreenshotStep) adapter.ScreenshotPolicy {
+		format := cmp.Or(step.Format, cfg.Format)
+
+		mimeType, ok := mimeTypes[format]
+		if !ok {
+			panic(fmt.Sprintf("unsupported screenshot format `%s`", format))
+		}
+
+		return adapter.ScreenshotPolicy{
+			Scope:    adapter.ScreenshotScope(step.Scope),
+			Selector: step.Selector,
+			Encoding: image.EncodeOptions{
+				MIMEType:  mimeType,
+				Quality:   cmp.Or(step.Quality, cfg.Quality),
+				MaxWidth:  cmp.Or(step.MaxWidth, cfg.MaxWidth),
+				MaxHeight: cmp.Or(step.MaxHeight, cfg.MaxHeight),
+			},
+		}
+	}
+
+	return adapter.ScreenshotPolicies{
+		Authorize: policy(cfg.Authorize),
+		Submit:    policy(cfg.Submit),
+		Calendar:  policy(cfg.Calendar),
 	}
 }
 
 type Config struct {
+	AppVersion         string
 	TwoCaptchaAPIKey   string
+	Captcha            Captcha
 	ArtifactsDirectory string
+	CrawlStorage       CrawlStorage
 	TelegramBotToken   string
 	RecipientStorage   RecipientStorage
-	ProxyURL           *url.URL
+	Proxy              Proxy
+	Kdmid              Kdmid
+}
+
+const (
+	CrawlStorageFs     = "fs"
+	CrawlStorageSQLite = "sqlite"
+	CrawlStorageS3     = "s3"
+)
+
+type CrawlStorage struct {
+	Kind string
+	// SQLitePath defaults to crawls.db in the artifacts directory.
+	SQLitePath    string
+	SQLiteMaxBlob int
+	// ArchiveFormat is zstd or gzip, only the file system storage archives.
+	ArchiveFormat string
+	S3            S3
+	Retention     Retention
+}
+
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+type Retention struct {
+	KeepDays        int
+	KeepNotableDays int
+	MaxBytes        int64
+	ArchiveDays     int
+	PruneInterval   time.Duration
 }
 
 type RecipientStorage struct {
 	Directory string
 	Limit     uint8
 }
+
+type Proxy struct {
+	URLs                []*url.URL
+	Rotation            string
+	Quarantine          time.Duration
+	HealthCheckURL      string
+	HealthCheckInterval time.Duration
+}
+
+const (
+	SolverTwoCaptcha  = "2captcha"
+	SolverOCR         = "ocr"
+	SolverTelegram    = "telegram"
+	SolverAntiCaptcha = "anticaptcha"
+)
+
+type Captcha struct {
+	Solvers              []string
+	OCRMinConfidence     float64
+	TwoCaptchaPrice      float64
+	DailyBudget          float64
+	BalanceCheckInterval time.Duration
+	LedgerKeepDays       int
+	TelegramChatID       int64
+	TelegramTimeout      time.Duration
+	// HumanOverBudget asks captchas in telegram once the daily budget is
+	// spent, even when telegram is not in the chain.
+	HumanOverBudget bool
+	AntiCaptcha     AntiCaptcha
+	// Preprocessing lists preprocessing steps by solver name.
+	Preprocessing map[string][]string
+}
+
+type AntiCaptcha struct {
+	Endpoint        string
+	APIKey          string
+	PollingInterval time.Duration
+	Timeout         time.Duration
+}
+
+const (
+	NavigatorBrowser = "browser"
+	NavigatorHTTP    = "http"
+)
+
+type Kdmid struct {
+	BaseURL     *url.URL
+	Timeout     time.Duration
+	Navigator   string
+	Browser     Browser
+	Blocking    Blocking
+	Screenshots Screenshots
+}
+
+type Browser struct {
+	MaxContexts         int
+	HealthCheckInterval time.Duration
+	RelaunchBackoff     time.Duration
+	MaxRelaunchBackoff  time.Duration
+}
+
+type Screenshots struct {
+	Format                      string
+	Quality                     int
+	MaxWidth, MaxHeight         int
+	Authorize, Submit, Calendar ScreenshotStep
+}
+
+// ScreenshotStep overrides the screenshot settings for one step, empty
+// fields inherit them.
+type ScreenshotStep struct {
+	Scope, Selector     string
+	Format              string
+	Quality             int
+	MaxWidth, MaxHeight int
+}
+
+type Blocking struct {
+	ResourceTypes    []string
+	BlockURLPatterns []*regexp.Regexp
+	AllowURLPatterns []*regexp.Regexp
+}